@@ -0,0 +1,549 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+const (
+	// archiveCompressionNone uploads plain NDJSON files
+	archiveCompressionNone = "none"
+
+	// archiveCompressionGzip uploads gzip-compressed NDJSON files
+	archiveCompressionGzip = "gzip"
+
+	// archiveCompressionZstd uploads zstd-compressed NDJSON files
+	archiveCompressionZstd = "zstd"
+
+	// s3Service is the SigV4 service name of S3
+	s3Service = "s3"
+
+	// archiveCheckInterval is the longest time between checks for files older than max_file_age
+	archiveCheckInterval = 10 * time.Second
+)
+
+// archiveChecksums are the supported checksum algorithms and their request headers
+var archiveChecksums = map[string]string{
+	"none":   "",
+	"md5":    "Content-MD5",
+	"crc32":  "X-Amz-Checksum-Crc32",
+	"crc32c": "X-Amz-Checksum-Crc32c",
+	"sha256": "X-Amz-Checksum-Sha256",
+}
+
+// ArchiveConfig configures the archive sink, which keeps a copy of the converted security events
+// as NDJSON files in S3-compatible object storage, alongside the primary endpoint
+type ArchiveConfig struct {
+	// Endpoint is the URL of the S3-compatible storage, such as http://minio:9000. It defaults to
+	// the AWS S3 endpoint of the region.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Bucket is the bucket the files are uploaded to
+	Bucket string `mapstructure:"bucket"`
+
+	// PathStyle addresses the bucket in the URL path instead of the host name, as most
+	// S3-compatible servers require
+	PathStyle bool `mapstructure:"path_style"`
+
+	// Prefix is the key prefix of each file. "%{attribute}" placeholders are replaced by event
+	// attributes and "%{+yyyy/MM/dd/HH}" by the event time, so events are partitioned by tenant
+	// and hour by default.
+	Prefix string `mapstructure:"prefix"`
+
+	// Compression is the file compression: "gzip" (default), "zstd" or "none"
+	Compression string `mapstructure:"compression"`
+
+	// MaxFileSize is the uncompressed size in bytes at which a file is closed and uploaded
+	MaxFileSize int `mapstructure:"max_file_size"`
+
+	// MaxFileAge is how long a file collects events before it is uploaded
+	MaxFileAge time.Duration `mapstructure:"max_file_age"`
+
+	// MaxPendingSize is the largest number of bytes of files kept in memory while they wait for
+	// their upload. The oldest files are dropped beyond it, for example while the storage is down.
+	MaxPendingSize int `mapstructure:"max_pending_size"`
+
+	// Checksum is the checksum S3 verifies on upload: "sha256" (default), "crc32", "crc32c",
+	// "md5" or "none"
+	Checksum string `mapstructure:"checksum"`
+
+	// StorageClass is the S3 storage class of the files, such as GLACIER_IR; empty uses the
+	// bucket default
+	StorageClass string `mapstructure:"storage_class"`
+
+	// ObjectLock retains the files in a bucket with object lock enabled
+	ObjectLock ArchiveObjectLockConfig `mapstructure:"object_lock"`
+
+	AWSCredentialsConfig `mapstructure:",squash"`
+}
+
+// ArchiveObjectLockConfig configures the object lock retention of uploaded files
+type ArchiveObjectLockConfig struct {
+	// Mode is the retention mode, "GOVERNANCE" or "COMPLIANCE"; no retention is set when empty
+	Mode string `mapstructure:"mode"`
+
+	// Retention is how long files are retained from their upload
+	Retention time.Duration `mapstructure:"retention"`
+
+	// LegalHold places a legal hold on the files
+	LegalHold bool `mapstructure:"legal_hold"`
+}
+
+// Validate checks the bucket, key prefix, file limits, checksum and object lock settings
+func (cfg *ArchiveConfig) Validate() error {
+	var errs []error
+	if cfg.Bucket == "" {
+		errs = append(errs, errors.New("archive bucket is required"))
+	}
+	if cfg.Region == "" {
+		errs = append(errs, errors.New("archive region is required"))
+	}
+	if cfg.Endpoint != "" {
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("invalid archive endpoint: %w", err))
+		}
+	}
+	if _, err := parseTemplate(cfg.Prefix); err != nil {
+		errs = append(errs, fmt.Errorf("invalid archive prefix: %w", err))
+	}
+	switch cfg.Compression {
+	case archiveCompressionNone, archiveCompressionGzip, archiveCompressionZstd:
+	default:
+		errs = append(errs, fmt.Errorf("invalid archive compression %q: must be %q, %q or %q", cfg.Compression, archiveCompressionGzip, archiveCompressionZstd, archiveCompressionNone))
+	}
+	if cfg.MaxFileSize <= 0 {
+		errs = append(errs, fmt.Errorf("archive max_file_size must be positive, got %d", cfg.MaxFileSize))
+	}
+	if cfg.MaxFileAge <= 0 {
+		errs = append(errs, fmt.Errorf("archive max_file_age must be positive, got %s", cfg.MaxFileAge))
+	}
+	if cfg.MaxPendingSize <= 0 {
+		errs = append(errs, fmt.Errorf("archive max_pending_size must be positive, got %d", cfg.MaxPendingSize))
+	}
+	if _, ok := archiveChecksums[cfg.Checksum]; !ok {
+		errs = append(errs, fmt.Errorf("invalid archive checksum %q: must be sha256, crc32, crc32c, md5 or none", cfg.Checksum))
+	}
+	switch cfg.ObjectLock.Mode {
+	case "":
+	case "GOVERNANCE", "COMPLIANCE":
+		if cfg.ObjectLock.Retention <= 0 {
+			errs = append(errs, fmt.Errorf("archive object_lock %s requires a positive retention", cfg.ObjectLock.Mode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid archive object_lock mode %q: must be GOVERNANCE or COMPLIANCE", cfg.ObjectLock.Mode))
+	}
+	// S3 rejects object lock uploads without an integrity checksum
+	if (cfg.ObjectLock.Mode != "" || cfg.ObjectLock.LegalHold) && cfg.Checksum == "none" {
+		errs = append(errs, errors.New("archive object_lock requires a checksum"))
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultArchiveConfig creates the default key layout and file limits
+func createDefaultArchiveConfig() ArchiveConfig {
+	return ArchiveConfig{
+		Prefix:         "%{tenant.id:default}/%{+yyyy/MM/dd/HH}/",
+		Compression:    archiveCompressionGzip,
+		MaxFileSize:    64 << 20,
+		MaxFileAge:     5 * time.Minute,
+		MaxPendingSize: 256 << 20,
+		Checksum:       "sha256",
+	}
+}
+
+// archiveFile is an open file collecting the events of one key prefix
+type archiveFile struct {
+	prefix string
+	key    string
+	opened time.Time
+	buffer bytes.Buffer
+	writer io.WriteCloser
+	size   int
+	events int
+}
+
+// archiveSink buffers converted events into one file per key prefix and uploads each file when it
+// reaches max_file_size or max_file_age. Uploads run in the background and files whose upload
+// fails are kept and uploaded again at the next check, up to max_pending_size, so archiving never
+// blocks or fails delivery to the primary endpoint.
+type archiveSink struct {
+	exp    *securityEventExporter
+	config *ArchiveConfig
+	prefix *fieldTemplate
+	now    func() time.Time
+
+	mu    sync.Mutex
+	files map[string]*archiveFile
+
+	// pending holds the full files and the files whose upload failed, oldest first
+	pending []*archiveFile
+
+	// uploads serializes uploads, so shutdown waits for the uploads in progress
+	uploads sync.Mutex
+
+	// wake asks the background goroutine to upload the pending files
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newArchiveSink creates the archive sink of e
+func newArchiveSink(e *securityEventExporter) (*archiveSink, error) {
+	cfg := e.config.Archive.Get()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	prefix, err := parseTemplate(cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &archiveSink{
+		exp:    e,
+		config: cfg,
+		prefix: prefix,
+		now:    time.Now,
+		files:  make(map[string]*archiveFile),
+		wake:   make(chan struct{}, 1),
+	}, nil
+}
+
+// start begins uploading full files and files that reach max_file_age
+func (s *archiveSink) start(context.Context, component.Host) error {
+	interval := min(s.config.MaxFileAge, archiveCheckInterval)
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				_ = s.flush(context.Background(), false)
+			case <-s.wake:
+				_ = s.flush(context.Background(), false)
+			}
+		}
+	}()
+	return nil
+}
+
+// add appends the events to the files of their key prefixes and hands the files that reached
+// max_file_size to the background goroutine for upload
+func (s *archiveSink) add(events []map[string]interface{}, sources []eventSource) {
+	full := false
+	s.mu.Lock()
+	for i, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			s.exp.logger.Warn("Failed to marshal security event for the archive", zap.Error(err))
+			continue
+		}
+		prefix := s.prefix.render(sources[i].attributes, sources[i].time)
+		file, ok := s.files[prefix]
+		if !ok {
+			if file, err = s.open(prefix); err != nil {
+				s.exp.logger.Warn("Failed to open archive file", zap.Error(err))
+				continue
+			}
+			s.files[prefix] = file
+		}
+		if _, err := file.writer.Write(append(line, '\n')); err != nil {
+			s.exp.logger.Warn("Failed to write security event to the archive", zap.Error(err))
+			continue
+		}
+		file.size += len(line) + 1
+		file.events++
+		if file.size >= s.config.MaxFileSize {
+			delete(s.files, prefix)
+			s.pending = append(s.pending, file)
+			full = true
+		}
+	}
+	if full {
+		s.trimPending()
+	}
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// trimPending drops the oldest pending files while the pending files exceed max_pending_size,
+// always keeping the newest. The caller holds mu.
+func (s *archiveSink) trimPending() {
+	size := 0
+	for _, file := range s.pending {
+		size += file.buffer.Len()
+	}
+	for len(s.pending) > 1 && size > s.config.MaxPendingSize {
+		file := s.pending[0]
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+		size -= file.buffer.Len()
+		s.exp.metrics.archiveEventsDropped.Add(int64(file.events))
+		s.exp.logger.Warn("Dropped the oldest archive file awaiting upload, max_pending_size exceeded",
+			zap.String("prefix", file.prefix),
+			zap.Int("event_count", file.events),
+			zap.Int("size", file.buffer.Len()),
+			zap.Int("max_pending_size", s.config.MaxPendingSize))
+	}
+}
+
+// open starts a new file for prefix
+func (s *archiveSink) open(prefix string) (*archiveFile, error) {
+	file := &archiveFile{prefix: prefix, opened: s.now()}
+	switch s.config.Compression {
+	case archiveCompressionGzip:
+		file.writer = gzip.NewWriter(&file.buffer)
+	case archiveCompressionZstd:
+		writer, err := zstd.NewWriter(&file.buffer)
+		if err != nil {
+			return nil, err
+		}
+		file.writer = writer
+	default:
+		file.writer = nopWriteCloser{&file.buffer}
+	}
+	return file, nil
+}
+
+// flush uploads the files older than max_file_age and the files whose upload failed before; with
+// all set it uploads every file. It returns an error when a file could not be uploaded.
+func (s *archiveSink) flush(ctx context.Context, all bool) error {
+	s.mu.Lock()
+	files := s.pending
+	s.pending = nil
+	now := s.now()
+	for prefix, file := range s.files {
+		if all || now.Sub(file.opened) >= s.config.MaxFileAge {
+			delete(s.files, prefix)
+			files = append(files, file)
+		}
+	}
+	s.mu.Unlock()
+
+	if failed := s.upload(ctx, files); failed > 0 {
+		return fmt.Errorf("failed to upload %d archive files", failed)
+	}
+	return nil
+}
+
+// upload closes and uploads the files, keeping those that failed for the next flush. It returns
+// the number of files that were not uploaded.
+func (s *archiveSink) upload(ctx context.Context, files []*archiveFile) int {
+	s.uploads.Lock()
+	defer s.uploads.Unlock()
+
+	var failed []*archiveFile
+	lost := 0
+	for _, file := range files {
+		if file.writer != nil {
+			// A file that cannot be completed cannot be uploaded again either
+			if err := file.writer.Close(); err != nil {
+				s.exp.metrics.archiveEventsDropped.Add(int64(file.events))
+				s.exp.logger.Error("Failed to close archive file, its events are not archived",
+					zap.String("prefix", file.prefix),
+					zap.Int("event_count", file.events),
+					zap.Error(err))
+				lost++
+				continue
+			}
+			file.writer = nil
+			file.key = s.key(file)
+		}
+		if err := s.put(ctx, file); err != nil {
+			s.exp.logger.Warn("Failed to upload archive file, it will be uploaded again",
+				zap.String("key", file.key),
+				zap.Int("event_count", file.events),
+				zap.Error(err))
+			failed = append(failed, file)
+			continue
+		}
+		s.exp.logger.Debug("Uploaded archive file",
+			zap.String("key", file.key),
+			zap.Int("event_count", file.events),
+			zap.Int("size", file.buffer.Len()))
+	}
+
+	if len(failed) > 0 {
+		// The failed files are older than the files that became pending meanwhile
+		s.mu.Lock()
+		s.pending = append(failed, s.pending...)
+		s.trimPending()
+		s.mu.Unlock()
+	}
+	return len(failed) + lost
+}
+
+// key returns the object key of file: its prefix followed by the time it was opened and a random
+// suffix, so files of the same prefix never overwrite each other. The key is chosen once, when
+// the file is closed, so an upload that is repeated replaces the same object.
+func (s *archiveSink) key(file *archiveFile) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := file.opened.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix) + ".ndjson"
+	switch s.config.Compression {
+	case archiveCompressionGzip:
+		name += ".gz"
+	case archiveCompressionZstd:
+		name += ".zst"
+	}
+	return file.prefix + name
+}
+
+// target returns the URL of key, in path or virtual-hosted style
+func (s *archiveSink) target(key string) (string, error) {
+	endpoint := s.config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.config.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid archive endpoint %q: %w", endpoint, err)
+	}
+
+	segments := strings.Split(key, "/")
+	if s.config.PathStyle {
+		segments = append([]string{s.config.Bucket}, segments...)
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	path, rawPath := strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.EscapedPath(), "/")
+	for _, segment := range segments {
+		path += "/" + segment
+		rawPath += "/" + sigV4Escape(segment)
+	}
+	u.Path, u.RawPath = path, rawPath
+	return u.String(), nil
+}
+
+// header returns the content, checksum, storage class and object lock headers of body
+func (s *archiveSink) header(body []byte, events int) http.Header {
+	header := http.Header{}
+	switch s.config.Compression {
+	case archiveCompressionGzip:
+		header.Set("Content-Type", "application/gzip")
+	case archiveCompressionZstd:
+		header.Set("Content-Type", "application/zstd")
+	default:
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	header.Set("X-Amz-Content-Sha256", sha256Hex(body))
+	header.Set("X-Amz-Meta-Event-Count", strconv.Itoa(events))
+
+	if name := archiveChecksums[s.config.Checksum]; name != "" {
+		var sum []byte
+		switch s.config.Checksum {
+		case "md5":
+			digest := md5.Sum(body)
+			sum = digest[:]
+		case "crc32":
+			sum = binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(body))
+		case "crc32c":
+			sum = binary.BigEndian.AppendUint32(nil, crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)))
+		default:
+			digest := sha256.Sum256(body)
+			sum = digest[:]
+		}
+		header.Set(name, base64.StdEncoding.EncodeToString(sum))
+	}
+	if s.config.StorageClass != "" {
+		header.Set("X-Amz-Storage-Class", s.config.StorageClass)
+	}
+	if lock := s.config.ObjectLock; lock.Mode != "" {
+		header.Set("X-Amz-Object-Lock-Mode", lock.Mode)
+		header.Set("X-Amz-Object-Lock-Retain-Until-Date", s.now().Add(lock.Retention).UTC().Format(time.RFC3339))
+	}
+	if s.config.ObjectLock.LegalHold {
+		header.Set("X-Amz-Object-Lock-Legal-Hold", "ON")
+	}
+	return header
+}
+
+// put uploads file with a signed PutObject request, or writes the would-be request in dry-run mode
+func (s *archiveSink) put(ctx context.Context, file *archiveFile) error {
+	target, err := s.target(file.key)
+	if err != nil {
+		return err
+	}
+	body := file.buffer.Bytes()
+	header := s.header(body, file.events)
+	if s.exp.dryRun != nil {
+		// The compressed file is not readable in the dry-run output; the headers and event count
+		// describe the upload
+		return s.exp.dryRun.writeRecord(http.MethodPut, target, header, nil, file.events)
+	}
+
+	creds, err := s.config.resolve()
+	if err != nil {
+		return err
+	}
+	if header, err = sigV4Header(http.MethodPut, target, header, body, creds, s3Service, s.now()); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create archive request: %w", err)
+	}
+	req.Header = header
+
+	resp, err := s.exp.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload archive file: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("archive upload failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// shutdown stops the age checks and uploads every open file
+func (s *archiveSink) shutdown(ctx context.Context) error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+	return s.flush(ctx, true)
+}
+
+// nopWriteCloser adds a no-op Close to an uncompressed file buffer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,512 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configoptional"
+)
+
+// s3Object is an object stored by the S3 stub
+type s3Object struct {
+	header http.Header
+	body   []byte
+}
+
+// s3Stub is a minimal path-style PutObject API that verifies SigV4 signatures and the SHA-256
+// checksum, and fails the next failUploads uploads
+type s3Stub struct {
+	creds       awsCredentials
+	failUploads int
+
+	mu      sync.Mutex
+	objects map[string]s3Object
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.verifySignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+	if checksum := r.Header.Get("X-Amz-Checksum-Sha256"); checksum != "" {
+		sum := sha256.Sum256(body)
+		if checksum != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, "<Error><Code>BadDigest</Code></Error>")
+			return
+		}
+	}
+	if s.failUploads > 0 {
+		s.failUploads--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if s.objects == nil {
+		s.objects = make(map[string]s3Object)
+	}
+	s.objects[r.URL.Path] = s3Object{header: r.Header.Clone(), body: body}
+}
+
+// verifySignature recomputes the request signature from the signed headers
+func (s *s3Stub) verifySignature(r *http.Request, body []byte) bool {
+	authorization := r.Header.Get("Authorization")
+	_, signedHeaders, ok := strings.Cut(authorization, "SignedHeaders=")
+	if !ok {
+		return false
+	}
+	signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+	now, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil || r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return false
+	}
+
+	header := http.Header{}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		switch name {
+		case "host", "x-amz-date", "x-amz-security-token":
+		default:
+			header.Set(name, r.Header.Get(name))
+		}
+	}
+	target := "http://" + r.Host + r.URL.RequestURI()
+	expected, err := sigV4Header(r.Method, target, header, body, s.creds, s3Service, now)
+	return err == nil && expected.Get("Authorization") == authorization
+}
+
+// snapshot returns the stored objects by path
+func (s *s3Stub) snapshot() map[string]s3Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := make(map[string]s3Object, len(s.objects))
+	for path, object := range s.objects {
+		objects[path] = object
+	}
+	return objects
+}
+
+// newTestArchiveExporter creates an exporter posting to primary that archives to a new S3 stub in
+// the bucket "archive"
+func newTestArchiveExporter(t *testing.T, primary string, configure func(cfg *ArchiveConfig)) (*securityEventExporter, *s3Stub) {
+	t.Helper()
+	stub := &s3Stub{creds: awsCredentials{
+		region:          "us-east-1",
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	archive := createDefaultArchiveConfig()
+	archive.Endpoint = server.URL
+	archive.Bucket = "archive"
+	archive.PathStyle = true
+	archive.Region = "us-east-1"
+	archive.AccessKeyID = "AKIDEXAMPLE"
+	archive.SecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	if configure != nil {
+		configure(&archive)
+	}
+	exp := newTestTransportExporter(t, transportHTTP, primary, func(cfg *Config) {
+		cfg.Archive = configoptional.Some(archive)
+	})
+
+	var err error
+	if exp.archive, err = newArchiveSink(exp); err != nil {
+		t.Fatalf("newArchiveSink() returned error: %v", err)
+	}
+	return exp, stub
+}
+
+// newTestPrimaryServer starts an endpoint answering every batch with status
+func newTestPrimaryServer(t *testing.T, status int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// archivedEvents decompresses an archived file and returns its events
+func archivedEvents(t *testing.T, body []byte, compression string) []map[string]interface{} {
+	t.Helper()
+	var reader io.Reader = bytes.NewReader(body)
+	switch compression {
+	case archiveCompressionGzip:
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			t.Fatalf("Archive file is not gzip: %v", err)
+		}
+		reader = gz
+	case archiveCompressionZstd:
+		zr, err := zstd.NewReader(reader)
+		if err != nil {
+			t.Fatalf("Archive file is not zstd: %v", err)
+		}
+		defer zr.Close()
+		reader = zr
+	}
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Archive line %q is not JSON: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read archive file: %v", err)
+	}
+	return events
+}
+
+func TestArchiveUploadsFullFiles(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), func(cfg *ArchiveConfig) {
+		cfg.MaxFileSize = 1
+	})
+
+	if err := exp.archive.start(context.Background(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("start() returned error: %v", err)
+	}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	// Full files are uploaded in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for len(stub.snapshot()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	objects := stub.snapshot()
+	if len(objects) != 2 {
+		t.Fatalf("Expected one file per event, got %d files", len(objects))
+	}
+	key := regexp.MustCompile(`^/archive/default/2023/11/14/22/\d{8}T\d{6}Z-[0-9a-f]{8}\.ndjson\.gz$`)
+	users := map[interface{}]bool{}
+	for path, object := range objects {
+		if !key.MatchString(path) {
+			t.Errorf("Unexpected object key %q", path)
+		}
+		if object.header.Get("Content-Type") != "application/gzip" || object.header.Get("X-Amz-Meta-Event-Count") != "1" {
+			t.Errorf("Unexpected object headers %v", object.header)
+		}
+		for _, event := range archivedEvents(t, object.body, archiveCompressionGzip) {
+			users[event["user.name"]] = true
+		}
+	}
+	if !users["alice"] || !users["bob"] {
+		t.Errorf("Expected the events of alice and bob to be archived, got %v", users)
+	}
+}
+
+func TestArchiveUploadsFilesByAge(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), func(cfg *ArchiveConfig) {
+		cfg.Prefix = "%{service.name}/%{+yyyy/MM/dd/HH}/"
+		cfg.Compression = archiveCompressionZstd
+		cfg.MaxFileAge = time.Minute
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exp.archive.now = func() time.Time { return now }
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := exp.archive.flush(context.Background(), false); err != nil || len(stub.snapshot()) != 0 {
+		t.Fatalf("Expected no upload before max_file_age, got %d files and %v", len(stub.snapshot()), err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := exp.archive.flush(context.Background(), false); err != nil {
+		t.Fatalf("flush() returned error: %v", err)
+	}
+	objects := stub.snapshot()
+	if len(objects) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(objects))
+	}
+	key := regexp.MustCompile(`^/archive/auth/2023/11/14/22/20240101T000000Z-[0-9a-f]{8}\.ndjson\.zst$`)
+	for path, object := range objects {
+		if !key.MatchString(path) {
+			t.Errorf("Unexpected object key %q", path)
+		}
+		if events := archivedEvents(t, object.body, archiveCompressionZstd); len(events) != 2 {
+			t.Errorf("Expected 2 events in the file, got %d", len(events))
+		}
+	}
+}
+
+func TestArchiveObjectLockAndChecksumHeaders(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), func(cfg *ArchiveConfig) {
+		cfg.Compression = archiveCompressionNone
+		cfg.Checksum = "md5"
+		cfg.StorageClass = "GLACIER_IR"
+		cfg.ObjectLock = ArchiveObjectLockConfig{Mode: "COMPLIANCE", Retention: 365 * 24 * time.Hour, LegalHold: true}
+	})
+	exp.archive.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := exp.archive.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() returned error: %v", err)
+	}
+
+	objects := stub.snapshot()
+	if len(objects) != 1 {
+		t.Fatalf("Expected the open file to be uploaded on shutdown, got %d files", len(objects))
+	}
+	for _, object := range objects {
+		sum := md5.Sum(object.body)
+		expected := map[string]string{
+			"Content-Type":                        "application/x-ndjson",
+			"Content-Md5":                         base64.StdEncoding.EncodeToString(sum[:]),
+			"X-Amz-Storage-Class":                 "GLACIER_IR",
+			"X-Amz-Object-Lock-Mode":              "COMPLIANCE",
+			"X-Amz-Object-Lock-Retain-Until-Date": "2024-12-31T00:00:00Z",
+			"X-Amz-Object-Lock-Legal-Hold":        "ON",
+		}
+		for name, value := range expected {
+			if got := object.header.Get(name); got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		}
+		if events := archivedEvents(t, object.body, archiveCompressionNone); len(events) != 1 {
+			t.Errorf("Expected 1 event, got %d", len(events))
+		}
+	}
+}
+
+func TestArchiveRetriesFailedUploads(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), nil)
+	stub.failUploads = 1
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("Expected archive failures not to fail delivery, got %v", err)
+	}
+	if err := exp.archive.flush(context.Background(), true); err == nil {
+		t.Fatal("Expected an error for the failed upload")
+	}
+	if err := exp.archive.flush(context.Background(), false); err != nil {
+		t.Fatalf("Expected the failed file to be uploaded at the next flush, got %v", err)
+	}
+	if objects := stub.snapshot(); len(objects) != 1 {
+		t.Errorf("Expected 1 file, got %d", len(objects))
+	}
+}
+
+func TestArchiveDropsOldestPendingFiles(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), func(cfg *ArchiveConfig) {
+		cfg.Compression = archiveCompressionNone
+		cfg.MaxFileSize = 1
+		cfg.MaxPendingSize = 1
+	})
+
+	// Without the background goroutine the full files stay pending
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol")); err != nil {
+		t.Fatalf("Expected archive failures not to fail delivery, got %v", err)
+	}
+	if len(exp.archive.pending) != 1 {
+		t.Fatalf("Expected only the newest file to be kept, got %d pending files", len(exp.archive.pending))
+	}
+	if dropped := exp.metrics.archiveEventsDropped.Load(); dropped != 2 {
+		t.Errorf("archiveEventsDropped = %d, want 2", dropped)
+	}
+
+	if err := exp.archive.flush(context.Background(), false); err != nil {
+		t.Fatalf("flush() returned error: %v", err)
+	}
+	objects := stub.snapshot()
+	if len(objects) != 1 {
+		t.Fatalf("Expected the newest file to be uploaded, got %d files", len(objects))
+	}
+	for _, object := range objects {
+		events := archivedEvents(t, object.body, archiveCompressionNone)
+		if len(events) != 1 || events[0]["user.name"] != "carol" {
+			t.Errorf("Expected the event of carol, got %v", events)
+		}
+	}
+}
+
+// failingCloser is an archive file writer whose Close fails
+type failingCloser struct {
+	io.Writer
+}
+
+func (failingCloser) Close() error {
+	return errors.New("compression failed")
+}
+
+func TestArchiveCountsFilesThatCannotBeClosed(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), nil)
+
+	file := &archiveFile{prefix: "default/", opened: time.Now(), events: 3}
+	file.writer = failingCloser{&file.buffer}
+	if failed := exp.archive.upload(context.Background(), []*archiveFile{file}); failed != 1 {
+		t.Errorf("upload() = %d, want 1 file not uploaded", failed)
+	}
+	if dropped := exp.metrics.archiveEventsDropped.Load(); dropped != 3 {
+		t.Errorf("archiveEventsDropped = %d, want 3", dropped)
+	}
+	if len(exp.archive.pending) != 0 || len(stub.snapshot()) != 0 {
+		t.Errorf("Expected the file to be neither kept nor uploaded, got %d pending and %d uploaded", len(exp.archive.pending), len(stub.snapshot()))
+	}
+}
+
+func TestArchiveSkipsEventsToBeRetried(t *testing.T) {
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusServiceUnavailable), nil)
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err == nil {
+		t.Fatal("Expected a retryable error from the primary endpoint")
+	}
+	if err := exp.archive.flush(context.Background(), true); err != nil {
+		t.Fatalf("flush() returned error: %v", err)
+	}
+	if objects := stub.snapshot(); len(objects) != 0 {
+		t.Errorf("Expected events to be archived once their retry completes, got %d files", len(objects))
+	}
+}
+
+func TestArchiveDryRun(t *testing.T) {
+	var out strings.Builder
+	exp, stub := newTestArchiveExporter(t, newTestPrimaryServer(t, http.StatusOK), nil)
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := exp.archive.flush(context.Background(), true); err != nil {
+		t.Fatalf("flush() returned error: %v", err)
+	}
+	if len(stub.snapshot()) != 0 {
+		t.Error("Expected no upload in dry-run mode")
+	}
+	var upload dryRunRequest
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &upload); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	if upload.Method != http.MethodPut || !strings.Contains(upload.URL, "/archive/default/2023/11/14/22/") || upload.EventCount != 1 {
+		t.Errorf("Unexpected dry-run upload: %s", lines[len(lines)-1])
+	}
+}
+
+func TestArchiveTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		want      string
+	}{
+		{name: "aws virtual-hosted", want: "https://archive.s3.eu-west-1.amazonaws.com/acme%20corp/2023/a%2Bb.ndjson"},
+		{name: "path style", endpoint: "http://minio:9000", pathStyle: true, want: "http://minio:9000/archive/acme%20corp/2023/a%2Bb.ndjson"},
+		{name: "endpoint path", endpoint: "https://storage.example.com/s3/", pathStyle: true, want: "https://storage.example.com/s3/archive/acme%20corp/2023/a%2Bb.ndjson"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &archiveSink{config: &ArchiveConfig{
+				Endpoint:             tt.endpoint,
+				Bucket:               "archive",
+				PathStyle:            tt.pathStyle,
+				AWSCredentialsConfig: AWSCredentialsConfig{Region: "eu-west-1"},
+			}}
+			got, err := sink.target("acme corp/2023/a+b.ndjson")
+			if err != nil || got != tt.want {
+				t.Errorf("target() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *ArchiveConfig)
+		errorMsg string
+	}{
+		{name: "valid", modify: func(*ArchiveConfig) {}},
+		{name: "missing bucket", modify: func(cfg *ArchiveConfig) {
+			cfg.Bucket = ""
+		}, errorMsg: "archive bucket is required"},
+		{name: "missing region", modify: func(cfg *ArchiveConfig) {
+			cfg.Region = ""
+		}, errorMsg: "archive region is required"},
+		{name: "invalid endpoint", modify: func(cfg *ArchiveConfig) {
+			cfg.Endpoint = "minio:9000"
+		}, errorMsg: "invalid archive endpoint"},
+		{name: "invalid prefix", modify: func(cfg *ArchiveConfig) {
+			cfg.Prefix = "%{tenant.id"
+		}, errorMsg: "unterminated placeholder"},
+		{name: "invalid compression", modify: func(cfg *ArchiveConfig) {
+			cfg.Compression = "bzip2"
+		}, errorMsg: "invalid archive compression"},
+		{name: "invalid file size", modify: func(cfg *ArchiveConfig) {
+			cfg.MaxFileSize = 0
+		}, errorMsg: "max_file_size must be positive"},
+		{name: "invalid file age", modify: func(cfg *ArchiveConfig) {
+			cfg.MaxFileAge = 0
+		}, errorMsg: "max_file_age must be positive"},
+		{name: "invalid pending size", modify: func(cfg *ArchiveConfig) {
+			cfg.MaxPendingSize = -1
+		}, errorMsg: "max_pending_size must be positive"},
+		{name: "invalid checksum", modify: func(cfg *ArchiveConfig) {
+			cfg.Checksum = "sha1"
+		}, errorMsg: "invalid archive checksum"},
+		{name: "invalid object lock mode", modify: func(cfg *ArchiveConfig) {
+			cfg.ObjectLock.Mode = "LEGAL"
+		}, errorMsg: "invalid archive object_lock mode"},
+		{name: "object lock without retention", modify: func(cfg *ArchiveConfig) {
+			cfg.ObjectLock.Mode = "GOVERNANCE"
+		}, errorMsg: "requires a positive retention"},
+		{name: "object lock without checksum", modify: func(cfg *ArchiveConfig) {
+			cfg.ObjectLock.LegalHold = true
+			cfg.Checksum = "none"
+		}, errorMsg: "archive object_lock requires a checksum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultArchiveConfig()
+			cfg.Bucket = "archive"
+			cfg.Region = "us-east-1"
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
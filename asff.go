@@ -0,0 +1,346 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// asffSchemaVersion is the AWS Security Finding Format version
+	asffSchemaVersion = "2018-10-08"
+
+	// asffTimeFormat is the ISO 8601 format of finding timestamps
+	asffTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+	// asffMaxProductFields is the maximum number of ProductFields entries of a finding
+	asffMaxProductFields = 50
+
+	// asffMaxProductFieldValue is the maximum length of a ProductFields value
+	asffMaxProductFieldValue = 2048
+)
+
+// asffAccountPattern matches AWS account IDs
+var asffAccountPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// asffTypeNamespaces are the namespaces finding types must start with
+var asffTypeNamespaces = map[string]bool{
+	"Software and Configuration Checks": true,
+	"TTPs":                              true,
+	"Effects":                           true,
+	"Unusual Behaviors":                 true,
+	"Sensitive Data Identifications":    true,
+}
+
+// asffSeverityLabels are the accepted Severity.Label values
+var asffSeverityLabels = map[string]bool{
+	"INFORMATIONAL": true,
+	"LOW":           true,
+	"MEDIUM":        true,
+	"HIGH":          true,
+	"CRITICAL":      true,
+}
+
+// ASFFConfig configures the "asff" encoding, which maps security events to AWS Security Hub
+// findings in the AWS Security Finding Format
+type ASFFConfig struct {
+	// ProductArn is the ARN of the product the findings are imported for. When empty the
+	// account's default product, arn:aws:securityhub:<region>:<account>:product/<account>/default,
+	// is used.
+	ProductArn string `mapstructure:"product_arn"`
+
+	// AWSAccountID is the account the finding belongs to
+	AWSAccountID FieldSource `mapstructure:"aws_account_id"`
+
+	// Region is the region of the finding's product ARN and resources
+	Region FieldSource `mapstructure:"region"`
+
+	// GeneratorID identifies the rule or detector that generated the finding
+	GeneratorID FieldSource `mapstructure:"generator_id"`
+
+	// Title is the finding title
+	Title FieldSource `mapstructure:"title"`
+
+	// Description is the finding description
+	Description FieldSource `mapstructure:"description"`
+
+	// TypeRules map log records to finding types. The first matching rule wins; when no rules are
+	// configured a built-in set keyed on event.category and event.kind is used. Records matching
+	// no rule get the type "Unusual Behaviors".
+	TypeRules []ASFFTypeRule `mapstructure:"type_rules"`
+
+	// IDAttributes are the attributes the finding Id is derived from, together with the account,
+	// the generator and the record timestamp. When empty all attributes are used, so identical
+	// records map to the same finding.
+	IDAttributes []string `mapstructure:"id_attributes"`
+
+	// SeverityMap overrides Severity.Label per OpenTelemetry severity family, for example
+	// ERROR: CRITICAL
+	SeverityMap map[string]string `mapstructure:"severity_map"`
+}
+
+// ASFFTypeRule maps matching log records to finding types
+type ASFFTypeRule struct {
+	// Types are finding types in namespace/category/classifier form, for example
+	// "TTPs/Credential Access"
+	Types []string `mapstructure:"types"`
+
+	// Match lists attribute values that must all be equal for the rule to apply. A value of "*"
+	// only requires the attribute to be present. An empty Match matches every record.
+	Match map[string]string `mapstructure:"match"`
+}
+
+// defaultASFFTypeRules are used when no type rules are configured
+var defaultASFFTypeRules = []ASFFTypeRule{
+	{Types: []string{"TTPs/Credential Access"}, Match: map[string]string{"event.category": "authentication", "event.outcome": "failure"}},
+	{Types: []string{"Unusual Behaviors/User"}, Match: map[string]string{"event.category": "authentication"}},
+	{Types: []string{"TTPs/Privilege Escalation"}, Match: map[string]string{"event.category": "iam"}},
+	{Types: []string{"TTPs/Execution"}, Match: map[string]string{"event.category": "process"}},
+	{Types: []string{"Effects/Data Exposure"}, Match: map[string]string{"event.category": "file"}},
+	{Types: []string{"Unusual Behaviors/Network Flow"}, Match: map[string]string{"event.category": "network"}},
+	{Types: []string{"Software and Configuration Checks/Vulnerabilities"}, Match: map[string]string{"event.category": "vulnerability"}},
+	{Types: []string{"TTPs"}, Match: map[string]string{"event.kind": "alert"}},
+}
+
+// Validate checks the product ARN, type rules and severity overrides
+func (cfg *ASFFConfig) Validate() error {
+	if cfg.ProductArn != "" && !strings.HasPrefix(cfg.ProductArn, "arn:") {
+		return fmt.Errorf("invalid asff product_arn %q: must be an ARN", cfg.ProductArn)
+	}
+	for i, rule := range cfg.TypeRules {
+		if len(rule.Types) == 0 {
+			return fmt.Errorf("asff type rule %d: at least one type is required", i)
+		}
+		for _, findingType := range rule.Types {
+			namespace, _, _ := strings.Cut(findingType, "/")
+			if !asffTypeNamespaces[namespace] {
+				return fmt.Errorf("asff type rule %d: type %q must start with a finding type namespace such as TTPs or Unusual Behaviors", i, findingType)
+			}
+		}
+	}
+	for family, label := range cfg.SeverityMap {
+		if !asffSeverityLabels[label] {
+			return fmt.Errorf("invalid asff severity_map label %q for %s: must be INFORMATIONAL, LOW, MEDIUM, HIGH or CRITICAL", label, family)
+		}
+	}
+	return nil
+}
+
+// createDefaultASFFConfig creates the default finding field sources
+func createDefaultASFFConfig() ASFFConfig {
+	return ASFFConfig{
+		AWSAccountID: FieldSource{Attributes: []string{"cloud.account.id"}},
+		Region:       FieldSource{Attributes: []string{"cloud.region"}},
+		GeneratorID:  FieldSource{Attributes: []string{"rule.id", "rule.name", "event.code", "event.action"}, Value: "opentelemetry-security-event"},
+		Title:        FieldSource{Attributes: []string{"rule.name", "event.name", "event.action"}, Value: "Security event"},
+		Description:  FieldSource{Attributes: []string{"rule.description", "event.reason"}, Value: "Security event reported by the OpenTelemetry Collector"},
+	}
+}
+
+// asffEncoder maps flat security events to AWS Security Finding Format findings
+type asffEncoder struct {
+	config *ASFFConfig
+	rules  []ASFFTypeRule
+}
+
+// newASFFEncoder creates an ASFF encoder from cfg
+func newASFFEncoder(cfg *ASFFConfig) (*asffEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	rules := cfg.TypeRules
+	if len(rules) == 0 {
+		rules = defaultASFFTypeRules
+	}
+	return &asffEncoder{config: cfg, rules: rules}, nil
+}
+
+// encode builds the finding for one record
+func (enc *asffEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	account := enc.config.AWSAccountID.resolve(event)
+	if !asffAccountPattern.MatchString(account) {
+		return nil, fmt.Errorf("asff finding needs a 12-digit AWS account ID, got %q", account)
+	}
+	region := enc.config.Region.resolve(event)
+	productArn := enc.config.ProductArn
+	if productArn == "" {
+		if region == "" {
+			return nil, fmt.Errorf("asff finding needs a region for the default product ARN")
+		}
+		productArn = fmt.Sprintf("arn:%s:securityhub:%s:%s:product/%s/default", awsPartition(region), region, account, account)
+	}
+
+	severity := map[string]interface{}{"Label": enc.severityLabel(severityNumber(logRecord))}
+	if text := logRecord.SeverityText(); text != "" {
+		severity["Original"] = text
+	}
+
+	generatorID := enc.config.GeneratorID.resolve(event)
+	timestamp := eventTime(logRecord).UTC()
+	observedAt := timestamp.Format(asffTimeFormat)
+	finding := map[string]interface{}{
+		"SchemaVersion":   asffSchemaVersion,
+		"Id":              enc.findingID(event, account, generatorID, timestamp),
+		"ProductArn":      productArn,
+		"GeneratorId":     generatorID,
+		"AwsAccountId":    account,
+		"Types":           enc.types(event),
+		"FirstObservedAt": observedAt,
+		"CreatedAt":       observedAt,
+		"UpdatedAt":       observedAt,
+		"Severity":        severity,
+		"Title":           truncateString(enc.config.Title.resolve(event), 256),
+		"Description":     truncateString(enc.config.Description.resolve(event), 1024),
+		"Resources":       asffResources(event, account, region),
+		"RecordState":     "ACTIVE",
+	}
+	if fields := asffProductFields(event); len(fields) > 0 {
+		finding["ProductFields"] = fields
+	}
+	return finding, nil
+}
+
+// findingID derives a deterministic finding Id, so the same record always updates the same
+// finding instead of creating a duplicate
+func (enc *asffEncoder) findingID(event map[string]interface{}, account, generatorID string, timestamp time.Time) string {
+	attributes := eventAttributes(event)
+	if len(enc.config.IDAttributes) > 0 {
+		selected := make(map[string]interface{}, len(enc.config.IDAttributes))
+		for _, key := range enc.config.IDAttributes {
+			if value, ok := event[key]; ok {
+				selected[key] = value
+			}
+		}
+		attributes = selected
+	}
+	// json.Marshal sorts map keys, which keeps the digest independent of map order
+	data, _ := json.Marshal([]interface{}{account, generatorID, timestamp.UnixNano(), attributes})
+	sum := sha256.Sum256(data)
+	return generatorID + "/" + hex.EncodeToString(sum[:16])
+}
+
+// types returns the finding types of the first rule matching event
+func (enc *asffEncoder) types(event map[string]interface{}) []string {
+	for _, rule := range enc.rules {
+		if matchesRule(event, rule.Match) {
+			return rule.Types
+		}
+	}
+	return []string{"Unusual Behaviors"}
+}
+
+// severityLabel maps a severity number to Severity.Label, applying the configured overrides
+func (enc *asffEncoder) severityLabel(number plog.SeverityNumber) string {
+	if label, ok := enc.config.SeverityMap[severityFamily(number)]; ok {
+		return label
+	}
+	if label, ok := udmSeverities[severityLevelOf(number)]; ok {
+		return label
+	}
+	return "INFORMATIONAL"
+}
+
+// asffResources describes the resources of a finding from cloud, host, container and Kubernetes
+// resource attributes. A finding always has at least one resource.
+func asffResources(event map[string]interface{}, account, region string) []interface{} {
+	partition := awsPartition(region)
+	newResource := func(resourceType, id string) map[string]interface{} {
+		resource := map[string]interface{}{"Type": resourceType, "Id": id, "Partition": partition}
+		if region != "" {
+			resource["Region"] = region
+		}
+		return resource
+	}
+
+	var resources []interface{}
+	if arn := stringField(event, "cloud.resource_id"); strings.HasPrefix(arn, "arn:") {
+		resources = append(resources, newResource("Other", arn))
+	}
+	if hostID := stringField(event, "host.id"); strings.HasPrefix(hostID, "i-") && region != "" {
+		resources = append(resources, newResource("AwsEc2Instance",
+			fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", partition, region, account, hostID)))
+	}
+	if cluster := stringField(event, "k8s.cluster.name"); cluster != "" && strings.EqualFold(stringField(event, "cloud.platform"), "aws_eks") && region != "" {
+		resources = append(resources, newResource("AwsEksCluster",
+			fmt.Sprintf("arn:%s:eks:%s:%s:cluster/%s", partition, region, account, cluster)))
+	}
+
+	container := firstField(event, "k8s.container.name", "container.name")
+	pod := stringField(event, "k8s.pod.name")
+	if container != "" || pod != "" {
+		id := firstField(event, "container.id", "k8s.pod.uid")
+		if id == "" {
+			id = strings.Trim(strings.Join([]string{stringField(event, "k8s.namespace.name"), pod, container}, "/"), "/")
+		}
+		resource := newResource("Container", id)
+		details := map[string]interface{}{}
+		if container != "" {
+			details["Name"] = container
+		} else {
+			details["Name"] = pod
+		}
+		if image := stringField(event, "container.image.name"); image != "" {
+			details["ImageName"] = image
+		}
+		resource["Details"] = map[string]interface{}{"Container": details}
+		tags := map[string]interface{}{}
+		for _, key := range []string{"k8s.cluster.name", "k8s.namespace.name", "k8s.pod.name", "k8s.deployment.name"} {
+			if value := stringField(event, key); value != "" {
+				tags[key] = value
+			}
+		}
+		if len(tags) > 0 {
+			resource["Tags"] = tags
+		}
+		resources = append(resources, resource)
+	}
+
+	if len(resources) == 0 {
+		id := firstField(event, "host.name", "service.name")
+		if id == "" {
+			id = "unknown"
+		}
+		resources = append(resources, newResource("Other", id))
+	}
+	return resources
+}
+
+// asffProductFields returns the event attributes as ProductFields, within the ASFF limits on
+// the number of entries and value length
+func asffProductFields(event map[string]interface{}) map[string]interface{} {
+	attributes := eventAttributes(event)
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) > asffMaxProductFields {
+		keys = keys[:asffMaxProductFields]
+	}
+
+	fields := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		fields[key] = truncateString(stringField(attributes, key), asffMaxProductFieldValue)
+	}
+	return fields
+}
+
+// awsPartition returns the partition of region
+func awsPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
@@ -0,0 +1,205 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestASFFEncoderBuildsFinding(t *testing.T) {
+	cfg := createDefaultASFFConfig()
+	enc, err := newASFFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newASFFEncoder() returned error: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"timestamp":        "2023-11-14T22:13:20Z",
+		"cloud.account.id": "123456789012",
+		"cloud.region":     "eu-west-1",
+		"host.id":          "i-0abc123",
+		"service.name":     "auth",
+		"user.name":        "alice",
+		"event.category":   "authentication",
+		"event.outcome":    "failure",
+		"rule.id":          "brute-force",
+		"rule.name":        "Repeated failed logins",
+	}
+	record := newUDMTestRecord()
+	record.SetSeverityText("WARN")
+
+	finding, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"SchemaVersion":   asffSchemaVersion,
+		"ProductArn":      "arn:aws:securityhub:eu-west-1:123456789012:product/123456789012/default",
+		"AwsAccountId":    "123456789012",
+		"GeneratorId":     "brute-force",
+		"Title":           "Repeated failed logins",
+		"CreatedAt":       "2023-11-14T22:13:20.000Z",
+		"FirstObservedAt": "2023-11-14T22:13:20.000Z",
+		"RecordState":     "ACTIVE",
+	}
+	for key, want := range expect {
+		if got := finding[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if !reflect.DeepEqual(finding["Types"], []string{"TTPs/Credential Access"}) {
+		t.Errorf("Types = %v, want [TTPs/Credential Access]", finding["Types"])
+	}
+	if !reflect.DeepEqual(finding["Severity"], map[string]interface{}{"Label": "MEDIUM", "Original": "WARN"}) {
+		t.Errorf("Severity = %v", finding["Severity"])
+	}
+	if id, _ := finding["Id"].(string); !strings.HasPrefix(id, "brute-force/") {
+		t.Errorf("Id = %q, want it prefixed with the generator", id)
+	}
+
+	resources, _ := finding["Resources"].([]interface{})
+	if len(resources) != 1 {
+		t.Fatalf("Expected one resource, got %v", finding["Resources"])
+	}
+	resource := resources[0].(map[string]interface{})
+	if resource["Type"] != "AwsEc2Instance" || resource["Id"] != "arn:aws:ec2:eu-west-1:123456789012:instance/i-0abc123" || resource["Region"] != "eu-west-1" {
+		t.Errorf("Unexpected resource: %v", resource)
+	}
+
+	fields, _ := finding["ProductFields"].(map[string]interface{})
+	if fields["user.name"] != "alice" {
+		t.Errorf("Expected user.name in ProductFields, got %v", fields)
+	}
+	if _, ok := fields["timestamp"]; ok {
+		t.Error("The converter timestamp should not be a product field")
+	}
+}
+
+func TestASFFEncoderFindingIDIsDeterministic(t *testing.T) {
+	cfg := createDefaultASFFConfig()
+	cfg.IDAttributes = []string{"user.name"}
+	enc, err := newASFFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newASFFEncoder() returned error: %v", err)
+	}
+
+	encode := func(event map[string]interface{}) string {
+		event["cloud.account.id"] = "123456789012"
+		event["cloud.region"] = "us-east-1"
+		finding, err := enc.encode(event, newUDMTestRecord(), pcommon.NewResource())
+		if err != nil {
+			t.Fatalf("encode() returned error: %v", err)
+		}
+		return finding["Id"].(string)
+	}
+
+	first := encode(map[string]interface{}{"user.name": "alice", "client.address": "10.0.0.1"})
+	if again := encode(map[string]interface{}{"user.name": "alice", "client.address": "10.0.0.2"}); again != first {
+		t.Errorf("Attributes outside id_attributes changed the Id: %s != %s", again, first)
+	}
+	if other := encode(map[string]interface{}{"user.name": "bob"}); other == first {
+		t.Error("Different id_attributes values should give different Ids")
+	}
+}
+
+func TestASFFEncoderContainerResourceAndOverrides(t *testing.T) {
+	cfg := createDefaultASFFConfig()
+	cfg.ProductArn = "arn:aws:securityhub:us-east-1:123456789012:product/123456789012/default"
+	cfg.TypeRules = []ASFFTypeRule{{Types: []string{"Effects/Resource Consumption"}, Match: map[string]string{"event.action": "*"}}}
+	cfg.SeverityMap = map[string]string{"WARN": "HIGH"}
+	enc, err := newASFFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newASFFEncoder() returned error: %v", err)
+	}
+
+	finding, err := enc.encode(map[string]interface{}{
+		"cloud.account.id":     "123456789012",
+		"k8s.cluster.name":     "prod",
+		"k8s.namespace.name":   "payments",
+		"k8s.pod.name":         "api-7d9f",
+		"k8s.container.name":   "api",
+		"container.id":         "3f1c",
+		"container.image.name": "registry/api",
+		"event.action":         "exec",
+	}, newUDMTestRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(finding["Types"], []string{"Effects/Resource Consumption"}) {
+		t.Errorf("Types = %v", finding["Types"])
+	}
+	if label := finding["Severity"].(map[string]interface{})["Label"]; label != "HIGH" {
+		t.Errorf("Severity.Label = %v, want HIGH from severity_map", label)
+	}
+	resource := finding["Resources"].([]interface{})[0].(map[string]interface{})
+	if resource["Type"] != "Container" || resource["Id"] != "3f1c" {
+		t.Errorf("Unexpected resource: %v", resource)
+	}
+	details := resource["Details"].(map[string]interface{})["Container"].(map[string]interface{})
+	if details["Name"] != "api" || details["ImageName"] != "registry/api" {
+		t.Errorf("Unexpected container details: %v", details)
+	}
+	if tags := resource["Tags"].(map[string]interface{}); tags["k8s.namespace.name"] != "payments" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
+
+func TestASFFEncoderRequiresAccount(t *testing.T) {
+	cfg := createDefaultASFFConfig()
+	enc, err := newASFFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newASFFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	if _, err := enc.encode(map[string]interface{}{"cloud.region": "us-east-1"}, record, pcommon.NewResource()); err == nil || !strings.Contains(err.Error(), "AWS account ID") {
+		t.Errorf("Expected missing account error, got %v", err)
+	}
+	if _, err := enc.encode(map[string]interface{}{"cloud.account.id": "123456789012"}, record, pcommon.NewResource()); err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("Expected missing region error, got %v", err)
+	}
+}
+
+func TestASFFConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *ASFFConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*ASFFConfig) {}},
+		{name: "invalid product arn", modify: func(cfg *ASFFConfig) {
+			cfg.ProductArn = "securityhub/default"
+		}, errorMsg: "must be an ARN"},
+		{name: "rule without types", modify: func(cfg *ASFFConfig) {
+			cfg.TypeRules = []ASFFTypeRule{{Match: map[string]string{"event.kind": "alert"}}}
+		}, errorMsg: "at least one type is required"},
+		{name: "unknown type namespace", modify: func(cfg *ASFFConfig) {
+			cfg.TypeRules = []ASFFTypeRule{{Types: []string{"Intrusion/Login"}}}
+		}, errorMsg: "must start with a finding type namespace"},
+		{name: "invalid severity label", modify: func(cfg *ASFFConfig) {
+			cfg.SeverityMap = map[string]string{"ERROR": "SEVERE"}
+		}, errorMsg: "invalid asff severity_map label"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultASFFConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
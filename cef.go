@@ -0,0 +1,334 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// unmappedKeep keeps attributes without a configured key, with the key sanitized
+	unmappedKeep = "keep"
+
+	// unmappedDrop drops attributes without a configured key
+	unmappedDrop = "drop"
+)
+
+// CEFConfig configures the CEF encoding
+type CEFConfig struct {
+	// DeviceVendor is the Device Vendor header field
+	DeviceVendor FieldSource `mapstructure:"device_vendor"`
+
+	// DeviceProduct is the Device Product header field
+	DeviceProduct FieldSource `mapstructure:"device_product"`
+
+	// DeviceVersion is the Device Version header field
+	DeviceVersion FieldSource `mapstructure:"device_version"`
+
+	// SignatureID is the Signature ID (Device Event Class ID) header field
+	SignatureID FieldSource `mapstructure:"signature_id"`
+
+	// Name is the Name header field
+	Name FieldSource `mapstructure:"name"`
+
+	// SeverityMap overrides the severity (0-10) per OpenTelemetry severity family, for example
+	// ERROR: 8. Unlisted families use the shared linear mapping.
+	SeverityMap map[string]int `mapstructure:"severity_map"`
+
+	// Extensions maps attribute keys to CEF extension keys, overriding or extending the built-in
+	// mapping (for example client.address to src). An empty key drops the attribute.
+	Extensions map[string]string `mapstructure:"extensions"`
+
+	// UnmappedAttributes controls attributes without an extension key: "keep" (default) adds them
+	// with the key reduced to letters, digits and underscores, "drop" removes them
+	UnmappedAttributes string `mapstructure:"unmapped_attributes"`
+}
+
+// defaultCEFExtensions maps semantic convention attributes to standard CEF extension keys
+var defaultCEFExtensions = map[string]string{
+	"client.address":            "src",
+	"source.address":            "src",
+	"source.ip":                 "src",
+	"client.port":               "spt",
+	"source.port":               "spt",
+	"server.address":            "dst",
+	"destination.address":       "dst",
+	"destination.ip":            "dst",
+	"server.port":               "dpt",
+	"destination.port":          "dpt",
+	"network.transport":         "proto",
+	"user.name":                 "suser",
+	"user.id":                   "suid",
+	"enduser.id":                "suser",
+	"destination.user.name":     "duser",
+	"host.name":                 "dvchost",
+	"host.ip":                   "dvc",
+	"url.full":                  "request",
+	"http.request.method":       "requestMethod",
+	"user_agent.original":       "requestClientApplication",
+	"event.action":              "act",
+	"event.outcome":             "outcome",
+	"file.name":                 "fname",
+	"file.path":                 "filePath",
+	"file.size":                 "fsize",
+	"process.pid":               "spid",
+	"process.executable.name":   "sproc",
+	"service.name":              "deviceProcessName",
+	"k8s.namespace.name":        "cs1",
+	"trace_id":                  "externalId",
+	"http.response.status_code": "cn1",
+}
+
+// defaultCEFExtensionLabels labels the custom extension fields used by the built-in mapping
+var defaultCEFExtensionLabels = map[string]string{
+	"cs1": "k8sNamespace",
+	"cn1": "httpStatusCode",
+}
+
+// defaultExtensionPrecedence orders the attributes the built-in CEF and LEEF mappings send to the
+// same key, most specific first. Earlier attributes win when an event has several of them.
+var defaultExtensionPrecedence = []string{
+	"source.ip", "source.address", "client.address",
+	"source.port", "client.port",
+	"destination.ip", "destination.address", "server.address",
+	"destination.port", "server.port",
+	"user.name", "enduser.id",
+}
+
+// extensionRankUnmapped ranks unmapped attributes below every mapped attribute
+const extensionRankUnmapped = math.MaxInt
+
+// extensionMapping maps event attributes to CEF extension or LEEF attribute keys. Several
+// attributes can map to the same key, and a sanitized unmapped key can equal a mapped one; the
+// attribute with the lowest rank wins, so the key's value does not depend on map iteration order.
+type extensionMapping struct {
+	keys         map[string]string
+	ranks        map[string]int
+	dropUnmapped bool
+}
+
+// newExtensionMapping merges the configured mapping over the built-in one. Configured attributes
+// rank first, then the built-in attributes in defaultExtensionPrecedence order, then the other
+// built-in attributes.
+func newExtensionMapping(defaults, configured map[string]string, unmapped string) *extensionMapping {
+	m := &extensionMapping{
+		keys:         make(map[string]string, len(defaults)+len(configured)),
+		ranks:        make(map[string]int, len(defaults)+len(configured)),
+		dropUnmapped: unmapped == unmappedDrop,
+	}
+	for attribute, key := range defaults {
+		m.keys[attribute] = key
+		m.ranks[attribute] = 1 + len(defaultExtensionPrecedence)
+	}
+	for i, attribute := range defaultExtensionPrecedence {
+		if _, ok := defaults[attribute]; ok {
+			m.ranks[attribute] = 1 + i
+		}
+	}
+	for attribute, key := range configured {
+		m.keys[attribute] = key
+		m.ranks[attribute] = 0
+	}
+	return m
+}
+
+// resolve returns the attribute chosen for every key the event's attributes map to. Attributes are
+// visited in sorted order, so equally ranked attributes resolve to the one sorting first.
+func (m *extensionMapping) resolve(event map[string]interface{}) map[string]string {
+	attributes := make([]string, 0, len(event))
+	for attribute := range event {
+		if attribute != "timestamp" && attribute != "span_id" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	sort.Strings(attributes)
+
+	chosen := make(map[string]string, len(attributes))
+	ranks := make(map[string]int, len(attributes))
+	for _, attribute := range attributes {
+		key, rank := m.key(attribute)
+		if key == "" {
+			continue
+		}
+		if current, taken := ranks[key]; taken && current <= rank {
+			continue
+		}
+		chosen[key], ranks[key] = attribute, rank
+	}
+	return chosen
+}
+
+// key returns the key and rank of an attribute. The key is empty for dropped attributes.
+func (m *extensionMapping) key(attribute string) (string, int) {
+	if key, mapped := m.keys[attribute]; mapped {
+		return key, m.ranks[attribute]
+	}
+	if m.dropUnmapped {
+		return "", extensionRankUnmapped
+	}
+	return sanitizeExtensionKey(attribute), extensionRankUnmapped
+}
+
+// mapped reports whether attribute has a built-in or configured key
+func (m *extensionMapping) mapped(attribute string) bool {
+	_, ok := m.keys[attribute]
+	return ok
+}
+
+// Validate checks severity overrides and the unmapped attribute handling
+func (cfg *CEFConfig) Validate() error {
+	var errs []error
+	if err := validateSeverityMap(cfg.SeverityMap, 0, 10); err != nil {
+		errs = append(errs, fmt.Errorf("cef: %w", err))
+	}
+	switch cfg.UnmappedAttributes {
+	case "", unmappedKeep, unmappedDrop:
+	default:
+		errs = append(errs, fmt.Errorf("invalid cef unmapped_attributes %q: must be %q or %q", cfg.UnmappedAttributes, unmappedKeep, unmappedDrop))
+	}
+	for attribute, key := range cfg.Extensions {
+		if key != "" && sanitizeExtensionKey(key) != key {
+			errs = append(errs, fmt.Errorf("cef extension key %q for %q must contain only letters, digits and underscores", key, attribute))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultCEFConfig creates the default CEF header field sources
+func createDefaultCEFConfig() CEFConfig {
+	return CEFConfig{
+		DeviceVendor:       FieldSource{Value: "OpenTelemetry"},
+		DeviceProduct:      FieldSource{Attributes: []string{"service.name"}, Value: "SecurityEventExporter"},
+		DeviceVersion:      FieldSource{Attributes: []string{"service.version"}, Value: "1.0"},
+		SignatureID:        FieldSource{Attributes: []string{"event.code", "rule.id", "event.action"}, Value: "security-event"},
+		Name:               FieldSource{Attributes: []string{"event.name", "event.action", "rule.name"}, Value: "Security Event"},
+		UnmappedAttributes: unmappedKeep,
+	}
+}
+
+// cefEncoder renders security events as CEF lines
+type cefEncoder struct {
+	config     *CEFConfig
+	extensions *extensionMapping
+}
+
+// newCEFEncoder creates a CEF encoder from cfg
+func newCEFEncoder(cfg *CEFConfig) (*cefEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	extensions := newExtensionMapping(defaultCEFExtensions, cfg.Extensions, cfg.UnmappedAttributes)
+	return &cefEncoder{config: cfg, extensions: extensions}, nil
+}
+
+// encode builds the structured CEF event: the header fields and the extension key/value pairs.
+// renderLine turns it into the CEF line.
+func (enc *cefEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	extension := map[string]interface{}{
+		"rt": strconv.FormatInt(eventTime(logRecord).UnixMilli(), 10),
+	}
+
+	chosen := enc.extensions.resolve(event)
+	for extensionKey, attribute := range chosen {
+		extension[extensionKey] = fmt.Sprintf("%v", event[attribute])
+	}
+	// Labels are set last so an unmapped attribute named like a label cannot replace them
+	for extensionKey, attribute := range chosen {
+		if label, ok := defaultCEFExtensionLabels[extensionKey]; ok && enc.extensions.mapped(attribute) {
+			extension[extensionKey+"Label"] = label
+		}
+	}
+
+	return map[string]interface{}{
+		"version":        0,
+		"device_vendor":  enc.config.DeviceVendor.resolve(event),
+		"device_product": enc.config.DeviceProduct.resolve(event),
+		"device_version": enc.config.DeviceVersion.resolve(event),
+		"signature_id":   enc.config.SignatureID.resolve(event),
+		"name":           enc.config.Name.resolve(event),
+		"severity":       severityScore(severityNumber(logRecord), enc.config.SeverityMap),
+		"extension":      extension,
+	}, nil
+}
+
+// renderLine renders a structured CEF event as
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func (enc *cefEncoder) renderLine(event map[string]interface{}) (string, error) {
+	var b strings.Builder
+	b.WriteString("CEF:")
+	b.WriteString(stringField(event, "version"))
+	for _, field := range []string{"device_vendor", "device_product", "device_version", "signature_id", "name", "severity"} {
+		b.WriteByte('|')
+		b.WriteString(escapeCEFHeader(stringField(event, field)))
+	}
+	b.WriteByte('|')
+
+	extension, _ := event["extension"].(map[string]interface{})
+	keys := make([]string, 0, len(extension))
+	for key := range extension {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(escapeCEFExtension(fmt.Sprintf("%v", extension[key])))
+	}
+	return b.String(), nil
+}
+
+// cefHeaderEscaper escapes backslashes and pipes in header fields, which cannot span lines
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// cefExtensionEscaper escapes backslashes, equal signs and line breaks in extension values
+var cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+// escapeCEFHeader escapes a CEF header field
+func escapeCEFHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+// escapeCEFExtension escapes a CEF extension value
+func escapeCEFExtension(value string) string {
+	return cefExtensionEscaper.Replace(value)
+}
+
+// sanitizeExtensionKey reduces key to letters, digits and underscores, the characters CEF and
+// LEEF parsers accept in extension keys
+func sanitizeExtensionKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// validateSeverityMap checks that overrides use severity family names and scores within [min, max]
+func validateSeverityMap(severityMap map[string]int, min, max int) error {
+	for family, score := range severityMap {
+		switch family {
+		case "TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL":
+		default:
+			return fmt.Errorf("severity_map key %q must be one of TRACE, DEBUG, INFO, WARN, ERROR or FATAL", family)
+		}
+		if score < min || score > max {
+			return fmt.Errorf("severity_map value %d for %s must be between %d and %d", score, family, min, max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestCEFEncoderRendersLine(t *testing.T) {
+	cfg := createDefaultCEFConfig()
+	enc, err := newCEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newCEFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	record.SetSeverityNumber(plog.SeverityNumberError)
+
+	event := map[string]interface{}{
+		"event.action":       "login",
+		"event.outcome":      "failure",
+		"client.address":     "10.0.0.7",
+		"user.name":          "alice",
+		"k8s.namespace.name": "auth",
+		"tenant.id":          "acme",
+		"timestamp":          "2023-11-14T22:13:20Z",
+	}
+
+	encoded, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	line, err := enc.renderLine(encoded)
+	if err != nil {
+		t.Fatalf("renderLine() returned error: %v", err)
+	}
+
+	expected := "CEF:0|OpenTelemetry|SecurityEventExporter|1.0|login|login|7|" +
+		"act=login cs1=auth cs1Label=k8sNamespace outcome=failure rt=1700000000000 src=10.0.0.7 suser=alice tenant_id=acme"
+	if line != expected {
+		t.Errorf("renderLine() =\n%s\nwant\n%s", line, expected)
+	}
+}
+
+func TestCEFEncoderConfiguration(t *testing.T) {
+	cfg := createDefaultCEFConfig()
+	cfg.DeviceVendor = FieldSource{Attributes: []string{"vendor"}, Value: "Acme"}
+	cfg.SignatureID = FieldSource{Attributes: []string{"rule.id"}, Value: "generic"}
+	cfg.SeverityMap = map[string]int{"WARN": 6}
+	cfg.Extensions = map[string]string{"tenant.id": "cs2", "user.name": ""}
+	cfg.UnmappedAttributes = unmappedDrop
+	enc, err := newCEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newCEFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetSeverityNumber(plog.SeverityNumberWarn)
+	event := map[string]interface{}{
+		"rule.id":   "R-42",
+		"tenant.id": "acme",
+		"user.name": "alice",
+		"other":     "dropped",
+	}
+
+	encoded, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	if encoded["device_vendor"] != "Acme" || encoded["signature_id"] != "R-42" || encoded["severity"] != 6 {
+		t.Errorf("Unexpected header fields: %v", encoded)
+	}
+
+	extension := encoded["extension"].(map[string]interface{})
+	if extension["cs2"] != "acme" {
+		t.Errorf("Expected tenant.id in cs2, got %v", extension)
+	}
+	for _, key := range []string{"suser", "other"} {
+		if _, ok := extension[key]; ok {
+			t.Errorf("Expected %s to be dropped, got %v", key, extension)
+		}
+	}
+}
+
+func TestCEFExtensionKeyCollisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions map[string]string
+		event      map[string]interface{}
+		expected   map[string]string
+	}{
+		{
+			name: "built-in precedence",
+			event: map[string]interface{}{
+				"client.address": "10.0.0.1",
+				"source.address": "10.0.0.2",
+				"source.ip":      "10.0.0.3",
+				"enduser.id":     "svc-account",
+				"user.name":      "alice",
+			},
+			expected: map[string]string{"src": "10.0.0.3", "suser": "alice"},
+		},
+		{
+			name: "mapped beats unmapped",
+			event: map[string]interface{}{
+				"client.address":     "10.0.0.1",
+				"src":                "spoofed",
+				"cs1Label":           "spoofed",
+				"k8s.namespace.name": "auth",
+			},
+			expected: map[string]string{"src": "10.0.0.1", "cs1": "auth", "cs1Label": "k8sNamespace"},
+		},
+		{
+			name:       "configured beats built-in",
+			extensions: map[string]string{"client.address": "src"},
+			event: map[string]interface{}{
+				"client.address": "10.0.0.1",
+				"source.ip":      "10.0.0.3",
+			},
+			expected: map[string]string{"src": "10.0.0.1"},
+		},
+		{
+			name: "unmapped keys in sorted order",
+			event: map[string]interface{}{
+				"tenant.id": "acme",
+				"tenant_id": "other",
+			},
+			expected: map[string]string{"tenant_id": "acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultCEFConfig()
+			cfg.Extensions = tt.extensions
+			enc, err := newCEFEncoder(&cfg)
+			if err != nil {
+				t.Fatalf("newCEFEncoder() returned error: %v", err)
+			}
+
+			// Map iteration order varies between runs, so encode repeatedly
+			for i := 0; i < 50; i++ {
+				encoded, err := enc.encode(tt.event, plog.NewLogRecord(), pcommon.NewResource())
+				if err != nil {
+					t.Fatalf("encode() returned error: %v", err)
+				}
+				extension := encoded["extension"].(map[string]interface{})
+				for key, want := range tt.expected {
+					if extension[key] != want {
+						t.Fatalf("Extension %s = %v, want %s", key, extension[key], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCEFEscaping(t *testing.T) {
+	cfg := createDefaultCEFConfig()
+	enc, err := newCEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newCEFEncoder() returned error: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"event.name": "a|b\\c\nd",
+		"url.full":   "https://example.com/?q=1\\2\r\nx",
+	}
+	encoded, err := enc.encode(event, plog.NewLogRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	line, err := enc.renderLine(encoded)
+	if err != nil {
+		t.Fatalf("renderLine() returned error: %v", err)
+	}
+
+	if !strings.Contains(line, `|a\|b\\c d|`) {
+		t.Errorf("Header field not escaped: %s", line)
+	}
+	if !strings.Contains(line, `request=https://example.com/?q\=1\\2\nx`) {
+		t.Errorf("Extension value not escaped: %s", line)
+	}
+	if strings.ContainsAny(line, "\r\n") {
+		t.Errorf("Line contains line breaks: %q", line)
+	}
+}
+
+func TestCEFConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      CEFConfig
+		errorMsg string
+	}{
+		{name: "defaults", cfg: createDefaultCEFConfig()},
+		{name: "unknown severity family", cfg: CEFConfig{SeverityMap: map[string]int{"CRITICAL": 9}}, errorMsg: "severity_map key"},
+		{name: "severity out of range", cfg: CEFConfig{SeverityMap: map[string]int{"ERROR": 11}}, errorMsg: "between 0 and 10"},
+		{name: "invalid unmapped handling", cfg: CEFConfig{UnmappedAttributes: "labels"}, errorMsg: "unmapped_attributes"},
+		{name: "invalid extension key", cfg: CEFConfig{Extensions: map[string]string{"tenant.id": "tenant id"}}, errorMsg: "extension key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestMarshalBatchRendersLines(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Encoding = encodingCEF
+	encoder, err := newEventEncoder(cfg)
+	if err != nil {
+		t.Fatalf("newEventEncoder() returned error: %v", err)
+	}
+	exp := &securityEventExporter{config: cfg, encoder: encoder}
+
+	events := make([]map[string]interface{}, 2)
+	for i := range events {
+		if events[i], err = encoder.encode(map[string]interface{}{"user.name": "alice"}, plog.NewLogRecord(), pcommon.NewResource()); err != nil {
+			t.Fatalf("encode() returned error: %v", err)
+		}
+	}
+
+	body, contentType, err := exp.marshalBatch(events)
+	if err != nil {
+		t.Fatalf("marshalBatch() returned error: %v", err)
+	}
+	if contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", contentType)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "CEF:0|") {
+		t.Errorf("Unexpected body: %q", body)
+	}
+
+	exp.encoder = nil
+	if _, contentType, _ = exp.marshalBatch(events); contentType != "application/json" {
+		t.Errorf("Unexpected content type %q for JSON", contentType)
+	}
+}
@@ -0,0 +1,264 @@
+package exporter
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+const (
+	// chronicleBatchCreatePath is the UDM events batch ingestion method, relative to the endpoint
+	chronicleBatchCreatePath = "/v2/udmevents:batchCreate"
+
+	// chronicleMaxBody is the ingestion API limit on the size of one batchCreate request
+	chronicleMaxBody = 1 << 20
+
+	// chronicleJWTBearerGrant is the OAuth 2.0 grant type of service account assertions
+	chronicleJWTBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// chronicleAssertionLifetime is the lifetime of the signed service account assertion
+	chronicleAssertionLifetime = time.Hour
+)
+
+// ChronicleConfig configures the Google Security Operations (Chronicle) transport. The endpoint is
+// the regional ingestion API, for example https://malachiteingestion-pa.googleapis.com or
+// https://europe-malachiteingestion-pa.googleapis.com.
+type ChronicleConfig struct {
+	// CustomerID is the Chronicle customer (instance) ID
+	CustomerID string `mapstructure:"customer_id"`
+
+	// CredentialsFile is the path of the service account JSON key
+	CredentialsFile string `mapstructure:"credentials_file"`
+
+	// Credentials is the service account JSON key itself, as an alternative to CredentialsFile
+	Credentials configopaque.String `mapstructure:"credentials"`
+
+	// Scope is the OAuth 2.0 scope requested for the service account
+	Scope string `mapstructure:"scope"`
+}
+
+// Validate checks that at most one credentials source is configured. The customer and the
+// credentials are only required when the transport is selected, which validateTransport checks.
+func (cfg *ChronicleConfig) Validate() error {
+	if cfg.CredentialsFile != "" && cfg.Credentials != "" {
+		return errors.New("chronicle credentials_file and credentials are mutually exclusive")
+	}
+	return nil
+}
+
+// validateDestination checks the settings needed to send
+func (cfg *ChronicleConfig) validateDestination() error {
+	if cfg.CustomerID == "" {
+		return errors.New("chronicle customer_id is required")
+	}
+	if cfg.CredentialsFile == "" && cfg.Credentials == "" {
+		return errors.New("chronicle credentials_file or credentials is required")
+	}
+	return nil
+}
+
+// createDefaultChronicleConfig creates the default Chronicle ingestion scope
+func createDefaultChronicleConfig() ChronicleConfig {
+	return ChronicleConfig{
+		Scope: "https://www.googleapis.com/auth/malachite-ingestion",
+	}
+}
+
+// serviceAccountKey is the part of a Google service account JSON key used to sign assertions
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// chronicleTransport posts UDM events to the udmevents:batchCreate API, splitting batches that
+// exceed its request size limit
+type chronicleTransport struct {
+	exp         *securityEventExporter
+	config      *ChronicleConfig
+	target      string
+	maxBodySize int
+
+	key         serviceAccountKey
+	signer      *rsa.PrivateKey
+	accessToken accessToken
+}
+
+// newChronicleTransport creates the Chronicle transport of e
+func newChronicleTransport(e *securityEventExporter) (*chronicleTransport, error) {
+	cfg := &e.config.Chronicle
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateDestination(); err != nil {
+		return nil, err
+	}
+	if _, ok := e.encoder.(*udmEncoder); !ok {
+		return nil, fmt.Errorf("the chronicle transport requires the %q encoding", encodingUDM)
+	}
+
+	target, err := url.JoinPath(e.config.Endpoint, chronicleBatchCreatePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	return &chronicleTransport{exp: e, config: cfg, target: target, maxBodySize: chronicleMaxBody}, nil
+}
+
+// start loads the service account key
+func (t *chronicleTransport) start(context.Context, component.Host) error {
+	data := []byte(t.config.Credentials)
+	if t.config.CredentialsFile != "" {
+		var err error
+		if data, err = os.ReadFile(t.config.CredentialsFile); err != nil {
+			return fmt.Errorf("failed to read chronicle credentials: %w", err)
+		}
+	}
+	if err := json.Unmarshal(data, &t.key); err != nil {
+		return fmt.Errorf("failed to parse chronicle credentials: %w", err)
+	}
+	if t.key.Type != "service_account" || t.key.ClientEmail == "" || t.key.TokenURI == "" {
+		return errors.New("chronicle credentials are not a service account key")
+	}
+	signer, err := parseRSAPrivateKey(t.key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("invalid chronicle service account private key: %w", err)
+	}
+	t.signer = signer
+	return nil
+}
+
+// send posts the batch in as many requests as the API's size limit requires
+func (t *chronicleTransport) send(ctx context.Context, events []map[string]interface{}, _ []eventSource) error {
+	records := make([]json.RawMessage, 0, len(events))
+	for _, event := range events {
+		record, err := json.Marshal(event)
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(fmt.Errorf("failed to marshal udm event: %w", err))
+		}
+		records = append(records, record)
+	}
+
+	customerID, err := json.Marshal(t.config.CustomerID)
+	if err != nil {
+		return consumererror.NewPermanent(err)
+	}
+	prefix := `{"customer_id":` + string(customerID) + `,"events":`
+	chunks, err := chunkRecords(records, t.maxBodySize-len(prefix)-len("}"), 0)
+	if err != nil {
+		t.exp.metrics.httpErrors.Add(1)
+		return consumererror.NewPermanent(fmt.Errorf("chronicle udm event %w", err))
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	// Dry runs do not request tokens; the Authorization header would be masked anyway
+	if !t.exp.config.isDryRun() {
+		token, err := t.token(ctx)
+		if err != nil {
+			return err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	// The events of the chunks before a failing one were accepted and are not retried
+	sent := 0
+	for _, chunk := range chunks {
+		eventsJSON, err := json.Marshal(chunk)
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return unsentError(consumererror.NewPermanent(fmt.Errorf("failed to marshal udm events: %w", err)), sent, len(records))
+		}
+		body := make([]byte, 0, len(prefix)+len(eventsJSON)+1)
+		body = append(append(append(body, prefix...), eventsJSON...), '}')
+		if _, err := t.exp.postBatch(ctx, t.target, body, header, len(chunk)); err != nil {
+			return unsentError(err, sent, len(records))
+		}
+		sent += len(chunk)
+	}
+	return nil
+}
+
+// shutdown has nothing to close
+func (t *chronicleTransport) shutdown(context.Context) error {
+	return nil
+}
+
+// token returns the cached access token of the service account, exchanging a newly signed
+// assertion for one when needed
+func (t *chronicleTransport) token(ctx context.Context) (string, error) {
+	return t.accessToken.get(ctx, func(ctx context.Context) (string, time.Duration, error) {
+		assertion, err := t.assertion(time.Now())
+		if err != nil {
+			return "", 0, consumererror.NewPermanent(err)
+		}
+		return t.exp.requestAccessToken(ctx, t.key.TokenURI, url.Values{
+			"grant_type": {chronicleJWTBearerGrant},
+			"assertion":  {assertion},
+		})
+	})
+}
+
+// assertion returns the RS256-signed JWT asserting the service account identity at now
+func (t *chronicleTransport) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": t.key.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   t.key.ClientEmail,
+		"scope": t.config.Scope,
+		"aud":   t.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(chronicleAssertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded PKCS#8 or PKCS#1 RSA private key
+func parseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,295 @@
+package exporter
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// chronicleStub is a minimal Google token endpoint and Chronicle ingestion API that verifies
+// service account assertions and records the UDM events it accepts
+type chronicleStub struct {
+	publicKey *rsa.PublicKey
+	tokenURL  string
+
+	mu            sync.Mutex
+	tokenRequests int
+	requests      int
+	customerIDs   []string
+	events        []map[string]interface{}
+
+	// failRequest is the 1-based number of a batchCreate request answered with 503
+	failRequest int
+	attempts    int
+}
+
+func (s *chronicleStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/token":
+		form, err := url.ParseQuery(string(body))
+		if err != nil || form.Get("grant_type") != chronicleJWTBearerGrant || !s.verifyAssertion(form.Get("assertion")) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		s.tokenRequests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "ya29.test", "expires_in": 3599, "token_type": "Bearer"})
+	case chronicleBatchCreatePath:
+		if r.Header.Get("Authorization") != "Bearer ya29.test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.attempts++
+		if s.attempts == s.failRequest {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var request struct {
+			CustomerID string                   `json:"customer_id"`
+			Events     []map[string]interface{} `json:"events"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.requests++
+		s.customerIDs = append(s.customerIDs, request.CustomerID)
+		s.events = append(s.events, request.Events...)
+		_, _ = io.WriteString(w, "{}")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// verifyAssertion checks the signature and claims of a service account JWT
+func (s *chronicleStub) verifyAssertion(assertion string) bool {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], signature) != nil {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Iss   string `json:"iss"`
+		Aud   string `json:"aud"`
+		Scope string `json:"scope"`
+		Iat   int64  `json:"iat"`
+		Exp   int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return false
+	}
+	return claims.Iss == "exporter@project.iam.gserviceaccount.com" && claims.Aud == s.tokenURL &&
+		claims.Scope == "https://www.googleapis.com/auth/malachite-ingestion" && claims.Exp > claims.Iat
+}
+
+// newTestChronicleExporter starts an exporter using the Chronicle transport against a new stub,
+// with a service account key written to a temporary file
+func newTestChronicleExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, *chronicleStub) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	stub := &chronicleStub{publicKey: &key.PublicKey}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	stub.tokenURL = server.URL + "/token"
+
+	credentials, err := json.Marshal(serviceAccountKey{
+		Type:         "service_account",
+		ClientEmail:  "exporter@project.iam.gserviceaccount.com",
+		PrivateKeyID: "key-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:     stub.tokenURL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal credentials: %v", err)
+	}
+	credentialsFile := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0o600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+
+	exp := newTestTransportExporter(t, transportChronicle, server.URL, func(cfg *Config) {
+		cfg.Encoding = encodingUDM
+		cfg.Chronicle.CustomerID = "c0ffee00-0000-0000-0000-000000000000"
+		cfg.Chronicle.CredentialsFile = credentialsFile
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+	return exp, stub
+}
+
+func TestChronicleBatchCreate(t *testing.T) {
+	exp, stub := newTestChronicleExporter(t, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+			t.Fatalf("ConsumeLogs() returned error: %v", err)
+		}
+	}
+
+	if stub.tokenRequests != 1 {
+		t.Errorf("Expected one token request, got %d", stub.tokenRequests)
+	}
+	if stub.requests != 2 || len(stub.events) != 4 {
+		t.Fatalf("Expected 4 events in 2 requests, got %d events in %d requests", len(stub.events), stub.requests)
+	}
+	if stub.customerIDs[0] != "c0ffee00-0000-0000-0000-000000000000" {
+		t.Errorf("customer_id = %q", stub.customerIDs[0])
+	}
+	event := stub.events[0]
+	if got, _ := nestedField(event, "metadata.event_type"); got != "GENERIC_EVENT" {
+		t.Errorf("metadata.event_type = %v, want GENERIC_EVENT", got)
+	}
+	if got, _ := nestedField(event, "principal.user.userid"); got != "alice" {
+		t.Errorf("principal.user.userid = %v, want alice", got)
+	}
+}
+
+func TestChronicleSplitsLargeBatches(t *testing.T) {
+	exp, stub := newTestChronicleExporter(t, nil)
+	exp.transport.(*chronicleTransport).maxBodySize = 600
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "dave")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.requests < 2 || len(stub.events) != 4 {
+		t.Errorf("Expected 4 events split over several requests, got %d events in %d requests", len(stub.events), stub.requests)
+	}
+}
+
+func TestChronicleRetriesOnlyUnsentChunks(t *testing.T) {
+	exp, stub := newTestChronicleExporter(t, nil)
+	exp.transport.(*chronicleTransport).maxBodySize = 600
+	stub.failRequest = 2
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "dave"))
+	if err == nil || consumererror.IsPermanent(err) {
+		t.Fatalf("Expected retryable error, got %v", err)
+	}
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected consumererror.Logs, got %T", err)
+	}
+
+	// The events of the first request were accepted; the failed chunk and the rest are retried
+	accepted := len(stub.events)
+	if accepted == 0 || accepted == 4 {
+		t.Fatalf("Expected part of the batch to be accepted, got %d events", accepted)
+	}
+	if got := logsErr.Data().LogRecordCount(); got != 4-accepted {
+		t.Errorf("Expected %d records to retry, got %d", 4-accepted, got)
+	}
+	if exp.metrics.eventsExported.Load() != int64(accepted) || exp.metrics.eventsFailed.Load() != int64(4-accepted) {
+		t.Errorf("eventsExported = %d, eventsFailed = %d, want %d and %d",
+			exp.metrics.eventsExported.Load(), exp.metrics.eventsFailed.Load(), accepted, 4-accepted)
+	}
+}
+
+func TestChronicleRejectedAssertionIsPermanent(t *testing.T) {
+	exp, stub := newTestChronicleExporter(t, func(cfg *Config) {
+		cfg.Chronicle.Scope = "https://www.googleapis.com/auth/cloud-platform"
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for rejected assertion, got %v", err)
+	}
+	if stub.requests != 0 {
+		t.Errorf("Expected no ingestion request without a token, got %d", stub.requests)
+	}
+}
+
+func TestChronicleStartRejectsInvalidCredentials(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = "https://malachiteingestion-pa.googleapis.com"
+	cfg.Transport = transportChronicle
+	cfg.Encoding = encodingUDM
+	cfg.Chronicle.CustomerID = "customer"
+	cfg.Chronicle.Credentials = `{"type":"authorized_user"}`
+
+	encoder, err := newEventEncoder(cfg)
+	if err != nil {
+		t.Fatalf("newEventEncoder() returned error: %v", err)
+	}
+	transport, err := newChronicleTransport(&securityEventExporter{config: cfg, encoder: encoder})
+	if err != nil {
+		t.Fatalf("newChronicleTransport() returned error: %v", err)
+	}
+	if err := transport.start(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "not a service account key") {
+		t.Errorf("Expected service account error, got %v", err)
+	}
+}
+
+func TestValidateChronicleTransport(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = "https://malachiteingestion-pa.googleapis.com"
+	cfg.Transport = transportChronicle
+	cfg.Chronicle.CustomerID = "customer"
+	cfg.Chronicle.CredentialsFile = "/etc/chronicle/sa.json"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "requires the \"udm\" encoding") {
+		t.Errorf("Expected udm encoding error, got %v", err)
+	}
+
+	cfg.Encoding = encodingUDM
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned error: %v", err)
+	}
+
+	cfg.Chronicle.Credentials = "{}"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("Expected mutually exclusive credentials error, got %v", err)
+	}
+
+	cfg.Chronicle.Credentials = ""
+	cfg.Chronicle.CustomerID = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "customer_id is required") {
+		t.Errorf("Expected missing customer_id error, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+const (
+	// cloudEventsStructured posts batches as one JSON array of CloudEvents
+	cloudEventsStructured = "structured"
+
+	// cloudEventsBinary posts each event in its own request with the attributes as ce-* headers
+	cloudEventsBinary = "binary"
+
+	// cloudEventsSpecVersion is the CloudEvents specification version of the envelopes
+	cloudEventsSpecVersion = "1.0"
+
+	// cloudEventsBatchContentType is the content type of structured batches
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+)
+
+// CloudEventsConfig configures the "cloudevents" transport, which wraps each security event in a
+// CloudEvents 1.0 envelope for event buses such as Knative Eventing or Argo Events
+type CloudEventsConfig struct {
+	// Mode is "structured" (default), posting each batch as a JSON array of events, or "binary",
+	// posting each event in its own request with the envelope attributes as ce-* headers
+	Mode string `mapstructure:"mode"`
+
+	// ID is the event ID; a random UUID is used when it resolves empty
+	ID FieldSource `mapstructure:"id"`
+
+	// Source identifies the producer of the event
+	Source FieldSource `mapstructure:"source"`
+
+	// Type is the event type, used by subscribers to filter events
+	Type FieldSource `mapstructure:"type"`
+
+	// Subject is the subject of the event within its source; it is omitted when empty
+	Subject FieldSource `mapstructure:"subject"`
+}
+
+// Validate checks the mode and that source and type always resolve
+func (cfg *CloudEventsConfig) Validate() error {
+	switch cfg.Mode {
+	case cloudEventsStructured, cloudEventsBinary:
+	default:
+		return fmt.Errorf("invalid cloudevents mode %q: must be %q or %q", cfg.Mode, cloudEventsStructured, cloudEventsBinary)
+	}
+	if cfg.Source.Value == "" {
+		return errors.New("cloudevents source requires a value used when its attributes are missing")
+	}
+	if cfg.Type.Value == "" {
+		return errors.New("cloudevents type requires a value used when its attributes are missing")
+	}
+	return nil
+}
+
+// createDefaultCloudEventsConfig creates the default envelope attribute sources
+func createDefaultCloudEventsConfig() CloudEventsConfig {
+	return CloudEventsConfig{
+		Mode:    cloudEventsStructured,
+		ID:      FieldSource{Attributes: []string{"event.id"}},
+		Source:  FieldSource{Attributes: []string{"service.name"}, Value: "otelcol-securityevent"},
+		Type:    FieldSource{Attributes: []string{"event.type", "event.category"}, Value: "security.event"},
+		Subject: FieldSource{Attributes: []string{"user.name"}},
+	}
+}
+
+// cloudEvent is a CloudEvents 1.0 envelope in the JSON event format
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            string      `json:"time,omitempty"`
+	Subject         string      `json:"subject,omitempty"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// cloudEventsTransport posts security events wrapped in CloudEvents envelopes
+type cloudEventsTransport struct {
+	exp    *securityEventExporter
+	config *CloudEventsConfig
+}
+
+// newCloudEventsTransport creates the CloudEvents transport of e
+func newCloudEventsTransport(e *securityEventExporter) (*cloudEventsTransport, error) {
+	cfg := &e.config.CloudEvents
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cloudEventsTransport{exp: e, config: cfg}, nil
+}
+
+// start has nothing to prepare
+func (t *cloudEventsTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send posts the batch in the configured mode. Events that cannot be wrapped or marshaled are
+// dropped and the others are still sent.
+func (t *cloudEventsTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	envelopes := make([]cloudEvent, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	var dropped []int
+	var droppedErr error
+	for i, event := range events {
+		envelope, err := t.envelope(event, sources[i])
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			dropped, droppedErr = append(dropped, i), consumererror.NewPermanent(err)
+			continue
+		}
+		envelopes = append(envelopes, envelope)
+		indexes = append(indexes, i)
+	}
+
+	if t.config.Mode == cloudEventsBinary {
+		return t.sendBinary(ctx, envelopes, indexes, dropped, droppedErr)
+	}
+	return t.sendBatch(ctx, envelopes, indexes, dropped, droppedErr)
+}
+
+// sendBatch posts the envelopes as one JSON array. dropped lists the events already dropped,
+// because of droppedErr.
+func (t *cloudEventsTransport) sendBatch(ctx context.Context, envelopes []cloudEvent, indexes []int, dropped []int, droppedErr error) error {
+	body := []byte{'['}
+	sent := make([]int, 0, len(envelopes))
+	for i, envelope := range envelopes {
+		data, err := json.Marshal(envelope)
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			dropped, droppedErr = append(dropped, indexes[i]), consumererror.NewPermanent(fmt.Errorf("failed to marshal cloudevent: %w", err))
+			continue
+		}
+		if len(sent) > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, data...)
+		sent = append(sent, indexes[i])
+	}
+	body = append(body, ']')
+
+	if len(sent) > 0 {
+		header := http.Header{}
+		header.Set("Content-Type", cloudEventsBatchContentType)
+		if _, err := t.exp.postBatch(ctx, t.exp.config.Endpoint, body, header, len(sent)); err != nil {
+			if len(dropped) == 0 {
+				return err
+			}
+			return &partialSendError{err: err, failed: sent, dropped: dropped}
+		}
+	}
+	if len(dropped) > 0 {
+		return newPartialSendError(droppedErr, dropped)
+	}
+	return nil
+}
+
+// sendBinary posts one request per event. Events the endpoint rejects for good are dropped and
+// the following events are still sent; after any other failure the remaining events are not
+// sent and are retried with the failed one. indexes are the positions of the envelopes in the
+// batch and dropped lists the events already dropped, because of droppedErr.
+func (t *cloudEventsTransport) sendBinary(ctx context.Context, envelopes []cloudEvent, indexes []int, dropped []int, droppedErr error) error {
+	for i, envelope := range envelopes {
+		body, err := envelope.binaryData()
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			dropped, droppedErr = append(dropped, indexes[i]), consumererror.NewPermanent(err)
+			continue
+		}
+		_, err = t.exp.postBatch(ctx, t.exp.config.Endpoint, body, envelope.binaryHeader(), 1)
+		if err == nil {
+			continue
+		}
+		if consumererror.IsPermanent(err) {
+			dropped, droppedErr = append(dropped, indexes[i]), err
+			continue
+		}
+		return &partialSendError{err: err, failed: indexes[i:], dropped: dropped}
+	}
+	if len(dropped) > 0 {
+		return newPartialSendError(droppedErr, dropped)
+	}
+	return nil
+}
+
+// envelope wraps an encoded event. Line-oriented encodings are carried as the rendered line;
+// all others as the JSON event. The envelope attributes are resolved from the flat event, since
+// encodings may rename or drop the attributes they come from.
+func (t *cloudEventsTransport) envelope(event map[string]interface{}, source eventSource) (cloudEvent, error) {
+	envelope := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              t.config.ID.resolve(source.attributes),
+		Source:          t.config.Source.resolve(source.attributes),
+		Type:            t.config.Type.resolve(source.attributes),
+		Subject:         t.config.Subject.resolve(source.attributes),
+		DataContentType: "application/json",
+		Data:            event,
+	}
+	if envelope.ID == "" {
+		envelope.ID = uuid.NewString()
+	}
+	if !source.time.IsZero() {
+		envelope.Time = source.time.UTC().Format(time.RFC3339Nano)
+	}
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return envelope, err
+		}
+		envelope.DataContentType = "text/plain; charset=utf-8"
+		envelope.Data = line
+	}
+	return envelope, nil
+}
+
+// binaryData returns the request body of the envelope in binary mode
+func (envelope cloudEvent) binaryData() ([]byte, error) {
+	if line, ok := envelope.Data.(string); ok {
+		return []byte(line), nil
+	}
+	data, err := json.Marshal(envelope.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal security event: %w", err)
+	}
+	return data, nil
+}
+
+// binaryHeader returns the ce-* headers of the envelope in binary mode
+func (envelope cloudEvent) binaryHeader() http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", envelope.DataContentType)
+	header.Set("Ce-Specversion", envelope.SpecVersion)
+	header.Set("Ce-Id", cloudEventsHeaderValue(envelope.ID))
+	header.Set("Ce-Source", cloudEventsHeaderValue(envelope.Source))
+	header.Set("Ce-Type", cloudEventsHeaderValue(envelope.Type))
+	if envelope.Time != "" {
+		header.Set("Ce-Time", envelope.Time)
+	}
+	if envelope.Subject != "" {
+		header.Set("Ce-Subject", cloudEventsHeaderValue(envelope.Subject))
+	}
+	return header
+}
+
+// cloudEventsHeaderValue percent-encodes the characters the HTTP binding does not allow as is in
+// ce-* header values: spaces, double quotes, percent signs and bytes outside printable ASCII
+func cloudEventsHeaderValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// shutdown has nothing to close
+func (t *cloudEventsTransport) shutdown(context.Context) error {
+	return nil
+}
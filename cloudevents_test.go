@@ -0,0 +1,290 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// cloudEventsRequest is a request received by cloudEventsStub
+type cloudEventsRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// cloudEventsStub records the requests it receives and answers the binary-mode events whose
+// ce-subject is listed in failSubjects with their status
+type cloudEventsStub struct {
+	failSubjects map[string]int
+
+	mu       sync.Mutex
+	requests []cloudEventsRequest
+}
+
+func (s *cloudEventsStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, cloudEventsRequest{header: r.Header.Clone(), body: body})
+	if status, ok := s.failSubjects[r.Header.Get("Ce-Subject")]; ok {
+		w.WriteHeader(status)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// newTestCloudEventsExporter starts an exporter using the cloudevents transport against a stub
+func newTestCloudEventsExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, *cloudEventsStub) {
+	t.Helper()
+	stub := &cloudEventsStub{}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return newTestTransportExporter(t, transportCloudEvents, server.URL+"/events", configure), stub
+}
+
+func TestCloudEventsStructuredBatch(t *testing.T) {
+	exp, stub := newTestCloudEventsExporter(t, func(cfg *Config) {
+		cfg.DefaultAttributes["event.category"] = "authentication"
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if len(stub.requests) != 1 {
+		t.Fatalf("Expected one batch request, got %d", len(stub.requests))
+	}
+	if got := stub.requests[0].header.Get("Content-Type"); got != cloudEventsBatchContentType {
+		t.Errorf("Content-Type = %q, want %q", got, cloudEventsBatchContentType)
+	}
+	var events []map[string]interface{}
+	if err := json.Unmarshal(stub.requests[0].body, &events); err != nil {
+		t.Fatalf("Batch is not a JSON array: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	event := events[1]
+	want := map[string]interface{}{
+		"specversion":     "1.0",
+		"source":          "auth",
+		"type":            "authentication",
+		"time":            "2023-11-14T22:13:20Z",
+		"subject":         "bob",
+		"datacontenttype": "application/json",
+	}
+	for key, value := range want {
+		if event[key] != value {
+			t.Errorf("%s = %v, want %v", key, event[key], value)
+		}
+	}
+	if id, _ := event["id"].(string); id == "" || id == events[0]["id"] {
+		t.Errorf("Expected a unique generated id, got %v and %v", events[0]["id"], event["id"])
+	}
+	if data, _ := event["data"].(map[string]interface{}); data["user.name"] != "bob" {
+		t.Errorf("Expected the security event as data, got %v", event["data"])
+	}
+}
+
+func TestCloudEventsBinaryMode(t *testing.T) {
+	exp, stub := newTestCloudEventsExporter(t, func(cfg *Config) {
+		cfg.CloudEvents.Mode = cloudEventsBinary
+		cfg.CloudEvents.ID = FieldSource{Attributes: []string{"user.name"}}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob smith")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if len(stub.requests) != 2 {
+		t.Fatalf("Expected one request per event, got %d", len(stub.requests))
+	}
+	header := stub.requests[1].header
+	want := map[string]string{
+		"Content-Type":   "application/json",
+		"Ce-Specversion": "1.0",
+		"Ce-Id":          "bob%20smith",
+		"Ce-Source":      "auth",
+		"Ce-Type":        "security.event",
+		"Ce-Time":        "2023-11-14T22:13:20Z",
+		"Ce-Subject":     "bob%20smith",
+	}
+	for key, value := range want {
+		if got := header.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(stub.requests[1].body, &event); err != nil {
+		t.Fatalf("Body is not the JSON event: %v", err)
+	}
+	if event["user.name"] != "bob smith" || event["specversion"] != nil {
+		t.Errorf("Expected the bare security event as body, got %v", event)
+	}
+}
+
+func TestCloudEventsLineEncoding(t *testing.T) {
+	exp, stub := newTestCloudEventsExporter(t, func(cfg *Config) {
+		cfg.Encoding = encodingCEF
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var events []map[string]interface{}
+	if err := json.Unmarshal(stub.requests[0].body, &events); err != nil {
+		t.Fatalf("Batch is not a JSON array: %v", err)
+	}
+	line, _ := events[0]["data"].(string)
+	if events[0]["datacontenttype"] != "text/plain; charset=utf-8" || !strings.HasPrefix(line, "CEF:0|") {
+		t.Errorf("Expected the CEF line as text data, got %v", events[0])
+	}
+	if events[0]["subject"] != "alice" {
+		t.Errorf("Expected the subject resolved before encoding, got %v", events[0]["subject"])
+	}
+}
+
+func TestCloudEventsBinaryPartialFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		permanent bool
+		retried   int
+		requests  int
+	}{
+		{name: "rejected event is dropped", status: http.StatusBadRequest, permanent: true, requests: 3},
+		{name: "remaining events are retried", status: http.StatusServiceUnavailable, retried: 2, requests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, stub := newTestCloudEventsExporter(t, func(cfg *Config) {
+				cfg.CloudEvents.Mode = cloudEventsBinary
+			})
+			stub.failSubjects = map[string]int{"mallory": tt.status}
+
+			err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "mallory", "bob"))
+			if err == nil || consumererror.IsPermanent(err) != tt.permanent {
+				t.Fatalf("ConsumeLogs() error = %v, want permanent = %v", err, tt.permanent)
+			}
+			if len(stub.requests) != tt.requests {
+				t.Errorf("Expected %d requests, got %d", tt.requests, len(stub.requests))
+			}
+			if tt.permanent {
+				var partial *partialSendError
+				if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.failed[0] != 1 {
+					t.Errorf("Expected only event 1 to fail, got %v", err)
+				}
+				return
+			}
+			var logsErr consumererror.Logs
+			if !errors.As(err, &logsErr) {
+				t.Fatalf("Expected consumererror.Logs, got %T", err)
+			}
+			retry := logsErr.Data()
+			if retry.LogRecordCount() != tt.retried {
+				t.Fatalf("Expected %d records to retry, got %d", tt.retried, retry.LogRecordCount())
+			}
+			if user, _ := retry.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("user.name"); user.Str() != "mallory" {
+				t.Errorf("Expected mallory to be retried first, got %q", user.Str())
+			}
+		})
+	}
+}
+
+func TestCloudEventsStructuredBatchDropsUnmarshalableEvents(t *testing.T) {
+	exp, stub := newTestCloudEventsExporter(t, nil)
+	events := []map[string]interface{}{
+		{"user.name": "alice", "risk.score": math.NaN()},
+		{"user.name": "bob"},
+	}
+	sources := []eventSource{newEventSource(events[0], plog.NewLogRecord()), newEventSource(events[1], plog.NewLogRecord())}
+
+	err := exp.transport.send(context.Background(), events, sources)
+	var partial *partialSendError
+	if !errors.As(err, &partial) || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected a permanent partial failure, got %v", err)
+	}
+	if len(partial.failed) != 1 || partial.failed[0] != 0 {
+		t.Errorf("Expected only event 0 to be dropped, got %v", partial.failed)
+	}
+	if len(stub.requests) != 1 {
+		t.Fatalf("Expected one batch request, got %d", len(stub.requests))
+	}
+	var batch []map[string]interface{}
+	if err := json.Unmarshal(stub.requests[0].body, &batch); err != nil {
+		t.Fatalf("Batch is not a JSON array: %v", err)
+	}
+	if data, _ := batch[0]["data"].(map[string]interface{}); len(batch) != 1 || data["user.name"] != "bob" {
+		t.Errorf("Expected only the event for bob in the batch, got %v", batch)
+	}
+}
+
+func TestCloudEventsHeaderValue(t *testing.T) {
+	tests := map[string]string{
+		"auth":                "auth",
+		"urn:svc/auth?x=1":    "urn:svc/auth?x=1",
+		"bob smith":           "bob%20smith",
+		`say "hi"`:            "say%20%22hi%22",
+		"100%":                "100%25",
+		"zoë":                 "zo%C3%AB",
+		"line\nbreak\ttabbed": "line%0Abreak%09tabbed",
+	}
+	for value, want := range tests {
+		if got := cloudEventsHeaderValue(value); got != want {
+			t.Errorf("cloudEventsHeaderValue(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestCloudEventsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *CloudEventsConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*CloudEventsConfig) {}},
+		{name: "binary", modify: func(cfg *CloudEventsConfig) {
+			cfg.Mode = cloudEventsBinary
+		}},
+		{name: "invalid mode", modify: func(cfg *CloudEventsConfig) {
+			cfg.Mode = "batch"
+		}, errorMsg: "invalid cloudevents mode"},
+		{name: "missing source value", modify: func(cfg *CloudEventsConfig) {
+			cfg.Source.Value = ""
+		}, errorMsg: "cloudevents source requires a value"},
+		{name: "missing type value", modify: func(cfg *CloudEventsConfig) {
+			cfg.Type.Value = ""
+		}, errorMsg: "cloudevents type requires a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultCloudEventsConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
+	"go.yaml.in/yaml/v3"
+
+	exporter "github.com/henrikrexed/SecurityEventExporter"
+)
+
+// loadConfig reads the exporter configuration from path, merged onto the exporter defaults.
+// The file may be a full collector configuration, in which case the exporters.<id> section is
+// used, or just the exporter section itself. Environment references in the section's values are
+// expanded as the collector does.
+func loadConfig(path, id string) (*exporter.Config, error) {
+	cfg := exporter.DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	section := raw
+	if exporters, ok := raw["exporters"].(map[string]interface{}); ok {
+		section, ok = exporters[id].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("exporter %q not found in config", id)
+		}
+	}
+
+	// Values are expanded after parsing, so variables holding ": ", " #" or line breaks stay
+	// part of the value
+	expandEnv(section)
+	if err := confmap.NewFromStringMap(section).Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode exporter config: %w", err)
+	}
+	if err := xconfmap.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("invalid exporter config: %w", err)
+	}
+	return cfg, nil
+}
+
+// envReference matches $$, and the environment references ${VAR}, ${env:VAR} and
+// ${env:VAR:-default} with the variable name and default as submatches
+var envReference = regexp.MustCompile(`\$\$|\$\{(?:env:)?([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces the environment references in the string values of value, recursing into
+// maps and lists, with the collector's rules: ${VAR} and ${env:VAR} become the variable,
+// ${env:VAR:-default} falls back to default when VAR is unset, and $$ is a literal $. Any other $,
+// such as a bare $VAR or $1 in a regular expression, is kept as written.
+func expandEnv(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = expandEnv(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandEnv(item)
+		}
+		return v
+	case string:
+		return expandEnvString(v)
+	default:
+		return value
+	}
+}
+
+// expandEnvString expands the references in s. When s is a single reference, the variable's
+// value is typed like a YAML scalar, so ${env:PORT} can set a number or ${env:ENABLED} a bool.
+func expandEnvString(s string) interface{} {
+	if match := envReference.FindStringSubmatchIndex(s); match != nil && match[0] == 0 && match[1] == len(s) && s != "$$" {
+		expanded := lookupEnv(envReference.FindStringSubmatch(s))
+		var typed interface{}
+		if err := yaml.Unmarshal([]byte(expanded), &typed); err == nil {
+			switch typed.(type) {
+			case int, float64, bool:
+				return typed
+			}
+		}
+		return expanded
+	}
+	return envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		if reference == "$$" {
+			return "$"
+		}
+		return lookupEnv(envReference.FindStringSubmatch(reference))
+	})
+}
+
+// lookupEnv returns the value of the variable of an envReference match, or its default when the
+// variable is unset
+func lookupEnv(match []string) string {
+	if value, ok := os.LookupEnv(match[1]); ok {
+		return value
+	}
+	return match[2]
+}
@@ -0,0 +1,178 @@
+// Command securityevent-convert converts OTLP/JSON log exports into security events offline.
+//
+// It reads files written by the collector's file exporter (or any input accepted by the
+// otlpjsonfile receiver), runs them through the same Config-driven conversion pipeline as the
+// securityevent exporter and prints the resulting events, so mapping configurations can be
+// iterated on locally and their output diffed in code review.
+//
+// Usage:
+//
+//	securityevent-convert [-config collector.yaml] [-exporter securityevent] [-format json|ndjson|lines] [-output file] [input ...]
+//
+// Inputs default to standard input; "-" also selects standard input.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	exporter "github.com/henrikrexed/SecurityEventExporter"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatLines  = "lines"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "securityevent-convert: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses args, converts every input and writes the events to stdout or the -output file
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("securityevent-convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "collector or exporter configuration file (YAML); defaults are used when empty")
+	exporterID := flags.String("exporter", "securityevent", "exporter ID to read from a collector configuration")
+	format := flags.String("format", formatJSON, "output format: json (indented array), ndjson (one event per line) or lines (rendered lines of line-oriented encodings such as cef and leef)")
+	outputPath := flags.String("output", "", "output file; defaults to standard output")
+	verbose := flags.Bool("verbose", false, "log conversion details to standard error")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case formatJSON, formatNDJSON, formatLines:
+	default:
+		return fmt.Errorf("invalid format %q: must be %q, %q or %q", *format, formatJSON, formatNDJSON, formatLines)
+	}
+
+	cfg, err := loadConfig(*configPath, *exporterID)
+	if err != nil {
+		return err
+	}
+
+	logger := zap.NewNop()
+	if *verbose {
+		logger, err = zap.NewDevelopment()
+		if err != nil {
+			return err
+		}
+	}
+
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	var events []map[string]interface{}
+	var conversionErrs []error
+	for _, input := range inputs {
+		logs, err := readLogs(input, stdin)
+		if err != nil {
+			return err
+		}
+		for _, ld := range logs {
+			converted, err := exporter.ConvertLogs(cfg, logger, ld)
+			if err != nil {
+				conversionErrs = append(conversionErrs, fmt.Errorf("%s: %w", input, err))
+			}
+			events = append(events, converted...)
+		}
+	}
+
+	out := stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == formatLines {
+		lines, err := exporter.FormatLines(cfg, events)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return fmt.Errorf("failed to write event: %w", err)
+			}
+		}
+		return errors.Join(conversionErrs...)
+	}
+
+	if err := writeEvents(out, *format, events); err != nil {
+		return err
+	}
+	return errors.Join(conversionErrs...)
+}
+
+// readLogs decodes every OTLP/JSON logs document in input. Documents may be newline-delimited,
+// as written by the file exporter, or a single pretty-printed request.
+func readLogs(input string, stdin io.Reader) ([]plog.Logs, error) {
+	var reader io.Reader = stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	unmarshaler := &plog.JSONUnmarshaler{}
+	decoder := json.NewDecoder(bufio.NewReader(reader))
+	var logs []plog.Logs
+	for document := 1; ; document++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return logs, nil
+			}
+			return nil, fmt.Errorf("%s: document %d: %w", input, document, err)
+		}
+		ld, err := unmarshaler.UnmarshalLogs(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: failed to unmarshal OTLP logs: %w", input, document, err)
+		}
+		logs = append(logs, ld)
+	}
+}
+
+// writeEvents writes events as an indented JSON array or as one compact JSON object per line
+func writeEvents(out io.Writer, format string, events []map[string]interface{}) error {
+	if format == formatNDJSON {
+		encoder := json.NewEncoder(out)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return fmt.Errorf("failed to write event: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if events == nil {
+		events = []map[string]interface{}{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(events); err != nil {
+		return fmt.Errorf("failed to write events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunNDJSON(t *testing.T) {
+	t.Setenv("CONVERT_TEST_ENVIRONMENT", "staging")
+
+	var stdout bytes.Buffer
+	args := []string{
+		"-config", filepath.Join("testdata", "collector.yaml"),
+		"-exporter", "securityevent/siem",
+		"-format", "ndjson",
+		filepath.Join("testdata", "logs.json"),
+	}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard); err != nil {
+		t.Fatalf("run() returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %s", len(lines), stdout.String())
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	if first["user.id"] != "alice" {
+		t.Errorf("Expected first event for alice, got %v", first["user.id"])
+	}
+	if first["environment"] != "staging" {
+		t.Errorf("Expected environment from config, got %v", first["environment"])
+	}
+	if first["timestamp"] != "2023-11-14T22:13:20Z" {
+		t.Errorf("Unexpected timestamp %v", first["timestamp"])
+	}
+}
+
+func TestRunJSONFromStdin(t *testing.T) {
+	input, err := readLogs(filepath.Join("testdata", "logs.json"), nil)
+	if err != nil {
+		t.Fatalf("readLogs() returned error: %v", err)
+	}
+	if len(input) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(input))
+	}
+
+	// A single pretty-printed document is accepted as well
+	pretty := `{
+  "resourceLogs": [{"scopeLogs": [{"logRecords": [{"attributes": [{"key": "user.id", "value": {"stringValue": "carol"}}]}]}]}]
+}`
+	var stdout bytes.Buffer
+	if err := run(nil, strings.NewReader(pretty), &stdout, io.Discard); err != nil {
+		t.Fatalf("run() returned error: %v", err)
+	}
+
+	var events []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &events); err != nil {
+		t.Fatalf("Failed to unmarshal output array: %v", err)
+	}
+	if len(events) != 1 || events[0]["user.id"] != "carol" {
+		t.Errorf("Unexpected events: %v", events)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	var stdout bytes.Buffer
+	args := []string{
+		"-config", filepath.Join("testdata", "collector.yaml"),
+		"-exporter", "securityevent/cef",
+		"-format", "lines",
+		filepath.Join("testdata", "logs.json"),
+	}
+	if err := run(args, strings.NewReader(""), &stdout, io.Discard); err != nil {
+		t.Fatalf("run() returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %s", len(lines), stdout.String())
+	}
+	if !strings.HasPrefix(lines[0], "CEF:0|Acme|") {
+		t.Errorf("Unexpected CEF line: %s", lines[0])
+	}
+	if !strings.Contains(lines[0], "suid=alice") {
+		t.Errorf("Expected user.id extension in %s", lines[0])
+	}
+
+	// JSON encodings have no line form
+	args = []string{"-format", "lines", filepath.Join("testdata", "logs.json")}
+	if err := run(args, strings.NewReader(""), io.Discard, io.Discard); err == nil {
+		t.Error("Expected error for lines format with the json encoding")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+	}{
+		{name: "invalid format", args: []string{"-format", "xml"}},
+		{name: "unknown exporter", args: []string{"-config", filepath.Join("testdata", "collector.yaml"), "-exporter", "securityevent/other"}},
+		{name: "invalid input", stdin: "not json"},
+		{name: "missing input file", args: []string{filepath.Join("testdata", "missing.json")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(tt.args, strings.NewReader(tt.stdin), io.Discard, io.Discard); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CONVERT_TEST_TOKEN", "secret")
+	t.Setenv("CONVERT_TEST_PORT", "8443")
+	t.Setenv("CONVERT_TEST_EMPTY", "")
+
+	tests := []struct {
+		value    string
+		expected interface{}
+	}{
+		{value: "${CONVERT_TEST_TOKEN}", expected: "secret"},
+		{value: "Bearer ${env:CONVERT_TEST_TOKEN}", expected: "Bearer secret"},
+		{value: "${env:CONVERT_TEST_PORT}", expected: 8443},
+		{value: "port ${env:CONVERT_TEST_PORT}", expected: "port 8443"},
+		{value: "${env:CONVERT_TEST_UNSET:-fallback}", expected: "fallback"},
+		{value: "${env:CONVERT_TEST_EMPTY:-fallback}", expected: ""},
+		{value: "${env:CONVERT_TEST_TOKEN:-fallback}", expected: "secret"},
+		{value: "pa$word", expected: "pa$word"},
+		{value: "^user-$1$", expected: "^user-$1$"},
+		{value: "$CONVERT_TEST_TOKEN", expected: "$CONVERT_TEST_TOKEN"},
+		{value: "$${CONVERT_TEST_TOKEN}", expected: "${CONVERT_TEST_TOKEN}"},
+		{value: "price: 5$$", expected: "price: 5$"},
+	}
+
+	for _, tt := range tests {
+		if got := expandEnv(tt.value); got != tt.expected {
+			t.Errorf("expandEnv(%q) = %#v, want %#v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadConfigExpandsParsedValues(t *testing.T) {
+	// Values that would change the YAML document if they were expanded before parsing
+	t.Setenv("CONVERT_TEST_HEADER", "Bearer a: b #c\nd")
+	t.Setenv("CONVERT_TEST_ENVIRONMENT", "line one\nline two")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `endpoint: https://siem.example.com/events
+headers:
+  Authorization: ${env:CONVERT_TEST_HEADER}
+default_attributes:
+  environment: ${CONVERT_TEST_ENVIRONMENT}
+  note: "costs $$5"
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := loadConfig(path, "")
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	if got := string(cfg.Headers["Authorization"]); got != "Bearer a: b #c\nd" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := cfg.DefaultAttributes["environment"]; got != "line one\nline two" {
+		t.Errorf("environment = %q", got)
+	}
+	if got := cfg.DefaultAttributes["note"]; got != "costs $5" {
+		t.Errorf("note = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Command securityevent-mock runs a mock security event receiver for local and integration testing.
+//
+// It accepts batches on every path, validates them against the configured schema and stores them
+// for assertions through the control API under /_mock/. Failures can be injected with flags or at
+// runtime through PUT /_mock/faults and POST /_mock/fail-next.
+//
+// Usage:
+//
+//	securityevent-mock [-listen :1080] [-config mock.json] [-rate-429 0.1] [-rate-500 0.05] [-rate-413 0] [-reset-rate 0] [-latency 50ms]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/henrikrexed/SecurityEventExporter/mockservice"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "securityevent-mock: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses args and serves until ctx is done
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	cfg, listen, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mockservice.New(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger := log.New(stderr, "", log.LstdFlags)
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Printf("mock security event receiver listening on %s", listen)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// parseFlags builds the server configuration from an optional JSON config file overridden by flags
+func parseFlags(args []string, stderr io.Writer) (mockservice.Config, string, error) {
+	flags := flag.NewFlagSet("securityevent-mock", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	listen := flags.String("listen", ":1080", "address to listen on")
+	configPath := flags.String("config", "", "JSON file with schema and faults configuration")
+	required := flags.String("required-fields", "", "comma-separated fields every event must contain")
+	maxBatch := flags.Int("max-batch-size", 0, "reject batches with more events with 413 (0 means unlimited)")
+	rate429 := flags.Float64("rate-429", 0, "rate of requests answered with 429")
+	rate500 := flags.Float64("rate-500", 0, "rate of requests answered with 500")
+	rate413 := flags.Float64("rate-413", 0, "rate of requests answered with 413")
+	resetRate := flags.Float64("reset-rate", 0, "rate of requests whose connection is reset")
+	retryAfter := flags.Duration("retry-after", 0, "Retry-After sent with injected 429 responses")
+	latency := flags.Duration("latency", 0, "latency added to every response")
+	jitter := flags.Duration("latency-jitter", 0, "random latency added on top of -latency")
+	seed := flags.Int64("seed", 0, "seed for fault injection (0 uses the current time)")
+	if err := flags.Parse(args); err != nil {
+		return mockservice.Config{}, "", err
+	}
+
+	var cfg mockservice.Config
+	if *configPath != "" {
+		content, err := os.ReadFile(*configPath)
+		if err != nil {
+			return cfg, "", fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := json.Unmarshal(content, &cfg); err != nil {
+			return cfg, "", fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	// Flags explicitly set on the command line override the config file
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "required-fields":
+			cfg.Schema.RequiredFields = strings.Split(*required, ",")
+		case "max-batch-size":
+			cfg.Schema.MaxBatchSize = *maxBatch
+		case "rate-429":
+			cfg.Faults.TooManyRequestsRate = *rate429
+		case "rate-500":
+			cfg.Faults.ServerErrorRate = *rate500
+		case "rate-413":
+			cfg.Faults.PayloadTooLargeRate = *rate413
+		case "reset-rate":
+			cfg.Faults.ConnectionResetRate = *resetRate
+		case "retry-after":
+			cfg.Faults.RetryAfter = *retryAfter
+		case "latency":
+			cfg.Faults.Latency = *latency
+		case "latency-jitter":
+			cfg.Faults.LatencyJitter = *jitter
+		case "seed":
+			cfg.Seed = *seed
+		}
+	})
+
+	if err := cfg.Faults.Validate(); err != nil {
+		return cfg, "", err
+	}
+	return cfg, *listen, nil
+}
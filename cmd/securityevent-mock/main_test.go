@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mock.json")
+	config := `{"schema":{"required_fields":["timestamp"]},"faults":{"too_many_requests_rate":0.2,"latency":"10ms"}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, listen, err := parseFlags([]string{"-config", configPath, "-listen", "127.0.0.1:0", "-rate-500", "0.1", "-seed", "7"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseFlags() returned error: %v", err)
+	}
+
+	if listen != "127.0.0.1:0" {
+		t.Errorf("Unexpected listen address %q", listen)
+	}
+	if len(cfg.Schema.RequiredFields) != 1 || cfg.Schema.RequiredFields[0] != "timestamp" {
+		t.Errorf("Expected schema from config file, got %+v", cfg.Schema)
+	}
+	if cfg.Faults.TooManyRequestsRate != 0.2 || cfg.Faults.Latency != 10*time.Millisecond {
+		t.Errorf("Expected faults from config file, got %+v", cfg.Faults)
+	}
+	if cfg.Faults.ServerErrorRate != 0.1 || cfg.Seed != 7 {
+		t.Errorf("Expected flags to override config file, got %+v", cfg)
+	}
+}
+
+func TestParseFlagsRejectsInvalidRates(t *testing.T) {
+	if _, _, err := parseFlags([]string{"-rate-429", "0.7", "-rate-500", "0.7"}, io.Discard); err == nil {
+		t.Error("Expected rates summing above 1 to be rejected")
+	}
+}
@@ -2,9 +2,14 @@ package exporter
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configoptional"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 )
 
 // Config defines the configuration for the security event exporter
@@ -12,7 +17,7 @@ type Config struct {
 	// Endpoint is the HTTP endpoint where security events will be sent
 	Endpoint string `mapstructure:"endpoint"`
 
-	// Timeout is the HTTP request timeout
+	// Timeout is the HTTP request timeout; zero uses the 30s default
 	Timeout time.Duration `mapstructure:"timeout"`
 
 	// Headers are additional HTTP headers to include in requests
@@ -21,43 +26,213 @@ type Config struct {
 	// DefaultAttributes are attributes that will be added to all security events
 	DefaultAttributes map[string]interface{} `mapstructure:"default_attributes"`
 
-	// RetrySettings configures retry behavior (simplified for now)
-	RetrySettings map[string]interface{} `mapstructure:"retry_on_failure"`
+	// RetrySettings configures retry behavior for failed requests
+	RetrySettings configretry.BackOffConfig `mapstructure:"retry_on_failure"`
 
-	// QueueSettings configures queue behavior (simplified for now)
-	QueueSettings map[string]interface{} `mapstructure:"sending_queue"`
+	// QueueSettings configures the sending queue and optional batching in front of the exporter
+	QueueSettings exporterhelper.QueueBatchConfig `mapstructure:"sending_queue"`
+
+	// Mode selects how security events are delivered: "live" (default) sends them
+	// to Endpoint, "dry_run" converts and batches them but only writes the request
+	// that would have been sent to DryRunOutput
+	Mode string `mapstructure:"mode"`
+
+	// DryRunOutput is where dry-run requests are written: "stdout" (default) or a file path
+	DryRunOutput string `mapstructure:"dry_run_output"`
+
+	// Encoding selects the event representation: "json" (default) sends the flat security
+	// event, "ocsf" maps it to an OCSF class event, "ecs" to a nested ECS document, "udm" to a
+	// Chronicle UDM event, "asff" to an AWS Security Hub finding, and "cef" and "leef" render it
+	// as a CEF or LEEF 2.0 line
+	Encoding string `mapstructure:"encoding"`
+
+	// PayloadFormat selects how the http transport serializes a batch of JSON events:
+	// "json_array" (default), "ndjson" with one event per line, "json_object_wrapper" with the
+	// array under PayloadWrapperKey, or "protobuf" as a securityevent.v1.SecurityEventBatch
+	PayloadFormat string `mapstructure:"payload_format"`
+
+	// PayloadWrapperKey is the key holding the event array in the "json_object_wrapper" format
+	PayloadWrapperKey string `mapstructure:"payload_wrapper_key"`
+
+	// ConversionWorkers is how many goroutines convert the log records of a batch to events: 1
+	// (default) converts them serially, more split large batches into chunks converted in
+	// parallel, keeping record order, and 0 uses one worker per available CPU
+	ConversionWorkers int `mapstructure:"conversion_workers"`
+
+	// DebugSampleRate logs the conversion of one in every DebugSampleRate log records when the
+	// debug level is enabled; 1 (default) and 0 log every record
+	DebugSampleRate int `mapstructure:"debug_sample_rate"`
+
+	// TraceConversion also logs every attribute added to the events of the logged records
+	TraceConversion bool `mapstructure:"trace_conversion"`
+
+	// Redaction masks, removes or hashes sensitive fields of the converted events, and of the
+	// request previews and response bodies written to the logs
+	Redaction RedactionConfig `mapstructure:"redaction"`
+
+	// OCSF configures the "ocsf" encoding
+	OCSF OCSFConfig `mapstructure:"ocsf"`
+
+	// ECS configures the "ecs" encoding
+	ECS ECSConfig `mapstructure:"ecs"`
+
+	// CEF configures the "cef" encoding
+	CEF CEFConfig `mapstructure:"cef"`
+
+	// LEEF configures the "leef" encoding
+	LEEF LEEFConfig `mapstructure:"leef"`
+
+	// UDM configures the "udm" encoding
+	UDM UDMConfig `mapstructure:"udm"`
+
+	// ASFF configures the "asff" encoding
+	ASFF ASFFConfig `mapstructure:"asff"`
+
+	// Transport selects how batches are delivered: "http" (default) posts them to the endpoint,
+	// "syslog" sends one syslog message per event to a udp://, tcp:// or tls:// endpoint and
+	// "splunk_hec" posts Splunk HTTP Event Collector envelopes, "sentinel" posts to Microsoft
+	// Sentinel custom tables, "chronicle" posts UDM events to Google Security Operations,
+	// "security_hub" imports ASFF findings into AWS Security Hub, "elasticsearch" indexes
+	// documents with the Elasticsearch or OpenSearch bulk API, "loki" pushes them to Grafana
+	// Loki, "kafka" produces one message per event, "file" appends them to a local file and
+	// "cloudevents" posts them wrapped in CloudEvents envelopes
+	Transport string `mapstructure:"transport"`
+
+	// Syslog configures the "syslog" transport
+	Syslog SyslogConfig `mapstructure:"syslog"`
+
+	// SplunkHEC configures the "splunk_hec" transport
+	SplunkHEC SplunkHECConfig `mapstructure:"splunk_hec"`
+
+	// Sentinel configures the "sentinel" transport
+	Sentinel SentinelConfig `mapstructure:"sentinel"`
+
+	// Chronicle configures the "chronicle" transport
+	Chronicle ChronicleConfig `mapstructure:"chronicle"`
+
+	// SecurityHub configures the "security_hub" transport
+	SecurityHub SecurityHubConfig `mapstructure:"security_hub"`
+
+	// Elasticsearch configures the "elasticsearch" transport
+	Elasticsearch ElasticsearchConfig `mapstructure:"elasticsearch"`
+
+	// Loki configures the "loki" transport
+	Loki LokiConfig `mapstructure:"loki"`
+
+	// Kafka configures the "kafka" transport
+	Kafka KafkaConfig `mapstructure:"kafka"`
+
+	// File configures the "file" transport; with other transports, setting its path also writes
+	// the events to the file
+	File FileConfig `mapstructure:"file"`
+
+	// CloudEvents configures the "cloudevents" transport
+	CloudEvents CloudEventsConfig `mapstructure:"cloudevents"`
+
+	// Archive also keeps the converted events in S3-compatible object storage when set
+	Archive configoptional.Optional[ArchiveConfig] `mapstructure:"archive"`
+}
+
+const (
+	// defaultTimeout is the HTTP request timeout used when none is configured
+	defaultTimeout = 30 * time.Second
+
+	// modeLive sends security events to the configured endpoint
+	modeLive = "live"
+
+	// modeDryRun writes the would-be requests instead of sending them
+	modeDryRun = "dry_run"
+
+	// dryRunStdout is the DryRunOutput value that selects standard output
+	dryRunStdout = "stdout"
+)
+
+// requestTimeout returns the configured timeout, or the default when it is zero
+func (cfg *Config) requestTimeout() time.Duration {
+	if cfg.Timeout == 0 {
+		return defaultTimeout
+	}
+	return cfg.Timeout
 }
 
-// Validate validates the configuration
+// Validate validates the configuration. It does not modify it: defaults are applied by
+// createDefaultConfig before the user configuration is unmarshaled on top.
 func (cfg *Config) Validate() error {
 	if cfg.Endpoint == "" {
 		return errors.New("endpoint is required")
 	}
 
-	if cfg.Timeout <= 0 {
-		cfg.Timeout = 30 * time.Second
+	if err := cfg.validateTransport(); err != nil {
+		return err
+	}
+
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", cfg.Timeout)
+	}
+
+	if cfg.ConversionWorkers < 0 {
+		return fmt.Errorf("conversion_workers must not be negative, got %d", cfg.ConversionWorkers)
+	}
+
+	if cfg.DebugSampleRate < 0 {
+		return fmt.Errorf("debug_sample_rate must not be negative, got %d", cfg.DebugSampleRate)
+	}
+
+	switch cfg.Mode {
+	case "", modeLive, modeDryRun:
+	default:
+		return fmt.Errorf("invalid mode %q: must be %q or %q", cfg.Mode, modeLive, modeDryRun)
+	}
+
+	if err := cfg.validateEncoding(); err != nil {
+		return err
 	}
 
+	if err := cfg.Redaction.Validate(); err != nil {
+		return err
+	}
+
+	return cfg.validatePayloadFormat()
+}
+
+// isDryRun reports whether the exporter should write requests instead of sending them
+func (cfg *Config) isDryRun() bool {
+	return cfg.Mode == modeDryRun
+}
+
+// validateEndpoint checks that endpoint is an absolute http or https URL with a host
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid endpoint %q: scheme must be http or https", endpoint)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q: host is required", endpoint)
+	}
 	return nil
 }
 
 // createDefaultRetrySettings creates default retry settings
-func createDefaultRetrySettings() map[string]interface{} {
-	return map[string]interface{}{
-		"enabled":              true,
-		"initial_interval":     "5s",
-		"randomization_factor": 0.5,
-		"multiplier":           1.5,
-		"max_interval":         "30s",
-		"max_elapsed_time":     "5m",
-	}
+func createDefaultRetrySettings() configretry.BackOffConfig {
+	settings := configretry.NewDefaultBackOffConfig()
+	settings.Enabled = true
+	settings.InitialInterval = 5 * time.Second
+	settings.RandomizationFactor = 0.5
+	settings.Multiplier = 1.5
+	settings.MaxInterval = 30 * time.Second
+	settings.MaxElapsedTime = 5 * time.Minute
+	return settings
 }
 
-// createDefaultQueueSettings creates default queue settings
-func createDefaultQueueSettings() map[string]interface{} {
-	return map[string]interface{}{
-		"enabled":       true,
-		"num_consumers": 10,
-		"queue_size":    1000,
-	}
+// createDefaultQueueSettings creates default queue settings. Batching across requests is
+// disabled unless a sending_queue::batch section is configured.
+func createDefaultQueueSettings() exporterhelper.QueueBatchConfig {
+	settings := exporterhelper.NewDefaultQueueConfig()
+	settings.Enabled = true
+	settings.NumConsumers = 10
+	settings.QueueSize = 1000
+	return settings
 }
@@ -1,8 +1,21 @@
 package exporter
 
 import (
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configoptional"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+
+	"github.com/henrikrexed/SecurityEventExporter/internal/metadata"
 )
 
 func TestConfigValidation(t *testing.T) {
@@ -33,16 +46,70 @@ func TestConfigValidation(t *testing.T) {
 				Endpoint: "https://example.com/events",
 				Timeout:  0,
 			},
-			wantErr: false, // Should set default timeout
+			wantErr: false, // Uses the 30s default
+		},
+		{
+			name: "negative timeout",
+			config: Config{
+				Endpoint: "https://example.com/events",
+				Timeout:  -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative endpoint",
+			config: Config{
+				Endpoint: "/events",
+				Timeout:  30 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported endpoint scheme",
+			config: Config{
+				Endpoint: "ftp://example.com/events",
+				Timeout:  30 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "endpoint without host",
+			config: Config{
+				Endpoint: "https:///events",
+				Timeout:  30 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "dry run mode",
+			config: Config{
+				Endpoint: "https://example.com/events",
+				Timeout:  30 * time.Second,
+				Mode:     "dry_run",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid mode",
+			config: Config{
+				Endpoint: "https://example.com/events",
+				Timeout:  30 * time.Second,
+				Mode:     "test",
+			},
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			before := tt.config
 			err := tt.config.Validate()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Config.Validate() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !reflect.DeepEqual(before, tt.config) {
+				t.Errorf("Config.Validate() modified the config: %+v -> %+v", before, tt.config)
+			}
 		})
 	}
 }
@@ -50,23 +117,398 @@ func TestConfigValidation(t *testing.T) {
 func TestCreateDefaultRetrySettings(t *testing.T) {
 	settings := createDefaultRetrySettings()
 
-	if settings == nil {
-		t.Error("createDefaultRetrySettings() returned nil")
+	if !settings.Enabled {
+		t.Error("Default retry settings should have enabled = true")
 	}
 
-	if settings["enabled"] != true {
-		t.Error("Default retry settings should have enabled = true")
+	if err := settings.Validate(); err != nil {
+		t.Errorf("Default retry settings should be valid: %v", err)
 	}
 }
 
 func TestCreateDefaultQueueSettings(t *testing.T) {
 	settings := createDefaultQueueSettings()
 
-	if settings == nil {
-		t.Error("createDefaultQueueSettings() returned nil")
+	if !settings.Enabled {
+		t.Error("Default queue settings should have enabled = true")
 	}
 
-	if settings["enabled"] != true {
-		t.Error("Default queue settings should have enabled = true")
+	if settings.NumConsumers != 10 || settings.QueueSize != 1000 {
+		t.Errorf("Unexpected default queue settings: num_consumers=%d queue_size=%d", settings.NumConsumers, settings.QueueSize)
+	}
+
+	if settings.Batch.HasValue() {
+		t.Error("Default queue settings should not batch across requests")
+	}
+}
+
+func TestConfigStruct(t *testing.T) {
+	if err := componenttest.CheckConfigStruct(createDefaultConfig()); err != nil {
+		t.Errorf("Config struct is invalid: %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cm, err := confmaptest.LoadConf(filepath.Join("testdata", "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load testdata config: %v", err)
+	}
+
+	tests := []struct {
+		id       component.ID
+		expected func(cfg *Config)
+		errorMsg string
+	}{
+		{
+			id:       component.NewID(metadata.Type),
+			expected: func(cfg *Config) {},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "full"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/api/events"
+				cfg.Timeout = 10 * time.Second
+				cfg.Headers = map[string]configopaque.String{"Authorization": "Bearer token"}
+				// Configured attributes are merged with the default ones
+				cfg.DefaultAttributes["environment"] = "production"
+				cfg.RetrySettings.InitialInterval = time.Second
+				cfg.RetrySettings.RandomizationFactor = 0.2
+				cfg.RetrySettings.Multiplier = 2
+				cfg.RetrySettings.MaxInterval = 10 * time.Second
+				cfg.RetrySettings.MaxElapsedTime = time.Minute
+				cfg.QueueSettings.NumConsumers = 4
+				cfg.QueueSettings.QueueSize = 500
+				cfg.QueueSettings.Batch = configoptional.Some(exporterhelper.BatchConfig{
+					FlushTimeout: 5 * time.Second,
+					Sizer:        exporterhelper.RequestSizerTypeItems,
+					MinSize:      100,
+				})
+				cfg.Mode = modeDryRun
+				cfg.DryRunOutput = "/tmp/security-events.ndjson"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "disabled_retry_and_queue"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/api/events"
+				cfg.RetrySettings.Enabled = false
+				cfg.QueueSettings.Enabled = false
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "zero_timeout"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/api/events"
+				cfg.Timeout = 0
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "ocsf"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/api/events"
+				cfg.Encoding = encodingOCSF
+				cfg.OCSF = OCSFConfig{
+					Product: OCSFProductConfig{Name: "auth-gateway", VendorName: "Acme"},
+					Rules: []OCSFRule{{
+						Class:             "authentication",
+						Match:             map[string]string{"event.category": "authentication"},
+						ActivityAttribute: "event.action",
+					}},
+				}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "cef"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/api/events"
+				cfg.Encoding = encodingCEF
+				cfg.CEF.DeviceVendor = FieldSource{Value: "Acme"}
+				cfg.CEF.SignatureID = FieldSource{Attributes: []string{"rule.id"}, Value: "generic"}
+				cfg.CEF.SeverityMap = map[string]int{"ERROR": 8}
+				cfg.CEF.Extensions = map[string]string{"tenant.id": "cs2"}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "leef"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://qradar.example.com/api/events"
+				cfg.Encoding = encodingLEEF
+				cfg.LEEF.Delimiter = "^"
+				cfg.LEEF.EventID = FieldSource{Attributes: []string{"qradar.qid"}, Value: "1002"}
+				cfg.LEEF.Keys = map[string]string{"tenant.id": "tenant"}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "syslog"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "tls://siem.example.com:6514"
+				cfg.Transport = transportSyslog
+				cfg.Encoding = encodingCEF
+				cfg.Syslog.Protocol = syslogRFC3164
+				cfg.Syslog.Framing = syslogNonTransparent
+				cfg.Syslog.Facility = "authpriv"
+				cfg.Syslog.AppName = FieldSource{Attributes: []string{"service.name"}, Value: "security"}
+				cfg.Syslog.TLS.CAFile = "/etc/ssl/siem-ca.pem"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "splunk_hec"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://splunk.example.com:8088/services/collector/event"
+				cfg.Transport = transportSplunkHEC
+				cfg.SplunkHEC.Token = "00000000-0000-0000-0000-000000000001"
+				cfg.SplunkHEC.Index = FieldSource{Value: "security"}
+				cfg.SplunkHEC.UseAck = true
+				cfg.SplunkHEC.AckTimeout = time.Minute
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "sentinel"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://security-dce.eastus-1.ingest.monitor.azure.com"
+				cfg.Transport = transportSentinel
+				cfg.Sentinel.DCRImmutableID = "dcr-00000000000000000000000000000000"
+				cfg.Sentinel.StreamName = "Custom-SecurityEvents_CL"
+				cfg.Sentinel.TenantID = "00000000-0000-0000-0000-00000000000a"
+				cfg.Sentinel.ClientID = "00000000-0000-0000-0000-00000000000b"
+				cfg.Sentinel.ClientSecret = "secret"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "chronicle"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://europe-malachiteingestion-pa.googleapis.com"
+				cfg.Transport = transportChronicle
+				cfg.Encoding = encodingUDM
+				cfg.UDM.VendorName = FieldSource{Value: "Acme"}
+				cfg.UDM.Rules = []UDMRule{{EventType: "USER_LOGIN", Match: map[string]string{"event.action": "login"}}}
+				cfg.UDM.Fields = map[string]string{"tenant.id": "target.user.company_name"}
+				cfg.Chronicle.CustomerID = "c0ffee00-0000-0000-0000-000000000000"
+				cfg.Chronicle.CredentialsFile = "/etc/chronicle/service-account.json"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "security_hub"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://securityhub.eu-west-1.amazonaws.com"
+				cfg.Transport = transportSecurityHub
+				cfg.Encoding = encodingASFF
+				cfg.ASFF.ProductArn = "arn:aws:securityhub:eu-west-1:123456789012:product/123456789012/default"
+				cfg.ASFF.AWSAccountID.Value = "123456789012"
+				cfg.ASFF.TypeRules = []ASFFTypeRule{{Types: []string{"TTPs/Credential Access"}, Match: map[string]string{"event.outcome": "failure"}}}
+				cfg.ASFF.SeverityMap = map[string]string{"ERROR": "CRITICAL"}
+				cfg.SecurityHub.Region = "eu-west-1"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "elasticsearch"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://elasticsearch.example.com:9200"
+				cfg.Transport = transportElasticsearch
+				cfg.Encoding = encodingECS
+				cfg.Headers = map[string]configopaque.String{"Authorization": "ApiKey ZXhhbXBsZQ=="}
+				cfg.Elasticsearch.Index = "logs-security-%{k8s.namespace.name:default}"
+				cfg.Elasticsearch.Pipeline = "security-enrich"
+				cfg.Elasticsearch.OpType = opTypeCreate
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "loki"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "http://loki.example.com:3100"
+				cfg.Transport = transportLoki
+				cfg.Loki.Format = lokiFormatJSON
+				cfg.Loki.TenantID = FieldSource{Attributes: []string{"tenant.id"}, Value: "security"}
+				cfg.Loki.LabelAttributes = []string{"k8s.namespace.name", "event.category"}
+				cfg.Loki.Labels["cluster"] = "prod"
+				cfg.Loki.MaxLabelValues = 50
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "kafka"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "kafka://broker-1:9092,broker-2:9092"
+				cfg.Transport = transportKafka
+				cfg.Kafka.Topic = "security-%{service.name}"
+				cfg.Kafka.PartitionKey.Attributes = []string{"source.ip"}
+				cfg.Kafka.Headers = map[string]string{"schema": "security-event/v1"}
+				cfg.Kafka.HeaderAttributes = []string{"tenant.id"}
+				cfg.Kafka.Compression = "zstd"
+				cfg.Kafka.SASL = KafkaSASLConfig{Mechanism: "SCRAM-SHA-512", Username: "exporter", Password: "secret"}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "payload_wrapper"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://ingest.example.com/v1/security-events"
+				cfg.PayloadFormat = payloadFormatJSONObjectWrapper
+				cfg.PayloadWrapperKey = "records"
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "conversion_workers"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/security-events"
+				cfg.ConversionWorkers = 0
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "debug_logging"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/security-events"
+				cfg.DebugSampleRate = 100
+				cfg.TraceConversion = true
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "redaction"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/security-events"
+				cfg.Redaction = RedactionConfig{
+					HashKey: "pepper",
+					Rules: []RedactionRule{
+						{Name: "secrets", Keys: []string{"*.password", "*.token"}, KeyMatch: keyMatchGlob, Action: redactionRemove},
+						{Name: "cards", Detectors: []string{"credit_card"}, Action: redactionPartial},
+					},
+				}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "cloudevents"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "http://broker-ingress.knative-eventing.svc.cluster.local/security/default"
+				cfg.Transport = transportCloudEvents
+				cfg.CloudEvents.Mode = cloudEventsBinary
+				cfg.CloudEvents.Type = FieldSource{Attributes: []string{"event.category"}, Value: "com.example.security"}
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "file"),
+			expected: func(cfg *Config) {
+				cfg.Transport = transportFile
+				cfg.File.Path = "/var/log/otelcol/security-events.log"
+				cfg.File.Format = fileFormatCEF
+				cfg.File.MaxSize = 10 << 20
+				cfg.File.RotationInterval = time.Hour
+				cfg.File.MaxBackups = 24
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "archive"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://siem.example.com/security-events"
+				archive := createDefaultArchiveConfig()
+				archive.Endpoint = "http://minio.example.com:9000"
+				archive.Bucket = "security-archive"
+				archive.PathStyle = true
+				archive.Region = "us-east-1"
+				archive.Compression = archiveCompressionZstd
+				archive.MaxFileAge = time.Minute
+				archive.ObjectLock = ArchiveObjectLockConfig{Mode: "COMPLIANCE", Retention: 8760 * time.Hour}
+				cfg.Archive = configoptional.Some(archive)
+			},
+		},
+		{
+			id: component.NewIDWithName(metadata.Type, "sentinel_data_collector"),
+			expected: func(cfg *Config) {
+				cfg.Endpoint = "https://00000000-0000-0000-0000-00000000000c.ods.opinsights.azure.com"
+				cfg.Transport = transportSentinel
+				cfg.Sentinel.API = sentinelDataCollector
+				cfg.Sentinel.WorkspaceID = "00000000-0000-0000-0000-00000000000c"
+				cfg.Sentinel.SharedKey = "c2hhcmVkLWtleQ=="
+				cfg.Sentinel.LogType = "SecurityEvents"
+				cfg.Sentinel.TimeGeneratedField = "EventTime"
+			},
+		},
+		{id: component.NewIDWithName(metadata.Type, "invalid_encoding"), errorMsg: "invalid encoding"},
+		{id: component.NewIDWithName(metadata.Type, "splunk_hec_missing_token"), errorMsg: "splunk_hec token is required"},
+		{id: component.NewIDWithName(metadata.Type, "chronicle_without_udm"), errorMsg: "requires the \"udm\" encoding"},
+		{id: component.NewIDWithName(metadata.Type, "security_hub_missing_region"), errorMsg: "security_hub region is required"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_elasticsearch_op_type"), errorMsg: "invalid elasticsearch op_type"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_loki_label"), errorMsg: "invalid loki label name"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_kafka_acks"), errorMsg: "invalid kafka acks"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_payload_format"), errorMsg: "invalid payload_format"},
+		{id: component.NewIDWithName(metadata.Type, "negative_conversion_workers"), errorMsg: "conversion_workers must not be negative"},
+		{id: component.NewIDWithName(metadata.Type, "negative_debug_sample_rate"), errorMsg: "debug_sample_rate must not be negative"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_redaction_detector"), errorMsg: "unknown detector"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_cloudevents_mode"), errorMsg: "invalid cloudevents mode"},
+		{id: component.NewIDWithName(metadata.Type, "file_missing_path"), errorMsg: "file path is required"},
+		{id: component.NewIDWithName(metadata.Type, "archive_missing_bucket"), errorMsg: "archive bucket is required"},
+		{id: component.NewIDWithName(metadata.Type, "sentinel_missing_stream"), errorMsg: "sentinel logs_ingestion requires stream_name"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_transport"), errorMsg: "invalid transport"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_syslog_endpoint"), errorMsg: "scheme must be udp, tcp or tls"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_cef_severity"), errorMsg: "between 0 and 10"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_leef_delimiter"), errorMsg: "invalid leef delimiter"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_ocsf_class"), errorMsg: "unknown class"},
+		{id: component.NewIDWithName(metadata.Type, "negative_timeout"), errorMsg: "timeout must not be negative"},
+		{id: component.NewIDWithName(metadata.Type, "missing_endpoint"), errorMsg: "endpoint is required"},
+		{id: component.NewIDWithName(metadata.Type, "relative_endpoint"), errorMsg: "scheme must be http or https"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_mode"), errorMsg: "invalid mode"},
+		{id: component.NewIDWithName(metadata.Type, "unknown_key"), errorMsg: "max_retries"},
+		{id: component.NewIDWithName(metadata.Type, "unknown_retry_key"), errorMsg: "max_retry"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_retry"), errorMsg: "randomization_factor"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_queue"), errorMsg: "num_consumers"},
+		{id: component.NewIDWithName(metadata.Type, "invalid_timeout_type"), errorMsg: "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id.String(), func(t *testing.T) {
+			sub, err := cm.Sub(tt.id.String())
+			if err != nil {
+				t.Fatalf("Failed to get config section: %v", err)
+			}
+
+			cfg := createDefaultConfig().(*Config)
+			err = sub.Unmarshal(cfg)
+			if err == nil {
+				err = xconfmap.Validate(cfg)
+			}
+
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := createDefaultConfig().(*Config)
+			tt.expected(expected)
+			if !reflect.DeepEqual(expected, cfg) {
+				t.Errorf("Unexpected config:\n got: %+v\nwant: %+v", cfg, expected)
+			}
+		})
+	}
+}
+
+func TestCreateDefaultConfigIsIndependent(t *testing.T) {
+	first := createDefaultConfig().(*Config)
+	first.DefaultAttributes["environment"] = "test"
+
+	second := createDefaultConfig().(*Config)
+	if _, ok := second.DefaultAttributes["environment"]; ok {
+		t.Error("Default configs should not share state")
+	}
+	if second.Timeout != defaultTimeout || second.Mode != modeLive {
+		t.Errorf("Unexpected defaults: timeout=%s mode=%q", second.Timeout, second.Mode)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{timeout: 0, expected: defaultTimeout},
+		{timeout: 5 * time.Second, expected: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{Timeout: tt.timeout}
+		if got := cfg.requestTimeout(); got != tt.expected {
+			t.Errorf("requestTimeout() with timeout %s = %s, want %s", tt.timeout, got, tt.expected)
+		}
 	}
 }
@@ -0,0 +1,204 @@
+package exporter
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// conversionChunkSize is the largest number of log records converted as one chunk. Scope logs
+// with more records are split, so a single large scope still spreads over the workers.
+const conversionChunkSize = 512
+
+// conversionPool bounds the goroutines converting the chunks of batches. Its slots are shared by
+// all batches the exporter converts at the same time, and the goroutine converting a batch always
+// takes part itself, so a batch never waits for a slot to free up.
+type conversionPool struct {
+	slots chan struct{}
+}
+
+// newConversionPool creates the pool for the conversion_workers setting. It returns nil, which
+// converts serially, for a single worker; zero uses one worker per available CPU.
+func newConversionPool(workers int) *conversionPool {
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers <= 1 {
+		return nil
+	}
+	// The caller is one of the workers
+	return &conversionPool{slots: make(chan struct{}, workers-1)}
+}
+
+// run calls fn for every index below n and returns once all calls are done. The indexes are
+// handed out in order to the caller and to as many extra goroutines as there are free slots.
+func (p *conversionPool) run(n int, fn func(i int)) {
+	var next atomic.Int64
+	work := func() {
+		for {
+			i := int(next.Add(1)) - 1
+			if i >= n {
+				return
+			}
+			fn(i)
+		}
+	}
+
+	var wg sync.WaitGroup
+acquire:
+	for helpers := 0; p != nil && helpers < n-1; helpers++ {
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-p.slots
+					wg.Done()
+				}()
+				work()
+			}()
+		default:
+			break acquire
+		}
+	}
+	work()
+	wg.Wait()
+}
+
+// conversionChunk is a range of the log records of one scope log
+type conversionChunk struct {
+	resourceLog   plog.ResourceLogs
+	scopeLog      plog.ScopeLogs
+	resourceIndex int
+	scopeIndex    int
+	start         int
+	end           int
+}
+
+// conversionChunks splits the log records of ld into chunks in record order. Scope logs without
+// records have no chunk.
+func (e *securityEventExporter) conversionChunks(ld plog.Logs) []conversionChunk {
+	debug := e.logger.Core().Enabled(zapcore.DebugLevel)
+	var chunks []conversionChunk
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		resourceLog := ld.ResourceLogs().At(i)
+
+		if debug {
+			e.logger.Debug("Processing resource log",
+				zap.Int("resource_index", i),
+				zap.Int("scope_logs_count", resourceLog.ScopeLogs().Len()))
+		}
+
+		for j := 0; j < resourceLog.ScopeLogs().Len(); j++ {
+			scopeLog := resourceLog.ScopeLogs().At(j)
+			logRecordsCount := scopeLog.LogRecords().Len()
+
+			if debug {
+				e.logger.Debug("Processing scope log",
+					zap.Int("scope_index", j),
+					zap.Int("log_records_count", logRecordsCount))
+			}
+
+			for start := 0; start < logRecordsCount; start += conversionChunkSize {
+				chunks = append(chunks, conversionChunk{
+					resourceLog:   resourceLog,
+					scopeLog:      scopeLog,
+					resourceIndex: i,
+					scopeIndex:    j,
+					start:         start,
+					end:           min(start+conversionChunkSize, logRecordsCount),
+				})
+			}
+		}
+	}
+	return chunks
+}
+
+// convertedChunk holds the events converted from a chunk
+type convertedChunk struct {
+	events           []map[string]interface{}
+	sources          []eventSource
+	conversionErrors int
+}
+
+// convertChunk converts the log records of a chunk to security events
+func (e *securityEventExporter) convertChunk(chunk conversionChunk) convertedChunk {
+	converted := convertedChunk{
+		events:  make([]map[string]interface{}, 0, chunk.end-chunk.start),
+		sources: make([]eventSource, 0, chunk.end-chunk.start),
+	}
+	resource := chunk.resourceLog.Resource()
+	logRecords := chunk.scopeLog.LogRecords()
+	for k := chunk.start; k < chunk.end; k++ {
+		logRecord := logRecords.At(k)
+
+		logging := e.recordLogging()
+		if logging >= recordLogDebug {
+			e.logger.Debug("Processing log record",
+				zap.Int("log_index", k),
+				zap.String("severity", logRecord.SeverityText()),
+				zap.Int64("timestamp", logRecord.Timestamp().AsTime().Unix()))
+		}
+
+		// Convert log to security event
+		securityEvent, err := e.convertLogToSecurityEvent(logRecord, resource, logging)
+		if err != nil {
+			e.logger.Error("Failed to convert log to security event",
+				zap.Error(err),
+				zap.Int("resource_index", chunk.resourceIndex),
+				zap.Int("scope_index", chunk.scopeIndex),
+				zap.Int("log_index", k),
+				zap.String("severity", logRecord.SeverityText()))
+			converted.conversionErrors++
+			continue
+		}
+
+		e.redactor.redactEvent(securityEvent)
+
+		source := newEventSource(securityEvent, logRecord)
+		source.resource, source.scope = resource, chunk.scopeLog.Scope()
+		if e.encoder != nil {
+			securityEvent, err = e.encoder.encode(securityEvent, logRecord, resource)
+			if err != nil {
+				e.logger.Error("Failed to encode security event",
+					zap.Error(err),
+					zap.String("encoding", e.config.Encoding),
+					zap.Int("resource_index", chunk.resourceIndex),
+					zap.Int("scope_index", chunk.scopeIndex),
+					zap.Int("log_index", k))
+				converted.conversionErrors++
+				continue
+			}
+		}
+
+		if logging >= recordLogDebug {
+			e.logger.Debug("Successfully converted log to security event",
+				zap.Int("event_field_count", len(securityEvent)))
+		}
+
+		converted.events = append(converted.events, securityEvent)
+		converted.sources = append(converted.sources, source)
+	}
+	return converted
+}
+
+// streamChunk writes the events of a chunk to buf, separated by commas for JSON arrays and each
+// followed by a newline for ndjson
+func (s *eventStreamer) streamChunk(buf *bytes.Buffer, chunk conversionChunk, ndjson bool) {
+	resource := chunk.resourceLog.Resource()
+	logRecords := chunk.scopeLog.LogRecords()
+	for k := chunk.start; k < chunk.end; k++ {
+		if k > chunk.start && !ndjson {
+			buf.WriteByte(',')
+		}
+		s.writeEvent(buf, logRecords.At(k), resource)
+		if ndjson {
+			buf.WriteByte('\n')
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newConversionTestLogs creates one resource log per entry of scopes, with one scope log per
+// record count. Every third record overrides a resource attribute.
+func newConversionTestLogs(scopes ...[]int) plog.Logs {
+	logs := plog.NewLogs()
+	for i, counts := range scopes {
+		resourceLogs := logs.ResourceLogs().AppendEmpty()
+		resourceLogs.Resource().Attributes().PutStr("service.name", fmt.Sprintf("service-%d", i))
+		resourceLogs.Resource().Attributes().PutStr("host.name", "web-1")
+		for j, count := range counts {
+			scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+			scopeLogs.Scope().SetName(fmt.Sprintf("scope-%d", j))
+			for k := 0; k < count; k++ {
+				record := scopeLogs.LogRecords().AppendEmpty()
+				record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20, k, time.UTC)))
+				record.Attributes().PutStr("event.category", "authentication")
+				record.Attributes().PutStr("user.name", fmt.Sprintf("user-%d-%d-%d", i, j, k))
+				record.Attributes().PutInt("source.port", int64(k))
+				if k%3 == 0 {
+					record.Attributes().PutStr("host.name", "web-2")
+				}
+			}
+		}
+	}
+	return logs
+}
+
+func TestConversionPoolBoundsWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    *conversionPool
+		maxRuns int32
+	}{
+		{name: "serial", pool: newConversionPool(1), maxRuns: 1},
+		{name: "three workers", pool: newConversionPool(3), maxRuns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning atomic.Int32
+			calls := make([]int32, 50)
+			var wg sync.WaitGroup
+			// Concurrent batches share the workers of the pool
+			for batch := 0; batch < 2; batch++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					tt.pool.run(len(calls), func(i int) {
+						current := running.Add(1)
+						for {
+							seen := maxRunning.Load()
+							if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+								break
+							}
+						}
+						time.Sleep(time.Millisecond)
+						atomic.AddInt32(&calls[i], 1)
+						running.Add(-1)
+					})
+				}()
+			}
+			wg.Wait()
+
+			// Each batch's own goroutine takes part on top of the shared workers
+			if limit := tt.maxRuns + 1; maxRunning.Load() > limit {
+				t.Errorf("Up to %d tasks ran at once, want at most %d", maxRunning.Load(), limit)
+			}
+			for i, count := range calls {
+				if count != 2 {
+					t.Errorf("Task %d ran %d times, want 2", i, count)
+				}
+			}
+		})
+	}
+}
+
+func TestConversionChunks(t *testing.T) {
+	exp := newStreamTestExporter(nil)
+	chunks := exp.conversionChunks(newConversionTestLogs([]int{0, 1200}, []int{3}))
+
+	want := [][4]int{{0, 1, 0, 512}, {0, 1, 512, 1024}, {0, 1, 1024, 1200}, {1, 0, 0, 3}}
+	if len(chunks) != len(want) {
+		t.Fatalf("Got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, chunk := range chunks {
+		got := [4]int{chunk.resourceIndex, chunk.scopeIndex, chunk.start, chunk.end}
+		if got != want[i] {
+			t.Errorf("Chunk %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestConvertLogsInParallel(t *testing.T) {
+	ld := newConversionTestLogs([]int{700, 0, 5}, []int{1500}, []int{1})
+
+	for _, encoding := range []string{encodingJSON, encodingOCSF, encodingECS, encodingUDM, encodingCEF} {
+		t.Run(encoding, func(t *testing.T) {
+			convert := func(workers int) ([]map[string]interface{}, []eventSource, int64) {
+				exp := newStreamTestExporter(func(cfg *Config) {
+					cfg.Encoding = encoding
+				})
+				encoder, err := newEventEncoder(exp.config)
+				if err != nil {
+					t.Fatalf("newEventEncoder() returned error: %v", err)
+				}
+				exp.encoder = encoder
+				exp.conversion = newConversionPool(workers)
+
+				events, sources, total, conversionErrors := exp.convertLogs(ld)
+				if total != ld.LogRecordCount() || conversionErrors != 0 {
+					t.Fatalf("convertLogs() saw %d records with %d errors, want %d without errors", total, conversionErrors, ld.LogRecordCount())
+				}
+				return events, sources, exp.metrics.attributeConflicts.Load()
+			}
+
+			serialEvents, serialSources, serialConflicts := convert(1)
+			events, sources, conflicts := convert(4)
+			if len(events) != ld.LogRecordCount() {
+				t.Fatalf("Got %d events, want %d", len(events), ld.LogRecordCount())
+			}
+			if !reflect.DeepEqual(events, serialEvents) {
+				t.Errorf("Events converted in parallel differ from the serial conversion")
+			}
+			for i := range sources {
+				if sources[i].attributes["user.name"] != serialSources[i].attributes["user.name"] ||
+					sources[i].scope.Name() != serialSources[i].scope.Name() {
+					t.Errorf("Source %d = %v, want %v", i, sources[i].attributes, serialSources[i].attributes)
+					break
+				}
+			}
+			if conflicts != serialConflicts || conflicts == 0 {
+				t.Errorf("attributeConflicts = %d, want %d", conflicts, serialConflicts)
+			}
+		})
+	}
+}
+
+func TestStreamLogsInParallel(t *testing.T) {
+	ld := newConversionTestLogs([]int{700, 0, 5}, []int{1500}, []int{1})
+
+	for _, format := range []string{payloadFormatJSONArray, payloadFormatNDJSON, payloadFormatJSONObjectWrapper} {
+		t.Run(format, func(t *testing.T) {
+			exp := newStreamTestExporter(func(cfg *Config) {
+				cfg.PayloadFormat = format
+				cfg.DefaultAttributes = map[string]interface{}{"environment": "production"}
+			})
+			var want bytes.Buffer
+			if _, err := exp.streamLogs(ld, &want); err != nil {
+				t.Fatalf("streamLogs() returned error: %v", err)
+			}
+			serialConflicts := exp.metrics.attributeConflicts.Load()
+			exp.metrics.attributeConflicts.Store(0)
+
+			exp.conversion = newConversionPool(4)
+			var buf bytes.Buffer
+			count, err := exp.streamLogs(ld, &buf)
+			if err != nil {
+				t.Fatalf("streamLogs() returned error: %v", err)
+			}
+			if count != ld.LogRecordCount() {
+				t.Errorf("streamLogs() wrote %d events, want %d", count, ld.LogRecordCount())
+			}
+			if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+				t.Errorf("Batch streamed in parallel differs from the serial one")
+			}
+			if exp.metrics.attributeConflicts.Load() != serialConflicts {
+				t.Errorf("attributeConflicts = %d, want %d", exp.metrics.attributeConflicts.Load(), serialConflicts)
+			}
+		})
+	}
+}
+
+// BenchmarkConversionWorkers converts and streams batches of 10000 records spread over several
+// resource logs with an increasing number of conversion workers
+func BenchmarkConversionWorkers(b *testing.B) {
+	ld := newConversionTestLogs([]int{2500, 2500}, []int{2500}, []int{2500})
+	events := ld.LogRecordCount()
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		exp := newStreamTestExporter(nil)
+		exp.conversion = newConversionPool(workers)
+
+		b.Run(fmt.Sprintf("maps/workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				securityEvents, _, _, _ := exp.convertLogs(ld)
+				data, _, err := exp.marshalBatch(securityEvents)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*events), "ns/event")
+		})
+
+		b.Run(fmt.Sprintf("streaming/workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				body := newBatchBody()
+				if _, err := exp.streamLogs(ld, body.buf); err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(body.buf.Len()))
+				body.release()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*events), "ns/event")
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// ConvertLogs runs ld through the same Config-driven conversion pipeline as the exporter and
+// returns the resulting security events in record order. Nothing is sent; this backs offline
+// tooling such as the securityevent-convert CLI. Records that fail conversion are skipped and
+// reported in the returned error alongside the events that did convert.
+func ConvertLogs(cfg *Config, logger *zap.Logger, ld plog.Logs) ([]map[string]interface{}, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	encoder, err := newEventEncoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := newRedactor(&cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &securityEventExporter{
+		config:     cfg,
+		logger:     logger,
+		encoder:    encoder,
+		redactor:   redactor,
+		metrics:    &exporterMetrics{},
+		conversion: newConversionPool(cfg.ConversionWorkers),
+	}
+
+	securityEvents, _, _, conversionErrors := exp.convertLogs(ld)
+	if conversionErrors > 0 {
+		return securityEvents, fmt.Errorf("%d log records failed conversion", conversionErrors)
+	}
+	return securityEvents, nil
+}
+
+// FormatLines renders events converted with a line-oriented encoding, such as cef or leef, to the lines
+// the exporter sends. It fails for encodings whose events are sent as JSON.
+func FormatLines(cfg *Config, events []map[string]interface{}) ([]string, error) {
+	encoder, err := newEventEncoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lines, ok := encoder.(lineEncoder)
+	if !ok {
+		return nil, fmt.Errorf("encoding %q is not line-oriented", cfg.Encoding)
+	}
+
+	rendered := make([]string, 0, len(events))
+	for _, event := range events {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, line)
+	}
+	return rendered, nil
+}
+
+// DefaultConfig returns the exporter's default configuration, the base that user
+// configuration is merged onto
+func DefaultConfig() *Config {
+	return createDefaultConfig().(*Config)
+}
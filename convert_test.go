@@ -0,0 +1,44 @@
+package exporter
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestConvertLogs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DefaultAttributes = map[string]interface{}{
+		"source":      "opentelemetry-collector",
+		"environment": "test",
+	}
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "auth-service")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Attributes().PutStr("user.id", "alice")
+	records.AppendEmpty().Attributes().PutStr("user.id", "bob")
+
+	events, err := ConvertLogs(cfg, nil, ld)
+	if err != nil {
+		t.Fatalf("ConvertLogs() returned error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	// Record order is preserved
+	if events[0]["user.id"] != "alice" || events[1]["user.id"] != "bob" {
+		t.Errorf("Unexpected event order: %v", events)
+	}
+	if events[0]["environment"] != "test" {
+		t.Errorf("Expected default attribute environment=test, got %v", events[0]["environment"])
+	}
+	if _, ok := events[0]["source"]; ok {
+		t.Error("Default attribute source should be excluded from events")
+	}
+	if events[0]["service.name"] != "auth-service" {
+		t.Errorf("Expected resource attribute service.name, got %v", events[0]["service.name"])
+	}
+}
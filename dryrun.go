@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maskedHeaderValue replaces the value of sensitive headers in dry-run output
+const maskedHeaderValue = "****"
+
+// dryRunWriter writes the requests the exporter would have sent, one JSON document per line
+type dryRunWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// dryRunRequest is the JSON representation of a request written in dry-run mode
+type dryRunRequest struct {
+	Time         string            `json:"time"`
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers"`
+	EventCount   int               `json:"event_count"`
+	Body         interface{}       `json:"body"`
+	BodyEncoding string            `json:"body_encoding,omitempty"`
+}
+
+// newDryRunWriter opens the dry-run output: stdout when output is empty or "stdout", otherwise
+// the file at output, which is created or appended to
+func newDryRunWriter(output string) (*dryRunWriter, error) {
+	if output == "" || output == dryRunStdout {
+		return &dryRunWriter{out: os.Stdout}, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dry-run output %q: %w", output, err)
+	}
+	return &dryRunWriter{out: file, closer: file}, nil
+}
+
+// write records a would-be request, masking sensitive header values
+func (w *dryRunWriter) write(req *http.Request, body []byte, eventCount int) error {
+	return w.writeRecord(req.Method, req.URL.String(), req.Header, body, eventCount)
+}
+
+// writeRecord records a would-be delivery. Transports other than HTTP use their name as the
+// method and have no headers.
+func (w *dryRunWriter) writeRecord(method, target string, header http.Header, body []byte, eventCount int) error {
+	headers := make(map[string]string, len(header))
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := header.Get(name)
+		if isSensitiveHeader(name) {
+			value = maskedHeaderValue
+		}
+		headers[name] = value
+	}
+
+	// Keep JSON bodies structured so the output can be inspected with jq, and binary bodies intact
+	var payload interface{} = string(body)
+	var bodyEncoding string
+	switch {
+	case json.Valid(body):
+		payload = json.RawMessage(body)
+	case header.Get("Content-Type") == "application/x-protobuf" || !utf8.Valid(body):
+		payload, bodyEncoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+
+	line, err := json.Marshal(dryRunRequest{
+		Time:         time.Now().UTC().Format(time.RFC3339Nano),
+		Method:       method,
+		URL:          target,
+		Headers:      headers,
+		EventCount:   eventCount,
+		Body:         payload,
+		BodyEncoding: bodyEncoding,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dry-run request: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dry-run request: %w", err)
+	}
+	return nil
+}
+
+// close closes the underlying file, if any
+func (w *dryRunWriter) close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
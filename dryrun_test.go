@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestDryRunWritesRequestInsteadOfSending(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "dry-run.ndjson")
+	exp := &securityEventExporter{
+		logger: zap.NewNop(),
+		config: &Config{
+			// Unroutable endpoint: the test fails if the exporter tries to send
+			Endpoint:     "http://127.0.0.1:1/security-events",
+			Timeout:      time.Second,
+			Mode:         modeDryRun,
+			DryRunOutput: output,
+			Headers: map[string]configopaque.String{
+				"Authorization": "Bearer secret-token",
+				"X-Tenant":      "acme",
+			},
+		},
+		metrics: &exporterMetrics{},
+	}
+
+	ctx := context.Background()
+	if err := exp.Start(ctx, &mockHost{}); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "auth-service")
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Attributes().PutStr("user.id", "alice")
+
+	if err := exp.ConsumeLogs(ctx, ld); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := exp.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() returned error: %v", err)
+	}
+
+	if exp.metrics.httpRequests.Load() != 0 {
+		t.Errorf("Expected no HTTP requests in dry-run mode, got %d", exp.metrics.httpRequests.Load())
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("Failed to open dry-run output: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("Expected one dry-run request in output")
+	}
+
+	var got struct {
+		Method     string                   `json:"method"`
+		URL        string                   `json:"url"`
+		Headers    map[string]string        `json:"headers"`
+		EventCount int                      `json:"event_count"`
+		Body       []map[string]interface{} `json:"body"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run request: %v", err)
+	}
+
+	if got.Method != "POST" || got.URL != exp.config.Endpoint {
+		t.Errorf("Unexpected request line: %s %s", got.Method, got.URL)
+	}
+	if got.Headers["Authorization"] != maskedHeaderValue {
+		t.Errorf("Expected Authorization header to be masked, got %q", got.Headers["Authorization"])
+	}
+	if got.Headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected X-Tenant header to be kept, got %q", got.Headers["X-Tenant"])
+	}
+	if got.EventCount != 1 || len(got.Body) != 1 {
+		t.Fatalf("Expected one event, got event_count=%d body=%d", got.EventCount, len(got.Body))
+	}
+	if got.Body[0]["user.id"] != "alice" || got.Body[0]["service.name"] != "auth-service" {
+		t.Errorf("Unexpected event in body: %v", got.Body[0])
+	}
+}
+
+func TestDryRunRequiresStart(t *testing.T) {
+	exp := &securityEventExporter{
+		logger: zap.NewNop(),
+		config: &Config{
+			Endpoint: "http://127.0.0.1:1/security-events",
+			Mode:     modeDryRun,
+		},
+		metrics: &exporterMetrics{},
+	}
+
+	err := exp.sendSecurityEventBatch(context.Background(), []map[string]interface{}{{"k": "v"}})
+	if err == nil {
+		t.Error("Expected error when dry-run output is not open")
+	}
+}
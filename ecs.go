@@ -0,0 +1,301 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// ecsUnmappedNested places attributes without an ECS equivalent at their dotted path
+	ecsUnmappedNested = "nested"
+
+	// ecsUnmappedLabels places attributes without an ECS equivalent under labels
+	ecsUnmappedLabels = "labels"
+
+	// ecsUnmappedDrop drops attributes without an ECS equivalent
+	ecsUnmappedDrop = "drop"
+)
+
+// ECSConfig configures the ECS encoding
+type ECSConfig struct {
+	// Version is reported as ecs.version
+	Version string `mapstructure:"version"`
+
+	// EventKind is the event.kind used when the record has no event.kind attribute
+	EventKind string `mapstructure:"event_kind"`
+
+	// Mappings maps attribute keys to ECS field names, overriding or extending the built-in
+	// translation of OpenTelemetry semantic conventions. An empty field name drops the attribute.
+	Mappings map[string]string `mapstructure:"mappings"`
+
+	// UnmappedAttributes controls attributes with no ECS equivalent: "nested" (default) keeps
+	// them at their dotted path as nested objects, "labels" moves them under labels and "drop"
+	// removes them
+	UnmappedAttributes string `mapstructure:"unmapped_attributes"`
+}
+
+// defaultECSMappings translates OpenTelemetry semantic convention attributes to ECS fields.
+// Attributes that already use ECS names (for example user.name or host.name) need no entry.
+var defaultECSMappings = map[string]string{
+	"client.address":              "source.address",
+	"client.port":                 "source.port",
+	"server.address":              "destination.address",
+	"server.port":                 "destination.port",
+	"network.peer.address":        "source.address",
+	"network.peer.port":           "source.port",
+	"network.protocol.name":       "network.protocol",
+	"enduser.id":                  "user.id",
+	"enduser.role":                "user.roles",
+	"host.arch":                   "host.architecture",
+	"os.type":                     "host.os.type",
+	"os.name":                     "host.os.name",
+	"os.version":                  "host.os.version",
+	"deployment.environment":      "service.environment",
+	"deployment.environment.name": "service.environment",
+	"service.instance.id":         "service.node.name",
+	"k8s.cluster.name":            "orchestrator.cluster.name",
+	"k8s.namespace.name":          "orchestrator.namespace",
+	"k8s.pod.name":                "orchestrator.resource.name",
+	"k8s.pod.uid":                 "orchestrator.resource.id",
+	"http.status_code":            "http.response.status_code",
+	"http.method":                 "http.request.method",
+	"http.url":                    "url.full",
+	"http.user_agent":             "user_agent.original",
+	"process.executable.path":     "process.executable",
+	"process.executable.name":     "process.name",
+	"exception.type":              "error.type",
+	"exception.message":           "error.message",
+	"exception.stacktrace":        "error.stack_trace",
+	"trace_id":                    "trace.id",
+	"span_id":                     "span.id",
+}
+
+// ecsNumericFields are ECS fields typed as numbers; string attribute values are converted
+var ecsNumericFields = map[string]bool{
+	"source.port":               true,
+	"destination.port":          true,
+	"client.port":               true,
+	"server.port":               true,
+	"http.response.status_code": true,
+	"process.pid":               true,
+	"process.parent.pid":        true,
+	"event.severity":            true,
+	"event.risk_score":          true,
+}
+
+// ecsArrayFields are ECS fields typed as arrays; comma-separated values are split
+var ecsArrayFields = map[string]bool{
+	"event.category": true,
+	"event.type":     true,
+	"user.roles":     true,
+	"tags":           true,
+}
+
+// Validate checks the unmapped attribute handling
+func (cfg *ECSConfig) Validate() error {
+	switch cfg.UnmappedAttributes {
+	case "", ecsUnmappedNested, ecsUnmappedLabels, ecsUnmappedDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid ecs unmapped_attributes %q: must be %q, %q or %q",
+			cfg.UnmappedAttributes, ecsUnmappedNested, ecsUnmappedLabels, ecsUnmappedDrop)
+	}
+}
+
+// ecsEncoder translates flat security events to nested ECS documents
+type ecsEncoder struct {
+	config   *ECSConfig
+	mappings map[string]string
+}
+
+// newECSEncoder creates an ECS encoder from cfg
+func newECSEncoder(cfg *ECSConfig) (*ecsEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	mappings := make(map[string]string, len(defaultECSMappings)+len(cfg.Mappings))
+	for attribute, field := range defaultECSMappings {
+		mappings[attribute] = field
+	}
+	for attribute, field := range cfg.Mappings {
+		mappings[attribute] = field
+	}
+	return &ecsEncoder{config: cfg, mappings: mappings}, nil
+}
+
+// encode builds the ECS document for one record
+func (enc *ecsEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	doc := map[string]interface{}{
+		"@timestamp": eventTime(logRecord).UTC().Format(time.RFC3339Nano),
+	}
+	setNestedField(doc, "ecs.version", defaultString(enc.config.Version, "8.11.0"))
+
+	// Keys are processed in sorted order so conflicting attributes resolve deterministically
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		if key != "timestamp" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := event[key]
+		field, mapped := enc.mappings[key]
+		if mapped && field == "" {
+			continue
+		}
+		if !mapped {
+			field = key
+			if !isECSField(key) {
+				switch enc.config.UnmappedAttributes {
+				case ecsUnmappedDrop:
+					continue
+				case ecsUnmappedLabels:
+					setNestedField(doc, "labels."+strings.ReplaceAll(key, ".", "_"), value)
+					continue
+				}
+			}
+		}
+		enc.setField(doc, field, value)
+	}
+
+	if _, ok := nestedField(doc, "event.kind"); !ok {
+		setNestedField(doc, "event.kind", defaultString(enc.config.EventKind, "event"))
+	}
+	number := severityNumber(logRecord)
+	if number != plog.SeverityNumberUnspecified {
+		if _, ok := nestedField(doc, "event.severity"); !ok {
+			setNestedField(doc, "event.severity", int(number))
+		}
+		if _, ok := nestedField(doc, "log.level"); !ok {
+			level := strings.ToLower(logRecord.SeverityText())
+			if level == "" {
+				level = strings.ToLower(severityFamily(number))
+			}
+			setNestedField(doc, "log.level", level)
+		}
+	}
+	if _, ok := nestedField(doc, "orchestrator.resource.name"); ok {
+		if _, ok := nestedField(doc, "orchestrator.type"); !ok {
+			setNestedField(doc, "orchestrator.type", "kubernetes")
+			setNestedField(doc, "orchestrator.resource.type", "pod")
+		}
+	}
+
+	return doc, nil
+}
+
+// setField stores value at the ECS field, converting it to the field's ECS type. Addresses that
+// are IP literals also populate the sibling .ip field. Values that collide with an existing
+// object or leaf are kept under labels instead of being lost.
+func (enc *ecsEncoder) setField(doc map[string]interface{}, field string, value interface{}) {
+	switch {
+	case ecsNumericFields[field]:
+		if s, ok := value.(string); ok {
+			if number, err := strconv.ParseInt(s, 10, 64); err == nil {
+				value = number
+			}
+		}
+	case ecsArrayFields[field]:
+		if s, ok := value.(string); ok {
+			parts := strings.Split(s, ",")
+			values := make([]interface{}, 0, len(parts))
+			for _, part := range parts {
+				if part = strings.TrimSpace(part); part != "" {
+					values = append(values, part)
+				}
+			}
+			value = values
+		}
+	}
+
+	// The first value stored at a field keeps it; a repeated identical value is not a collision
+	if existing, exists := nestedField(doc, field); exists {
+		if _, isObject := existing.(map[string]interface{}); isObject || !reflect.DeepEqual(existing, value) {
+			setNestedField(doc, "labels."+strings.ReplaceAll(field, ".", "_"), value)
+		}
+		return
+	}
+	if !setNestedField(doc, field, value) {
+		setNestedField(doc, "labels."+strings.ReplaceAll(field, ".", "_"), value)
+		return
+	}
+
+	if strings.HasSuffix(field, ".address") {
+		ipField := strings.TrimSuffix(field, ".address") + ".ip"
+		if _, exists := nestedField(doc, ipField); !exists {
+			if s, ok := value.(string); ok && net.ParseIP(s) != nil {
+				setNestedField(doc, ipField, s)
+			}
+		}
+	}
+}
+
+// isECSField reports whether key starts with a top-level ECS field set, in which case it is
+// kept at its path even when unmapped attributes go to labels
+func isECSField(key string) bool {
+	root, _, _ := strings.Cut(key, ".")
+	switch root {
+	case "agent", "client", "cloud", "container", "destination", "dns", "ecs", "error", "event",
+		"file", "group", "host", "http", "labels", "log", "network", "observer", "orchestrator",
+		"organization", "process", "rule", "server", "service", "source", "threat", "tls", "trace",
+		"span", "url", "user", "user_agent", "vulnerability":
+		return true
+	}
+	return false
+}
+
+// setNestedField stores value at the dotted path in doc, creating intermediate objects. It
+// returns false when the path collides with an existing value of a different shape.
+func setNestedField(doc map[string]interface{}, path string, value interface{}) bool {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists {
+			child := make(map[string]interface{})
+			current[part] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		current = child
+	}
+
+	last := parts[len(parts)-1]
+	if existing, exists := current[last]; exists {
+		if _, isObject := existing.(map[string]interface{}); isObject {
+			return false
+		}
+	}
+	current[last] = value
+	return true
+}
+
+// nestedField returns the value at the dotted path in doc
+func nestedField(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
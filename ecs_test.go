@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestECSEncoderTranslatesSemanticConventions(t *testing.T) {
+	enc, err := newECSEncoder(&ECSConfig{})
+	if err != nil {
+		t.Fatalf("newECSEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	record.SetSeverityNumber(plog.SeverityNumberError)
+	record.SetSeverityText("ERROR")
+
+	event := map[string]interface{}{
+		"timestamp":          "2023-11-14T22:13:20Z",
+		"client.address":     "10.0.0.7",
+		"client.port":        "51234",
+		"user.id":            "u-42",
+		"user.name":          "alice",
+		"host.name":          "node-1",
+		"k8s.pod.name":       "auth-7d9f",
+		"k8s.namespace.name": "security",
+		"event.category":     "authentication,iam",
+		"event.outcome":      "failure",
+		"trace_id":           "0102030405060708090a0b0c0d0e0f10",
+	}
+
+	doc, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"@timestamp":                 "2023-11-14T22:13:20Z",
+		"source.address":             "10.0.0.7",
+		"source.ip":                  "10.0.0.7",
+		"source.port":                int64(51234),
+		"user.id":                    "u-42",
+		"user.name":                  "alice",
+		"host.name":                  "node-1",
+		"orchestrator.resource.name": "auth-7d9f",
+		"orchestrator.namespace":     "security",
+		"orchestrator.type":          "kubernetes",
+		"event.outcome":              "failure",
+		"event.kind":                 "event",
+		"event.severity":             17,
+		"log.level":                  "error",
+		"trace.id":                   "0102030405060708090a0b0c0d0e0f10",
+	}
+	for path, want := range expect {
+		got, ok := nestedField(doc, path)
+		if !ok || got != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", path, got, got, want, want)
+		}
+	}
+
+	if got, _ := nestedField(doc, "event.category"); !reflect.DeepEqual(got, []interface{}{"authentication", "iam"}) {
+		t.Errorf("event.category = %v, want [authentication iam]", got)
+	}
+	if _, ok := doc["user.name"]; ok {
+		t.Error("ECS documents should use nested objects, not dotted root keys")
+	}
+}
+
+func TestECSEncoderOverridesAndUnmappedAttributes(t *testing.T) {
+	event := map[string]interface{}{
+		"tenant.id":     "acme",
+		"session.token": "secret",
+		"user.id":       "u-42",
+	}
+
+	tests := []struct {
+		name   string
+		config ECSConfig
+		expect map[string]interface{}
+		absent []string
+	}{
+		{
+			name:   "nested by default",
+			config: ECSConfig{},
+			expect: map[string]interface{}{"tenant.id": "acme", "user.id": "u-42"},
+		},
+		{
+			name:   "labels",
+			config: ECSConfig{UnmappedAttributes: ecsUnmappedLabels},
+			expect: map[string]interface{}{"labels.tenant_id": "acme", "user.id": "u-42"},
+			absent: []string{"tenant.id"},
+		},
+		{
+			name:   "drop",
+			config: ECSConfig{UnmappedAttributes: ecsUnmappedDrop},
+			expect: map[string]interface{}{"user.id": "u-42"},
+			absent: []string{"tenant.id", "labels"},
+		},
+		{
+			name: "configured mappings",
+			config: ECSConfig{
+				EventKind: "alert",
+				Mappings:  map[string]string{"tenant.id": "organization.id", "session.token": ""},
+			},
+			expect: map[string]interface{}{"organization.id": "acme", "event.kind": "alert"},
+			absent: []string{"tenant.id", "session.token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := newECSEncoder(&tt.config)
+			if err != nil {
+				t.Fatalf("newECSEncoder() returned error: %v", err)
+			}
+			doc, err := enc.encode(event, plog.NewLogRecord(), pcommon.NewResource())
+			if err != nil {
+				t.Fatalf("encode() returned error: %v", err)
+			}
+			for path, want := range tt.expect {
+				if got, _ := nestedField(doc, path); got != want {
+					t.Errorf("%s = %v, want %v", path, got, want)
+				}
+			}
+			for _, path := range tt.absent {
+				if _, ok := nestedField(doc, path); ok {
+					t.Errorf("Expected %s to be absent", path)
+				}
+			}
+		})
+	}
+}
+
+func TestSetNestedFieldConflicts(t *testing.T) {
+	doc := map[string]interface{}{}
+	if !setNestedField(doc, "service.name", "auth") {
+		t.Fatal("Expected first value to be stored")
+	}
+	if setNestedField(doc, "service", "leaf") {
+		t.Error("Expected a leaf replacing an object to be rejected")
+	}
+	if setNestedField(doc, "service.name.full", "nested") {
+		t.Error("Expected an object below a leaf to be rejected")
+	}
+	if got, _ := nestedField(doc, "service.name"); got != "auth" {
+		t.Errorf("service.name = %v, want auth", got)
+	}
+}
+
+func TestECSEncoderMovesLeafCollisionsToLabels(t *testing.T) {
+	enc, err := newECSEncoder(&ECSConfig{})
+	if err != nil {
+		t.Fatalf("newECSEncoder() returned error: %v", err)
+	}
+
+	// enduser.id and user.id both map to user.id; enduser.id sorts first and keeps the field
+	event := map[string]interface{}{
+		"enduser.id": "svc-account",
+		"user.id":    "u-42",
+	}
+	doc, err := enc.encode(event, plog.NewLogRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	if got, _ := nestedField(doc, "user.id"); got != "svc-account" {
+		t.Errorf("user.id = %v, want svc-account", got)
+	}
+	if got, _ := nestedField(doc, "labels.user_id"); got != "u-42" {
+		t.Errorf("labels.user_id = %v, want u-42", got)
+	}
+}
+
+func TestECSConfigValidate(t *testing.T) {
+	if err := (&ECSConfig{UnmappedAttributes: "keep"}).Validate(); err == nil {
+		t.Error("Expected invalid unmapped_attributes to be rejected")
+	}
+}
@@ -0,0 +1,246 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+const (
+	// elasticsearchBulkPath is the bulk API, relative to the endpoint
+	elasticsearchBulkPath = "/_bulk"
+
+	// elasticsearchFilterPath limits the bulk response to what per-item handling needs
+	elasticsearchFilterPath = "errors,items.*.status,items.*.error"
+
+	// opTypeIndex indexes documents, replacing documents with the same _id
+	opTypeIndex = "index"
+
+	// opTypeCreate only creates documents, as data streams require
+	opTypeCreate = "create"
+)
+
+// ElasticsearchConfig configures the Elasticsearch and OpenSearch bulk transport. The endpoint is
+// the cluster URL, for example https://elasticsearch.example.com:9200; credentials are sent with
+// the headers setting.
+type ElasticsearchConfig struct {
+	// Index is the index, alias or data stream of each document. "%{attribute}" placeholders are
+	// replaced by event attributes and "%{+yyyy.MM.dd}" by the event date. Elasticsearch date math
+	// such as "<security-{now/d}>" is passed on unchanged.
+	Index string `mapstructure:"index"`
+
+	// Pipeline is the ingest pipeline documents are processed by
+	Pipeline string `mapstructure:"pipeline"`
+
+	// OpType is the bulk action: "index" (default) or "create", which data streams require
+	OpType string `mapstructure:"op_type"`
+
+	// DocumentID sets the _id of each document, so a retried event does not create a duplicate
+	DocumentID FieldSource `mapstructure:"document_id"`
+}
+
+// Validate checks the index template and operation type
+func (cfg *ElasticsearchConfig) Validate() error {
+	if cfg.Index == "" {
+		return errors.New("elasticsearch index is required")
+	}
+	if _, err := parseTemplate(cfg.Index); err != nil {
+		return fmt.Errorf("invalid elasticsearch index: %w", err)
+	}
+	if cfg.OpType != opTypeIndex && cfg.OpType != opTypeCreate {
+		return fmt.Errorf("invalid elasticsearch op_type %q: must be %q or %q", cfg.OpType, opTypeIndex, opTypeCreate)
+	}
+	return nil
+}
+
+// createDefaultElasticsearchConfig creates the default daily index and operation type
+func createDefaultElasticsearchConfig() ElasticsearchConfig {
+	return ElasticsearchConfig{
+		Index:  "security-events-%{+yyyy.MM.dd}",
+		OpType: opTypeIndex,
+	}
+}
+
+// elasticsearchBulkResponse is the filtered bulk API response
+type elasticsearchBulkResponse struct {
+	Errors bool                                     `json:"errors"`
+	Items  []map[string]elasticsearchBulkItemResult `json:"items"`
+}
+
+// elasticsearchBulkItemResult is the outcome of one bulk action
+type elasticsearchBulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// elasticsearchTransport sends batches as NDJSON bulk requests and retries only the documents
+// the cluster failed to index with a retryable status, such as 429 when its write queue is full
+type elasticsearchTransport struct {
+	exp    *securityEventExporter
+	config *ElasticsearchConfig
+	target string
+	index  *fieldTemplate
+}
+
+// newElasticsearchTransport creates the bulk transport of e
+func newElasticsearchTransport(e *securityEventExporter) (*elasticsearchTransport, error) {
+	cfg := &e.config.Elasticsearch
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	index, err := parseTemplate(cfg.Index)
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.JoinPath(e.config.Endpoint, elasticsearchBulkPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	return &elasticsearchTransport{
+		exp:    e,
+		config: cfg,
+		target: target + "?filter_path=" + url.QueryEscape(elasticsearchFilterPath),
+		index:  index,
+	}, nil
+}
+
+// start has nothing to open; bulk requests use the exporter's HTTP client
+func (t *elasticsearchTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send posts the batch as one bulk request and reports the documents that failed
+func (t *elasticsearchTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i, event := range events {
+		if err := encoder.Encode(t.action(sources[i])); err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(fmt.Errorf("failed to marshal bulk action: %w", err))
+		}
+		document, err := t.document(event, sources[i])
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(err)
+		}
+		if err := encoder.Encode(document); err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(fmt.Errorf("failed to marshal bulk document: %w", err))
+		}
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-ndjson")
+	respBody, err := t.exp.postBatch(ctx, t.target, body.Bytes(), header, len(events))
+	if err != nil || t.exp.config.isDryRun() {
+		return err
+	}
+
+	var resp elasticsearchBulkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	if len(resp.Items) != len(events) {
+		return fmt.Errorf("bulk response has %d items for %d documents", len(resp.Items), len(events))
+	}
+	return t.itemErrors(resp.Items)
+}
+
+// itemErrors classifies the failed bulk items. Documents rejected with 429 or a server error are
+// retried; documents rejected for any other reason, such as a mapping conflict, are dropped. A
+// version conflict of a create action means the document was already indexed.
+func (t *elasticsearchTransport) itemErrors(items []map[string]elasticsearchBulkItemResult) error {
+	var retry, dropped []int
+	var lastErr error
+	for i, item := range items {
+		for _, result := range item {
+			if result.Status < 300 || (result.Status == http.StatusConflict && t.config.OpType == opTypeCreate) {
+				continue
+			}
+			errorType, reason := "", ""
+			if result.Error != nil {
+				errorType, reason = result.Error.Type, result.Error.Reason
+			}
+			t.exp.logger.Warn("Elasticsearch rejected document",
+				zap.Int("item", i),
+				zap.Int("status", result.Status),
+				zap.String("error_type", errorType),
+				zap.String("reason", reason))
+			lastErr = fmt.Errorf("status %d %s: %s", result.Status, errorType, reason)
+			if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+				retry = append(retry, i)
+			} else {
+				dropped = append(dropped, i)
+			}
+		}
+	}
+
+	switch {
+	case len(retry) > 0:
+		return &partialSendError{err: fmt.Errorf("bulk request failed for %d documents, last error %w", len(retry)+len(dropped), lastErr), failed: retry, dropped: dropped}
+	case len(dropped) > 0:
+		return newPartialSendError(consumererror.NewPermanent(fmt.Errorf("bulk request rejected %d documents, last error %w", len(dropped), lastErr)), dropped)
+	default:
+		return nil
+	}
+}
+
+// action returns the bulk action line of the document converted from source
+func (t *elasticsearchTransport) action(source eventSource) map[string]interface{} {
+	index := t.index.render(source.attributes, source.time)
+	// Index names must be lower case; date math is left as is since {now/M} and {now/m} differ
+	if !strings.HasPrefix(index, "<") {
+		index = strings.ToLower(index)
+	}
+	metadata := map[string]interface{}{"_index": index}
+	if t.config.Pipeline != "" {
+		metadata["pipeline"] = t.config.Pipeline
+	}
+	if id := t.config.DocumentID.resolve(source.attributes); id != "" {
+		metadata["_id"] = id
+	}
+	return map[string]interface{}{t.config.OpType: metadata}
+}
+
+// document returns the indexed document. Line-oriented encodings are indexed as the message
+// field. Documents get an @timestamp from the event time when they have none, as data streams
+// require it.
+func (t *elasticsearchTransport) document(event map[string]interface{}, source eventSource) (map[string]interface{}, error) {
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, err
+		}
+		event = map[string]interface{}{"message": line}
+	}
+	if _, ok := event["@timestamp"]; !ok {
+		document := make(map[string]interface{}, len(event)+1)
+		for key, value := range event {
+			document[key] = value
+		}
+		document["@timestamp"] = source.time.UTC().Format(time.RFC3339Nano)
+		event = document
+	}
+	return event, nil
+}
+
+// shutdown has nothing to close
+func (t *elasticsearchTransport) shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,219 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// elasticsearchStub is a minimal bulk API that records the actions and documents it receives and
+// answers each item with the status returned by itemStatus for the document's user.name
+type elasticsearchStub struct {
+	itemStatus map[string]int
+
+	mu          sync.Mutex
+	requests    int
+	contentType string
+	filterPath  string
+	actions     []map[string]map[string]interface{}
+	documents   []map[string]interface{}
+}
+
+func (s *elasticsearchStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != elasticsearchBulkPath {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests++
+	s.contentType = r.Header.Get("Content-Type")
+	s.filterPath = r.URL.Query().Get("filter_path")
+
+	var items []map[string]interface{}
+	hasErrors := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &document); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		status := http.StatusCreated
+		if override, ok := s.itemStatus[stringField(document, "user.name")]; ok {
+			status = override
+		}
+		result := map[string]interface{}{"status": status}
+		if status >= 300 {
+			hasErrors = true
+			result["error"] = map[string]interface{}{"type": "es_rejected_execution_exception", "reason": "rejected"}
+		} else {
+			s.actions = append(s.actions, action)
+			s.documents = append(s.documents, document)
+		}
+		for opType := range action {
+			items = append(items, map[string]interface{}{opType: result})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": hasErrors, "items": items})
+}
+
+// newTestElasticsearchExporter starts an exporter using the bulk transport against a new stub
+func newTestElasticsearchExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, *elasticsearchStub) {
+	t.Helper()
+	stub := &elasticsearchStub{}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	exp := newTestTransportExporter(t, transportElasticsearch, server.URL, configure)
+	return exp, stub
+}
+
+func TestElasticsearchBulkRequest(t *testing.T) {
+	exp, stub := newTestElasticsearchExporter(t, func(cfg *Config) {
+		cfg.Elasticsearch.Index = "Security-%{service.name}-%{+yyyy.MM.dd}"
+		cfg.Elasticsearch.Pipeline = "security-enrich"
+		cfg.Elasticsearch.OpType = opTypeCreate
+		cfg.Elasticsearch.DocumentID = FieldSource{Attributes: []string{"user.name"}}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.requests != 1 || len(stub.documents) != 2 {
+		t.Fatalf("Expected 2 documents in 1 request, got %d documents in %d requests", len(stub.documents), stub.requests)
+	}
+	if stub.contentType != "application/x-ndjson" || stub.filterPath != elasticsearchFilterPath {
+		t.Errorf("Content-Type = %q, filter_path = %q", stub.contentType, stub.filterPath)
+	}
+
+	action, ok := stub.actions[0][opTypeCreate]
+	if !ok {
+		t.Fatalf("Expected a create action, got %v", stub.actions[0])
+	}
+	if action["_index"] != "security-auth-2023.11.14" || action["pipeline"] != "security-enrich" || action["_id"] != "alice" {
+		t.Errorf("Unexpected action metadata: %v", action)
+	}
+	if got := stub.documents[0]["@timestamp"]; got != "2023-11-14T22:13:20Z" {
+		t.Errorf("@timestamp = %v, want 2023-11-14T22:13:20Z", got)
+	}
+}
+
+func TestElasticsearchRetriesOnlyFailedItems(t *testing.T) {
+	exp, stub := newTestElasticsearchExporter(t, nil)
+	stub.itemStatus = map[string]int{"bob": http.StatusTooManyRequests, "mallory": http.StatusBadRequest}
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "mallory", "carol"))
+	if err == nil || consumererror.IsPermanent(err) {
+		t.Fatalf("Expected retryable error, got %v", err)
+	}
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected consumererror.Logs, got %T", err)
+	}
+	retry := logsErr.Data()
+	if retry.LogRecordCount() != 1 {
+		t.Fatalf("Expected 1 record to retry, got %d", retry.LogRecordCount())
+	}
+	if user, _ := retry.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("user.name"); user.Str() != "bob" {
+		t.Errorf("Expected bob to be retried, got %q", user.Str())
+	}
+	if exp.metrics.eventsFailed.Load() != 2 || exp.metrics.eventsExported.Load() != 2 {
+		t.Errorf("eventsFailed = %d, eventsExported = %d, want 2 and 2", exp.metrics.eventsFailed.Load(), exp.metrics.eventsExported.Load())
+	}
+
+	stub.itemStatus = nil
+	if err := exp.ConsumeLogs(context.Background(), retry); err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if len(stub.documents) != 3 {
+		t.Errorf("Expected 3 indexed documents after the retry, got %d", len(stub.documents))
+	}
+}
+
+func TestElasticsearchRejectedItemsArePermanent(t *testing.T) {
+	exp, stub := newTestElasticsearchExporter(t, nil)
+	stub.itemStatus = map[string]int{"mallory": http.StatusBadRequest}
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "mallory"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for a rejected document, got %v", err)
+	}
+}
+
+func TestElasticsearchCreateConflictIsDelivered(t *testing.T) {
+	exp, stub := newTestElasticsearchExporter(t, func(cfg *Config) {
+		cfg.Elasticsearch.OpType = opTypeCreate
+	})
+	stub.itemStatus = map[string]int{"alice": http.StatusConflict}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Errorf("Expected version conflicts of create actions to count as delivered, got %v", err)
+	}
+}
+
+func TestElasticsearchLineEncoding(t *testing.T) {
+	exp, stub := newTestElasticsearchExporter(t, func(cfg *Config) {
+		cfg.Encoding = encodingCEF
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if message, _ := stub.documents[0]["message"].(string); !strings.HasPrefix(message, "CEF:0|") {
+		t.Errorf("Expected a CEF message field, got %v", stub.documents[0])
+	}
+}
+
+func TestElasticsearchConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *ElasticsearchConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*ElasticsearchConfig) {}},
+		{name: "missing index", modify: func(cfg *ElasticsearchConfig) {
+			cfg.Index = ""
+		}, errorMsg: "elasticsearch index is required"},
+		{name: "invalid template", modify: func(cfg *ElasticsearchConfig) {
+			cfg.Index = "security-%{service.name"
+		}, errorMsg: "unterminated placeholder"},
+		{name: "invalid op type", modify: func(cfg *ElasticsearchConfig) {
+			cfg.OpType = "update"
+		}, errorMsg: "invalid elasticsearch op_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultElasticsearchConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// encodingJSON sends the flat security event produced by convertLogToSecurityEvent
+	encodingJSON = "json"
+
+	// encodingOCSF maps security events to Open Cybersecurity Schema Framework classes
+	encodingOCSF = "ocsf"
+
+	// encodingECS translates security events to nested Elastic Common Schema documents
+	encodingECS = "ecs"
+
+	// encodingCEF renders security events as ArcSight Common Event Format lines
+	encodingCEF = "cef"
+
+	// encodingLEEF renders security events as IBM QRadar LEEF 2.0 lines
+	encodingLEEF = "leef"
+
+	// encodingUDM maps security events to Google Security Operations Unified Data Model events
+	encodingUDM = "udm"
+
+	// encodingASFF maps security events to AWS Security Finding Format findings
+	encodingASFF = "asff"
+)
+
+// supportedEncodings lists the accepted values of the encoding setting
+var supportedEncodings = []string{encodingJSON, encodingOCSF, encodingECS, encodingCEF, encodingLEEF, encodingUDM, encodingASFF}
+
+// eventEncoder turns the flat security event converted from a log record into the configured
+// output representation. The record and its resource are passed along for fields that are not
+// part of the flat event, such as the severity.
+type eventEncoder interface {
+	encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error)
+}
+
+// lineEncoder is implemented by encoders of line-oriented formats. The encoded events stay
+// structured through the pipeline and are rendered to one line each when the batch is serialized.
+type lineEncoder interface {
+	eventEncoder
+	renderLine(event map[string]interface{}) (string, error)
+}
+
+// FieldSource resolves an output field from the first non-empty attribute in Attributes,
+// falling back to the static Value
+type FieldSource struct {
+	// Attributes are the event attributes tried in order
+	Attributes []string `mapstructure:"attributes"`
+
+	// Value is used when none of the attributes is set
+	Value string `mapstructure:"value"`
+}
+
+// resolve returns the field value for event
+func (src FieldSource) resolve(event map[string]interface{}) string {
+	if value := firstField(event, src.Attributes...); value != "" {
+		return value
+	}
+	return src.Value
+}
+
+// newEventEncoder creates the encoder selected by cfg.Encoding. It returns nil for the default
+// JSON encoding, which sends the flat event unchanged.
+func newEventEncoder(cfg *Config) (eventEncoder, error) {
+	switch cfg.Encoding {
+	case "", encodingJSON:
+		return nil, nil
+	case encodingOCSF:
+		return newOCSFEncoder(&cfg.OCSF)
+	case encodingECS:
+		return newECSEncoder(&cfg.ECS)
+	case encodingCEF:
+		return newCEFEncoder(&cfg.CEF)
+	case encodingLEEF:
+		return newLEEFEncoder(&cfg.LEEF)
+	case encodingUDM:
+		return newUDMEncoder(&cfg.UDM)
+	case encodingASFF:
+		return newASFFEncoder(&cfg.ASFF)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", cfg.Encoding)
+	}
+}
+
+// validateEncoding checks the encoding name and the settings of the selected encoder
+func (cfg *Config) validateEncoding() error {
+	switch cfg.Encoding {
+	case "", encodingJSON:
+		return nil
+	case encodingOCSF:
+		return cfg.OCSF.Validate()
+	case encodingECS:
+		return cfg.ECS.Validate()
+	case encodingCEF:
+		return cfg.CEF.Validate()
+	case encodingLEEF:
+		return cfg.LEEF.Validate()
+	case encodingUDM:
+		return cfg.UDM.Validate()
+	case encodingASFF:
+		return cfg.ASFF.Validate()
+	default:
+		return fmt.Errorf("invalid encoding %q: must be one of %s", cfg.Encoding, strings.Join(supportedEncodings, ", "))
+	}
+}
+
+// eventAttributes returns the event fields that came from attributes, leaving out the fields the
+// converter adds itself
+func eventAttributes(event map[string]interface{}) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(event))
+	for key, value := range event {
+		switch key {
+		case "timestamp", "trace_id", "span_id":
+			continue
+		}
+		attributes[key] = value
+	}
+	return attributes
+}
+
+// eventTime returns the record timestamp, falling back to the observed timestamp
+func eventTime(logRecord plog.LogRecord) time.Time {
+	if ts := logRecord.Timestamp(); ts != 0 {
+		return ts.AsTime()
+	}
+	return logRecord.ObservedTimestamp().AsTime()
+}
+
+// stringField returns event[key] as a string, or an empty string when it is missing
+func stringField(event map[string]interface{}, key string) string {
+	value, ok := event[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// firstField returns the first non-empty value among keys
+func firstField(event map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value := stringField(event, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
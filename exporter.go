@@ -4,18 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configoptional"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
+
+	"github.com/henrikrexed/SecurityEventExporter/internal/metadata"
 )
 
 // securityEventExporter is the implementation of the security event exporter
@@ -24,36 +34,110 @@ type securityEventExporter struct {
 	logger  *zap.Logger
 	client  *http.Client
 	metrics *exporterMetrics
+
+	// dryRun receives the would-be requests when the exporter runs in dry-run mode
+	dryRun *dryRunWriter
+
+	// encoder renders converted events in the configured encoding; nil sends them unchanged
+	encoder eventEncoder
+
+	// transport delivers batches when a transport other than HTTP is configured
+	transport eventTransport
+
+	// fileTee writes a copy of the delivered events when a file path is configured alongside
+	// another transport
+	fileTee *fileSink
+
+	// archive keeps a copy of the delivered events when an archive is configured
+	archive *archiveSink
+
+	// conversion converts the chunks of a batch in parallel; nil converts them serially
+	conversion *conversionPool
+
+	// debugSamples counts the records considered for sampled debug logging
+	debugSamples atomic.Uint64
+
+	// redactor redacts sensitive fields of the converted events; nil when no rules are configured
+	redactor *redactor
 }
 
-// exporterMetrics contains the metrics for the security event exporter
+// exporterMetrics contains the metrics for the security event exporter. The exporter helper
+// queue calls ConsumeLogs from several consumers at once, so every metric is safe for concurrent
+// use.
 type exporterMetrics struct {
-	logsReceived       int64
-	eventsExported     int64
-	eventsFailed       int64
-	conversionErrors   int64
-	httpErrors         int64
-	httpRequests       int64
-	httpDurations      []time.Duration
-	attributeConflicts int64
+	logsReceived       atomic.Int64
+	eventsExported     atomic.Int64
+	eventsFailed       atomic.Int64
+	conversionErrors   atomic.Int64
+	httpErrors         atomic.Int64
+	httpRequests       atomic.Int64
+	httpDurations      durationSummary
+	attributeConflicts atomic.Int64
+
+	// archiveEventsDropped counts the events of archive files that could not be kept for upload
+	archiveEventsDropped atomic.Int64
+}
+
+// durationSummary accumulates request durations in constant space
+type durationSummary struct {
+	mu    sync.Mutex
+	count int
+	total time.Duration
+}
+
+// record adds the duration of one request
+func (s *durationSummary) record(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.total += duration
+}
+
+// summary returns the number of recorded requests and their total duration
+func (s *durationSummary) summary() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count, s.total
 }
 
 // NewFactory creates a new factory for the security event exporter
 func NewFactory() exporter.Factory {
 	return exporter.NewFactory(
-		component.MustNewType("securityevent"),
+		metadata.Type,
 		createDefaultConfig,
-		exporter.WithLogs(createLogsExporter, component.StabilityLevelStable),
+		exporter.WithLogs(createLogsExporter, metadata.LogsStability),
 	)
 }
 
 // createDefaultConfig creates the default configuration for the security event exporter
 func createDefaultConfig() component.Config {
 	return &Config{
-		Endpoint:      "http://localhost:8080/security-events",
-		Timeout:       30 * time.Second,
-		RetrySettings: createDefaultRetrySettings(),
-		QueueSettings: createDefaultQueueSettings(),
+		Endpoint:          "http://localhost:8080/security-events",
+		Timeout:           defaultTimeout,
+		RetrySettings:     createDefaultRetrySettings(),
+		QueueSettings:     createDefaultQueueSettings(),
+		Mode:              modeLive,
+		DryRunOutput:      dryRunStdout,
+		Encoding:          encodingJSON,
+		PayloadFormat:     payloadFormatJSONArray,
+		PayloadWrapperKey: "events",
+		ConversionWorkers: 1,
+		DebugSampleRate:   1,
+		CEF:               createDefaultCEFConfig(),
+		LEEF:              createDefaultLEEFConfig(),
+		UDM:               createDefaultUDMConfig(),
+		ASFF:              createDefaultASFFConfig(),
+		Transport:         transportHTTP,
+		Syslog:            createDefaultSyslogConfig(),
+		SplunkHEC:         createDefaultSplunkHECConfig(),
+		Sentinel:          createDefaultSentinelConfig(),
+		Chronicle:         createDefaultChronicleConfig(),
+		Elasticsearch:     createDefaultElasticsearchConfig(),
+		Loki:              createDefaultLokiConfig(),
+		Kafka:             createDefaultKafkaConfig(),
+		File:              createDefaultFileConfig(),
+		CloudEvents:       createDefaultCloudEventsConfig(),
+		Archive:           configoptional.Default(createDefaultArchiveConfig()),
 		DefaultAttributes: map[string]interface{}{
 			"source": "opentelemetry-collector",
 		},
@@ -71,45 +155,87 @@ func createLogsExporter(
 	config := cfg.(*Config)
 	set.Logger.Debug("Security event exporter configuration",
 		zap.String("endpoint", config.Endpoint),
-		zap.Duration("timeout", config.Timeout),
+		zap.Duration("timeout", config.requestTimeout()),
 		zap.Int("header_count", len(config.Headers)),
 		zap.Int("default_attribute_count", len(config.DefaultAttributes)))
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
+	// Validate configuration, including the nested retry and queue settings
+	if err := xconfmap.Validate(config); err != nil {
 		set.Logger.Error("Invalid configuration for security event exporter", zap.Error(err))
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	set.Logger.Debug("Configuration validation passed")
 
+	encoder, err := newEventEncoder(config)
+	if err != nil {
+		set.Logger.Error("Failed to create security event encoder", zap.Error(err))
+		return nil, fmt.Errorf("invalid encoding configuration: %w", err)
+	}
+
+	redactor, err := newRedactor(&config.Redaction)
+	if err != nil {
+		set.Logger.Error("Failed to create security event redactor", zap.Error(err))
+		return nil, fmt.Errorf("invalid redaction configuration: %w", err)
+	}
+
 	// Create HTTP client
 	client := &http.Client{
-		Timeout: config.Timeout,
+		Timeout: config.requestTimeout(),
 	}
 
 	set.Logger.Debug("Created HTTP client",
-		zap.Duration("timeout", config.Timeout))
+		zap.Duration("timeout", config.requestTimeout()))
 
 	// Create exporter instance
 	exp := &securityEventExporter{
-		config: config,
-		logger: set.Logger,
-		client: client,
-		metrics: &exporterMetrics{
-			logsReceived:       0,
-			eventsExported:     0,
-			eventsFailed:       0,
-			conversionErrors:   0,
-			httpErrors:         0,
-			httpRequests:       0,
-			httpDurations:      make([]time.Duration, 0),
-			attributeConflicts: 0,
-		},
+		config:     config,
+		logger:     set.Logger,
+		client:     client,
+		encoder:    encoder,
+		metrics:    &exporterMetrics{},
+		conversion: newConversionPool(config.ConversionWorkers),
+		redactor:   redactor,
+	}
+
+	transport, err := newEventTransport(exp)
+	if err != nil {
+		set.Logger.Error("Failed to create security event transport", zap.Error(err))
+		return nil, fmt.Errorf("invalid transport configuration: %w", err)
+	}
+	exp.transport = transport
+
+	if config.Transport != transportFile && config.File.Path != "" {
+		if exp.fileTee, err = newFileSink(exp); err != nil {
+			set.Logger.Error("Failed to create security event file", zap.Error(err))
+			return nil, fmt.Errorf("invalid file configuration: %w", err)
+		}
+	}
+
+	if config.Archive.HasValue() {
+		if exp.archive, err = newArchiveSink(exp); err != nil {
+			set.Logger.Error("Failed to create security event archive", zap.Error(err))
+			return nil, fmt.Errorf("invalid archive configuration: %w", err)
+		}
+	}
+
+	// The exporter helper provides the retry and queue behavior configured by
+	// retry_on_failure and sending_queue; the HTTP client enforces the request timeout
+	logsExporter, err := exporterhelper.NewLogs(ctx, set, cfg, exp.ConsumeLogs,
+		exporterhelper.WithStart(exp.Start),
+		exporterhelper.WithShutdown(exp.Shutdown),
+		exporterhelper.WithCapabilities(exp.Capabilities()),
+		exporterhelper.WithTimeout(exporterhelper.TimeoutConfig{Timeout: 0}),
+		exporterhelper.WithRetry(config.RetrySettings),
+		exporterhelper.WithQueue(config.QueueSettings),
+	)
+	if err != nil {
+		set.Logger.Error("Failed to create exporter helper for security event exporter", zap.Error(err))
+		return nil, err
 	}
 
 	set.Logger.Info("Successfully created security event logs exporter")
-	return exp, nil
+	return logsExporter, nil
 }
 
 // Capabilities returns the capabilities of the exporter
@@ -121,22 +247,56 @@ func (e *securityEventExporter) Capabilities() consumer.Capabilities {
 func (e *securityEventExporter) Start(ctx context.Context, host component.Host) error {
 	e.logger.Info("Starting security event exporter",
 		zap.String("endpoint", e.config.Endpoint),
-		zap.Duration("timeout", e.config.Timeout),
+		zap.Duration("timeout", e.config.requestTimeout()),
 		zap.Int("header_count", len(e.config.Headers)),
 		zap.Int("default_attribute_count", len(e.config.DefaultAttributes)))
 
+	if e.config.isDryRun() {
+		writer, err := newDryRunWriter(e.config.DryRunOutput)
+		if err != nil {
+			e.logger.Error("Failed to open dry-run output", zap.Error(err))
+			return err
+		}
+		e.dryRun = writer
+		e.logger.Warn("Security event exporter running in dry-run mode, events will not be sent",
+			zap.String("dry_run_output", e.config.DryRunOutput))
+	}
+
+	if e.transport != nil {
+		if err := e.transport.start(ctx, host); err != nil {
+			e.logger.Error("Failed to start security event transport",
+				zap.String("transport", e.config.Transport),
+				zap.Error(err))
+			return err
+		}
+	}
+
+	if e.fileTee != nil {
+		if err := e.fileTee.start(ctx, host); err != nil {
+			e.logger.Error("Failed to open security event file", zap.Error(err))
+			return err
+		}
+	}
+
+	if e.archive != nil {
+		if err := e.archive.start(ctx, host); err != nil {
+			e.logger.Error("Failed to start security event archive", zap.Error(err))
+			return err
+		}
+	}
+
 	e.logger.Debug("Security event exporter configuration",
 		zap.Any("retry_settings", e.config.RetrySettings),
 		zap.Any("queue_settings", e.config.QueueSettings))
 
 	e.logger.Debug("Initialized telemetry metrics",
-		zap.Int64("logs_received", e.metrics.logsReceived),
-		zap.Int64("events_exported", e.metrics.eventsExported),
-		zap.Int64("events_failed", e.metrics.eventsFailed),
-		zap.Int64("conversion_errors", e.metrics.conversionErrors),
-		zap.Int64("http_requests", e.metrics.httpRequests),
-		zap.Int64("http_errors", e.metrics.httpErrors),
-		zap.Int64("attribute_conflicts", e.metrics.attributeConflicts))
+		zap.Int64("logs_received", e.metrics.logsReceived.Load()),
+		zap.Int64("events_exported", e.metrics.eventsExported.Load()),
+		zap.Int64("events_failed", e.metrics.eventsFailed.Load()),
+		zap.Int64("conversion_errors", e.metrics.conversionErrors.Load()),
+		zap.Int64("http_requests", e.metrics.httpRequests.Load()),
+		zap.Int64("http_errors", e.metrics.httpErrors.Load()),
+		zap.Int64("attribute_conflicts", e.metrics.attributeConflicts.Load()))
 
 	return nil
 }
@@ -146,26 +306,61 @@ func (e *securityEventExporter) Shutdown(ctx context.Context) error {
 	e.logger.Info("Shutting down security event exporter")
 
 	// Report final metrics
+	durationSamples, durationTotal := e.metrics.httpDurations.summary()
 	e.logger.Info("Final telemetry metrics",
-		zap.Int64("logs_received", e.metrics.logsReceived),
-		zap.Int64("events_exported", e.metrics.eventsExported),
-		zap.Int64("events_failed", e.metrics.eventsFailed),
-		zap.Int64("conversion_errors", e.metrics.conversionErrors),
-		zap.Int64("http_requests", e.metrics.httpRequests),
-		zap.Int64("http_errors", e.metrics.httpErrors),
-		zap.Int64("attribute_conflicts", e.metrics.attributeConflicts),
-		zap.Int("http_duration_samples", len(e.metrics.httpDurations)))
+		zap.Int64("logs_received", e.metrics.logsReceived.Load()),
+		zap.Int64("events_exported", e.metrics.eventsExported.Load()),
+		zap.Int64("events_failed", e.metrics.eventsFailed.Load()),
+		zap.Int64("conversion_errors", e.metrics.conversionErrors.Load()),
+		zap.Int64("http_requests", e.metrics.httpRequests.Load()),
+		zap.Int64("http_errors", e.metrics.httpErrors.Load()),
+		zap.Int64("attribute_conflicts", e.metrics.attributeConflicts.Load()),
+		zap.Int64("archive_events_dropped", e.metrics.archiveEventsDropped.Load()),
+		zap.Int("http_duration_samples", durationSamples))
+
+	if e.redactor != nil {
+		counts := e.redactor.redactionCounts()
+		fields := make([]zap.Field, 0, len(counts))
+		for _, rule := range e.redactor.rules {
+			fields = append(fields, zap.Int64(rule.name, counts[rule.name]))
+		}
+		e.logger.Info("Redacted security event fields per rule", fields...)
+	}
 
 	// Calculate and report average HTTP duration if we have samples
-	if len(e.metrics.httpDurations) > 0 {
-		totalDuration := time.Duration(0)
-		for _, duration := range e.metrics.httpDurations {
-			totalDuration += duration
-		}
-		avgDuration := totalDuration / time.Duration(len(e.metrics.httpDurations))
+	if durationSamples > 0 {
 		e.logger.Info("HTTP request performance metrics",
-			zap.Duration("average_duration", avgDuration),
-			zap.Int("sample_count", len(e.metrics.httpDurations)))
+			zap.Duration("average_duration", durationTotal/time.Duration(durationSamples)),
+			zap.Int("sample_count", durationSamples))
+	}
+
+	if e.transport != nil {
+		if err := e.transport.shutdown(ctx); err != nil {
+			e.logger.Error("Failed to shut down security event transport", zap.Error(err))
+			return err
+		}
+	}
+
+	if e.fileTee != nil {
+		if err := e.fileTee.shutdown(ctx); err != nil {
+			e.logger.Error("Failed to close security event file", zap.Error(err))
+			return err
+		}
+	}
+
+	if e.archive != nil {
+		if err := e.archive.shutdown(ctx); err != nil {
+			e.logger.Error("Failed to upload the remaining archive files", zap.Error(err))
+			return err
+		}
+	}
+
+	if e.dryRun != nil {
+		if err := e.dryRun.close(); err != nil {
+			e.logger.Error("Failed to close dry-run output", zap.Error(err))
+			return err
+		}
+		e.dryRun = nil
 	}
 
 	e.logger.Debug("Security event exporter shutdown completed")
@@ -174,84 +369,65 @@ func (e *securityEventExporter) Shutdown(ctx context.Context) error {
 
 // ConsumeLogs processes the incoming logs and converts them to security events
 func (e *securityEventExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	if ld.LogRecordCount() > 0 && e.canStream() {
+		return e.consumeStreamedLogs(ctx, ld)
+	}
+
 	totalResourceLogs := ld.ResourceLogs().Len()
-	totalLogRecords := 0
-	conversionErrors := 0
 
 	e.logger.Debug("Processing logs batch",
 		zap.Int("resource_logs_count", totalResourceLogs))
 
 	// Collect all security events to batch them
-	var securityEvents []map[string]interface{}
-
-	// Process each resource log
-	for i := 0; i < ld.ResourceLogs().Len(); i++ {
-		resourceLog := ld.ResourceLogs().At(i)
-
-		e.logger.Debug("Processing resource log",
-			zap.Int("resource_index", i),
-			zap.Int("scope_logs_count", resourceLog.ScopeLogs().Len()))
-
-		// Process each scope log
-		for j := 0; j < resourceLog.ScopeLogs().Len(); j++ {
-			scopeLog := resourceLog.ScopeLogs().At(j)
-			logRecordsCount := scopeLog.LogRecords().Len()
-			totalLogRecords += logRecordsCount
-
-			e.logger.Debug("Processing scope log",
-				zap.Int("scope_index", j),
-				zap.Int("log_records_count", logRecordsCount))
-
-			// Process each log record
-			for k := 0; k < scopeLog.LogRecords().Len(); k++ {
-				logRecord := scopeLog.LogRecords().At(k)
-
-				e.logger.Debug("Processing log record",
-					zap.Int("log_index", k),
-					zap.String("severity", logRecord.SeverityText()),
-					zap.Int64("timestamp", logRecord.Timestamp().AsTime().Unix()))
-
-				// Convert log to security event
-				securityEvent, err := e.convertLogToSecurityEvent(logRecord, resourceLog.Resource())
-				if err != nil {
-					e.logger.Error("Failed to convert log to security event",
-						zap.Error(err),
-						zap.Int("resource_index", i),
-						zap.Int("scope_index", j),
-						zap.Int("log_index", k),
-						zap.String("severity", logRecord.SeverityText()))
-					conversionErrors++
-					continue
-				}
-
-				e.logger.Debug("Successfully converted log to security event",
-					zap.Int("event_field_count", len(securityEvent)))
-
-				// Add to batch
-				securityEvents = append(securityEvents, securityEvent)
-			}
-		}
-	}
+	securityEvents, sources, totalLogRecords, conversionErrors := e.convertLogs(ld)
 
 	// Update metrics
-	e.metrics.logsReceived += int64(totalLogRecords)
-	e.metrics.conversionErrors += int64(conversionErrors)
+	e.metrics.logsReceived.Add(int64(totalLogRecords))
+	e.metrics.conversionErrors.Add(int64(conversionErrors))
 
 	// Send all security events in a single batch
 	if len(securityEvents) > 0 {
 		e.logger.Debug("Sending batch of security events",
 			zap.Int("event_count", len(securityEvents)))
 
-		if err := e.sendSecurityEventBatch(ctx, securityEvents); err != nil {
+		var err error
+		if e.transport != nil {
+			err = e.transport.send(ctx, securityEvents, sources)
+		} else {
+			err = e.sendSecurityEventBatch(ctx, securityEvents)
+		}
+		var partial *partialSendError
+		if errors.As(err, &partial) {
+			e.logger.Error("Failed to send part of security event batch",
+				zap.Error(err),
+				zap.Int("event_count", len(securityEvents)),
+				zap.Int("failed_count", len(partial.failed)+len(partial.dropped)),
+				zap.String("endpoint", e.config.Endpoint))
+			failedCount := len(partial.failed) + len(partial.dropped)
+			e.metrics.eventsFailed.Add(int64(failedCount))
+			e.metrics.eventsExported.Add(int64(len(securityEvents) - failedCount))
+			if consumererror.IsPermanent(err) {
+				e.teeEvents(ctx, securityEvents, sources, nil)
+				return err
+			}
+			// Only the records of the failed events are retried
+			e.teeEvents(ctx, securityEvents, sources, partial.failed)
+			return consumererror.NewLogs(err, failedLogs(sources, partial.failed))
+		}
+		if err != nil {
 			e.logger.Error("Failed to send security event batch",
 				zap.Error(err),
 				zap.Int("event_count", len(securityEvents)),
 				zap.String("endpoint", e.config.Endpoint))
-			e.metrics.eventsFailed += int64(len(securityEvents))
+			e.metrics.eventsFailed.Add(int64(len(securityEvents)))
+			if consumererror.IsPermanent(err) {
+				e.teeEvents(ctx, securityEvents, sources, nil)
+			}
 			return err
 		}
 
-		e.metrics.eventsExported += int64(len(securityEvents))
+		e.teeEvents(ctx, securityEvents, sources, nil)
+		e.metrics.eventsExported.Add(int64(len(securityEvents)))
 		e.logger.Debug("Successfully sent security event batch",
 			zap.Int("event_count", len(securityEvents)))
 	}
@@ -267,12 +443,73 @@ func (e *securityEventExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) e
 	return nil
 }
 
-// convertLogToSecurityEvent converts an OpenTelemetry log record to a security event
-func (e *securityEventExporter) convertLogToSecurityEvent(logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
-	e.logger.Debug("Starting log to security event conversion")
+// teeEvents writes the events to the file tee and the archive, except those at the retried
+// indexes: they are copied when their retry completes, so retries do not copy an event twice.
+// Events that failed permanently are copied too, since the copy may be the only one.
+func (e *securityEventExporter) teeEvents(ctx context.Context, events []map[string]interface{}, sources []eventSource, retried []int) {
+	if e.fileTee == nil && e.archive == nil {
+		return
+	}
+	if len(retried) > 0 {
+		skip := make(map[int]bool, len(retried))
+		for _, i := range retried {
+			skip[i] = true
+		}
+		kept, keptSources := make([]map[string]interface{}, 0, len(events)-len(retried)), make([]eventSource, 0, len(events)-len(retried))
+		for i, event := range events {
+			if !skip[i] {
+				kept, keptSources = append(kept, event), append(keptSources, sources[i])
+			}
+		}
+		events, sources = kept, keptSources
+	}
+	if e.fileTee != nil {
+		e.fileTee.add(ctx, events, sources)
+	}
+	if e.archive != nil {
+		e.archive.add(events, sources)
+	}
+}
+
+// convertLogs converts every log record in ld to a security event, preserving record order.
+// It returns the converted events, the source of each event, the number of log records seen
+// and the number of records that failed conversion.
+func (e *securityEventExporter) convertLogs(ld plog.Logs) ([]map[string]interface{}, []eventSource, int, int) {
+	chunks := e.conversionChunks(ld)
+	converted := make([]convertedChunk, len(chunks))
+	e.conversion.run(len(chunks), func(i int) {
+		converted[i] = e.convertChunk(chunks[i])
+	})
+
+	// Join the chunks in record order
+	totalLogRecords := ld.LogRecordCount()
+	conversionErrors := 0
+	eventCount := 0
+	for _, chunk := range converted {
+		eventCount += len(chunk.events)
+	}
+	securityEvents := make([]map[string]interface{}, 0, eventCount)
+	sources := make([]eventSource, 0, eventCount)
+	for _, chunk := range converted {
+		securityEvents = append(securityEvents, chunk.events...)
+		sources = append(sources, chunk.sources...)
+		conversionErrors += chunk.conversionErrors
+	}
+
+	return securityEvents, sources, totalLogRecords, conversionErrors
+}
+
+// convertLogToSecurityEvent converts an OpenTelemetry log record to a security event. logging
+// selects which debug logs are written; their arguments are not evaluated otherwise.
+func (e *securityEventExporter) convertLogToSecurityEvent(logRecord plog.LogRecord, resource pcommon.Resource, logging recordLogging) (map[string]interface{}, error) {
+	debug := logging >= recordLogDebug
+	trace := logging >= recordLogTrace
+	if debug {
+		e.logger.Debug("Starting log to security event conversion")
+	}
 
 	// Create base security event
-	securityEvent := make(map[string]interface{})
+	securityEvent := make(map[string]interface{}, len(e.config.DefaultAttributes)+resource.Attributes().Len()+logRecord.Attributes().Len()+3)
 
 	// Add default attributes (excluding source)
 	defaultAttrCount := 0
@@ -284,75 +521,98 @@ func (e *securityEventExporter) convertLogToSecurityEvent(logRecord plog.LogReco
 		securityEvent[key] = value
 		defaultAttrCount++
 	}
-	e.logger.Debug("Added default attributes",
-		zap.Int("count", defaultAttrCount))
+	if debug {
+		e.logger.Debug("Added default attributes",
+			zap.Int("count", defaultAttrCount))
+	}
 
 	// Add resource attributes (at root level)
-	resourceAttrCount := 0
 	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
-		securityEvent[key] = value.AsString()
-		resourceAttrCount++
-		e.logger.Debug("Added resource attribute",
-			zap.String("key", key),
-			zap.String("value", value.AsString()))
+		str := value.AsString()
+		securityEvent[key] = str
+		if trace {
+			e.logger.Debug("Added resource attribute",
+				zap.String("key", key),
+				zap.String("value", e.redactor.redactLogValue(key, str)))
+		}
 		return true
 	})
-	e.logger.Debug("Added resource attributes",
-		zap.Int("count", resourceAttrCount))
+	if debug {
+		e.logger.Debug("Added resource attributes",
+			zap.Int("count", resource.Attributes().Len()))
+	}
 
 	// Add log record attributes (at root level)
 	// Check for conflicts with resource attributes
-	logAttrCount := 0
 	conflictCount := 0
 	logRecord.Attributes().Range(func(key string, value pcommon.Value) bool {
+		str := value.AsString()
 		// Check if this key already exists (from resource attributes)
-		if _, exists := securityEvent[key]; exists {
-			e.logger.Warn("Attribute key conflict detected",
-				zap.String("key", key),
-				zap.String("resource_value", fmt.Sprintf("%v", securityEvent[key])),
-				zap.String("log_value", value.AsString()),
-				zap.String("message", "Log attribute will overwrite resource attribute"))
+		if existing, exists := securityEvent[key]; exists {
+			if ce := e.logger.Check(zap.WarnLevel, "Attribute key conflict detected"); ce != nil {
+				ce.Write(zap.String("key", key),
+					zap.String("resource_value", e.redactor.redactLogValue(key, fmt.Sprintf("%v", existing))),
+					zap.String("log_value", e.redactor.redactLogValue(key, str)),
+					zap.String("message", "Log attribute will overwrite resource attribute"))
+			}
 			conflictCount++
-			e.metrics.attributeConflicts++
+			e.metrics.attributeConflicts.Add(1)
+		}
+		securityEvent[key] = str
+		if trace {
+			e.logger.Debug("Added log attribute",
+				zap.String("key", key),
+				zap.String("value", e.redactor.redactLogValue(key, str)))
 		}
-		securityEvent[key] = value.AsString()
-		logAttrCount++
-		e.logger.Debug("Added log attribute",
-			zap.String("key", key),
-			zap.String("value", value.AsString()))
 		return true
 	})
-	e.logger.Debug("Added log attributes",
-		zap.Int("count", logAttrCount),
-		zap.Int("conflicts", conflictCount))
+	if debug {
+		e.logger.Debug("Added log attributes",
+			zap.Int("count", logRecord.Attributes().Len()),
+			zap.Int("conflicts", conflictCount))
+	}
 
 	// Add log record fields
-	timestamp := logRecord.Timestamp().AsTime()
-	securityEvent["timestamp"] = timestamp.Format(time.RFC3339)
-
-	e.logger.Debug("Added log record fields",
-		zap.String("timestamp", timestamp.Format(time.RFC3339)))
+	timestamp := logRecord.Timestamp().AsTime().Format(time.RFC3339)
+	securityEvent["timestamp"] = timestamp
+	if debug {
+		e.logger.Debug("Added log record fields",
+			zap.String("timestamp", timestamp))
+	}
 
 	// Add trace and span information if available
 	if traceID := logRecord.TraceID(); !traceID.IsEmpty() {
 		securityEvent["trace_id"] = traceID.String()
-		e.logger.Debug("Added trace ID", zap.String("trace_id", traceID.String()))
+		if trace {
+			e.logger.Debug("Added trace ID", zap.String("trace_id", traceID.String()))
+		}
 	}
 	if spanID := logRecord.SpanID(); !spanID.IsEmpty() {
 		securityEvent["span_id"] = spanID.String()
-		e.logger.Debug("Added span ID", zap.String("span_id", spanID.String()))
+		if trace {
+			e.logger.Debug("Added span ID", zap.String("span_id", spanID.String()))
+		}
 	}
 
 	// Log body is intentionally excluded from the security event payload
-	e.logger.Debug("Log body excluded from security event payload")
-
-	totalFields := len(securityEvent)
-	e.logger.Debug("Completed log to security event conversion",
-		zap.Int("total_fields", totalFields))
+	if debug {
+		e.logger.Debug("Completed log to security event conversion",
+			zap.Int("total_fields", len(securityEvent)))
+	}
 
 	return securityEvent, nil
 }
 
+// jsonPreview returns the first 200 characters of a request body for the debug logs, redacted.
+// Detected values straddling the cut are still found, since a longer prefix is redacted first.
+func (e *securityEventExporter) jsonPreview(jsonData []byte) string {
+	const previewSize, redactionMargin = 200, 1024
+	if e.redactor == nil {
+		return truncateString(string(jsonData[:min(len(jsonData), previewSize+1)]), previewSize)
+	}
+	return truncateString(e.redactor.redactText(string(jsonData[:min(len(jsonData), previewSize+redactionMargin)])), previewSize)
+}
+
 // truncateString truncates a string to the specified length and adds ellipsis if needed
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -361,37 +621,86 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
+// marshalBatch serializes a batch of events and returns the matching content type. Events of
+// line-oriented encodings are rendered one per line; all others are sent in the configured
+// payload format.
+func (e *securityEventExporter) marshalBatch(securityEvents []map[string]interface{}) ([]byte, string, error) {
+	lines, ok := e.encoder.(lineEncoder)
+	if !ok {
+		return e.marshalPayload(securityEvents)
+	}
+
+	var buf bytes.Buffer
+	for _, event := range securityEvents {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, "", err
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), "text/plain; charset=utf-8", nil
+}
+
 // sendSecurityEventBatch sends a batch of security events to the configured endpoint
 func (e *securityEventExporter) sendSecurityEventBatch(ctx context.Context, securityEvents []map[string]interface{}) error {
 	e.logger.Debug("Starting to send security event batch",
 		zap.String("endpoint", e.config.Endpoint),
 		zap.Int("event_count", len(securityEvents)))
 
-	// Marshal security events to JSON array
-	jsonData, err := json.Marshal(securityEvents)
+	// Marshal security events in the payload format, or to lines for line-oriented encodings
+	jsonData, contentType, err := e.marshalBatch(securityEvents)
 	if err != nil {
-		e.logger.Error("Failed to marshal security event batch to JSON",
+		e.logger.Error("Failed to marshal security event batch",
 			zap.Error(err),
 			zap.Int("event_count", len(securityEvents)))
-		e.metrics.httpErrors++
-		return fmt.Errorf("failed to marshal security event batch: %w", err)
+		e.metrics.httpErrors.Add(1)
+		return consumererror.NewPermanent(fmt.Errorf("failed to marshal security event batch: %w", err))
 	}
 
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	_, err = e.postBatch(ctx, e.config.Endpoint, jsonData, header, len(securityEvents))
+	return err
+}
+
+// maxResponseBodySize bounds how much of a successful response body is read
+const maxResponseBodySize = 10 << 20
+
+// postBatch posts a serialized batch of eventCount events to target with the configured
+// headers added to header, or records it in dry-run mode. It returns the body of a successful
+// response, which is nil in dry-run mode. Unsuccessful responses are classified for retry by
+// classifyStatusError.
+func (e *securityEventExporter) postBatch(ctx context.Context, target string, jsonData []byte, header http.Header, eventCount int) ([]byte, error) {
+	return e.postBody(ctx, target, jsonData, func() io.ReadCloser {
+		return io.NopCloser(bytes.NewReader(jsonData))
+	}, header, eventCount)
+}
+
+// postBody is postBatch with the request bodies opened by open, which each read jsonData
+func (e *securityEventExporter) postBody(ctx context.Context, target string, jsonData []byte, open func() io.ReadCloser, header http.Header, eventCount int) ([]byte, error) {
 	jsonSize := len(jsonData)
-	e.logger.Debug("Successfully marshaled security event batch to JSON",
-		zap.Int("json_size_bytes", jsonSize),
-		zap.Int("event_count", len(securityEvents)),
-		zap.String("json_preview", truncateString(string(jsonData), 200)))
+	if ce := e.logger.Check(zap.DebugLevel, "Successfully marshaled security event batch"); ce != nil {
+		ce.Write(zap.Int("json_size_bytes", jsonSize),
+			zap.Int("event_count", eventCount),
+			zap.String("json_preview", e.jsonPreview(jsonData)))
+	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", e.config.Endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", target, open())
 	if err != nil {
 		e.logger.Error("Failed to create HTTP request for batch",
 			zap.Error(err),
-			zap.String("endpoint", e.config.Endpoint),
+			zap.String("endpoint", target),
 			zap.String("method", "POST"))
-		e.metrics.httpErrors++
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		e.metrics.httpErrors.Add(1)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if jsonSize > 0 {
+		req.ContentLength = int64(jsonSize)
+		req.GetBody = func() (io.ReadCloser, error) { return open(), nil }
+	} else {
+		req.Body = http.NoBody
 	}
 
 	e.logger.Debug("Created HTTP request for batch",
@@ -399,8 +708,11 @@ func (e *securityEventExporter) sendSecurityEventBatch(ctx context.Context, secu
 		zap.String("method", req.Method))
 
 	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	headerCount := 1 // Content-Type header
+	headerCount := 0
+	for key, values := range header {
+		req.Header[key] = values
+		headerCount++
+	}
 	for key, value := range e.config.Headers {
 		req.Header.Set(key, string(value))
 		headerCount++
@@ -412,29 +724,47 @@ func (e *securityEventExporter) sendSecurityEventBatch(ctx context.Context, secu
 	e.logger.Debug("Set HTTP headers for batch",
 		zap.Int("total_headers", headerCount))
 
+	// In dry-run mode the request is recorded instead of sent
+	if e.config.isDryRun() {
+		if e.dryRun == nil {
+			return nil, errors.New("dry-run output is not open, exporter was not started")
+		}
+		_ = req.Body.Close()
+		if err := e.dryRun.write(req, jsonData, eventCount); err != nil {
+			e.logger.Error("Failed to write dry-run request for batch",
+				zap.Error(err),
+				zap.Int("event_count", eventCount))
+			return nil, err
+		}
+		e.logger.Debug("Wrote dry-run request for batch",
+			zap.Int("json_size_bytes", jsonSize),
+			zap.Int("event_count", eventCount))
+		return nil, nil
+	}
+
 	// Send request
 	e.logger.Debug("Sending HTTP request for batch",
-		zap.String("endpoint", e.config.Endpoint),
-		zap.Duration("timeout", e.config.Timeout),
-		zap.Int("event_count", len(securityEvents)))
+		zap.String("endpoint", target),
+		zap.Duration("timeout", e.config.requestTimeout()),
+		zap.Int("event_count", eventCount))
 
 	startTime := time.Now()
 	resp, err := e.client.Do(req)
 	requestDuration := time.Since(startTime)
 
 	// Update metrics
-	e.metrics.httpRequests++
-	e.metrics.httpDurations = append(e.metrics.httpDurations, requestDuration)
+	e.metrics.httpRequests.Add(1)
+	e.metrics.httpDurations.record(requestDuration)
 
 	if err != nil {
 		e.logger.Error("Failed to send HTTP request for batch",
 			zap.Error(err),
-			zap.String("endpoint", e.config.Endpoint),
+			zap.String("endpoint", target),
 			zap.Duration("request_duration", requestDuration),
-			zap.Duration("timeout", e.config.Timeout),
-			zap.Int("event_count", len(securityEvents)))
-		e.metrics.httpErrors++
-		return fmt.Errorf("failed to send HTTP request: %w", err)
+			zap.Duration("timeout", e.config.requestTimeout()),
+			zap.Int("event_count", eventCount))
+		e.metrics.httpErrors.Add(1)
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -443,37 +773,43 @@ func (e *securityEventExporter) sendSecurityEventBatch(ctx context.Context, secu
 		zap.String("status", resp.Status),
 		zap.Duration("request_duration", requestDuration),
 		zap.Int64("content_length", resp.ContentLength),
-		zap.Int("event_count", len(securityEvents)))
+		zap.Int("event_count", eventCount))
 
 	// Check response status
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		e.logger.Error("HTTP request failed with non-success status for batch",
 			zap.Int("status_code", resp.StatusCode),
 			zap.String("status", resp.Status),
-			zap.String("endpoint", e.config.Endpoint),
+			zap.String("endpoint", target),
 			zap.Duration("request_duration", requestDuration),
-			zap.Int("event_count", len(securityEvents)))
+			zap.Int("event_count", eventCount))
 
 		// Try to read response body for additional error details
 		if resp.Body != nil {
 			body, readErr := io.ReadAll(resp.Body)
 			if readErr == nil && len(body) > 0 {
 				e.logger.Error("HTTP error response body for batch",
-					zap.String("response_body", truncateString(string(body), 500)))
+					zap.String("response_body", truncateString(e.redactor.redactText(string(body)), 500)))
 			}
 		}
 
-		e.metrics.httpErrors++
-		return fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode)
+		e.metrics.httpErrors.Add(1)
+		return nil, classifyStatusError(fmt.Errorf("HTTP request failed with status: %d", resp.StatusCode), resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		e.metrics.httpErrors.Add(1)
+		return nil, fmt.Errorf("failed to read HTTP response: %w", err)
 	}
 
 	e.logger.Debug("Successfully sent security event batch",
 		zap.Int("status_code", resp.StatusCode),
 		zap.Duration("request_duration", requestDuration),
 		zap.Int("json_size_bytes", jsonSize),
-		zap.Int("event_count", len(securityEvents)))
+		zap.Int("event_count", eventCount))
 
-	return nil
+	return body, nil
 }
 
 // sendSecurityEvent sends a single security event to the configured endpoint (deprecated - use batch method)
@@ -492,9 +828,10 @@ func (e *securityEventExporter) sendSecurityEvent(ctx context.Context, securityE
 	}
 
 	jsonSize := len(jsonData)
-	e.logger.Debug("Successfully marshaled security event to JSON",
-		zap.Int("json_size_bytes", jsonSize),
-		zap.String("json_preview", truncateString(string(jsonData), 200)))
+	if ce := e.logger.Check(zap.DebugLevel, "Successfully marshaled security event to JSON"); ce != nil {
+		ce.Write(zap.Int("json_size_bytes", jsonSize),
+			zap.String("json_preview", e.jsonPreview(jsonData)))
+	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", e.config.Endpoint, bytes.NewBuffer(jsonData))
@@ -527,7 +864,7 @@ func (e *securityEventExporter) sendSecurityEvent(ctx context.Context, securityE
 	// Send request
 	e.logger.Debug("Sending HTTP request",
 		zap.String("endpoint", e.config.Endpoint),
-		zap.Duration("timeout", e.config.Timeout))
+		zap.Duration("timeout", e.config.requestTimeout()))
 
 	startTime := time.Now()
 	resp, err := e.client.Do(req)
@@ -538,7 +875,7 @@ func (e *securityEventExporter) sendSecurityEvent(ctx context.Context, securityE
 			zap.Error(err),
 			zap.String("endpoint", e.config.Endpoint),
 			zap.Duration("request_duration", requestDuration),
-			zap.Duration("timeout", e.config.Timeout))
+			zap.Duration("timeout", e.config.requestTimeout()))
 		return fmt.Errorf("failed to send HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
@@ -562,7 +899,7 @@ func (e *securityEventExporter) sendSecurityEvent(ctx context.Context, securityE
 			body, readErr := io.ReadAll(resp.Body)
 			if readErr == nil && len(body) > 0 {
 				e.logger.Error("HTTP error response body",
-					zap.String("response_body", truncateString(string(body), 500)))
+					zap.String("response_body", truncateString(e.redactor.redactText(string(body)), 500)))
 			}
 		}
 
@@ -588,3 +925,35 @@ func isSensitiveHeader(headerName string) bool {
 	}
 	return false
 }
+
+// classifyStatusError prepares a non-success HTTP response error for the retry sender: client
+// errors that cannot succeed on retry are permanent, and throttling responses honor Retry-After
+func classifyStatusError(err error, resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if delay := parseRetryAfter(resp.Header.Get("Retry-After")); delay > 0 {
+			return exporterhelper.NewThrottleRetry(err, delay)
+		}
+		return err
+	case resp.StatusCode == http.StatusRequestTimeout:
+		return err
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return consumererror.NewPermanent(err)
+	default:
+		return err
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -2,12 +2,21 @@ package exporter
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
+
+	"github.com/henrikrexed/SecurityEventExporter/mockservice"
 )
 
 func TestNewFactory(t *testing.T) {
@@ -38,16 +47,7 @@ func TestStart(t *testing.T) {
 			Endpoint: "https://example.com/events",
 			Timeout:  30 * time.Second,
 		},
-		metrics: &exporterMetrics{
-			logsReceived:       0,
-			eventsExported:     0,
-			eventsFailed:       0,
-			conversionErrors:   0,
-			httpErrors:         0,
-			httpRequests:       0,
-			httpDurations:      make([]time.Duration, 0),
-			attributeConflicts: 0,
-		},
+		metrics: &exporterMetrics{},
 	}
 
 	ctx := context.Background()
@@ -63,17 +63,8 @@ func TestStart(t *testing.T) {
 
 func TestShutdown(t *testing.T) {
 	exp := &securityEventExporter{
-		logger: zap.NewNop(),
-		metrics: &exporterMetrics{
-			logsReceived:       0,
-			eventsExported:     0,
-			eventsFailed:       0,
-			conversionErrors:   0,
-			httpErrors:         0,
-			httpRequests:       0,
-			httpDurations:      make([]time.Duration, 0),
-			attributeConflicts: 0,
-		},
+		logger:  zap.NewNop(),
+		metrics: &exporterMetrics{},
 	}
 
 	ctx := context.Background()
@@ -86,17 +77,8 @@ func TestShutdown(t *testing.T) {
 
 func TestConsumeLogs(t *testing.T) {
 	exp := &securityEventExporter{
-		logger: zap.NewNop(),
-		metrics: &exporterMetrics{
-			logsReceived:       0,
-			eventsExported:     0,
-			eventsFailed:       0,
-			conversionErrors:   0,
-			httpErrors:         0,
-			httpRequests:       0,
-			httpDurations:      make([]time.Duration, 0),
-			attributeConflicts: 0,
-		},
+		logger:  zap.NewNop(),
+		metrics: &exporterMetrics{},
 	}
 
 	ctx := context.Background()
@@ -109,6 +91,199 @@ func TestConsumeLogs(t *testing.T) {
 	}
 }
 
+func TestConsumeLogsDeliversToMockService(t *testing.T) {
+	mock := mockservice.New(mockservice.Config{
+		Schema: mockservice.Schema{RequiredFields: []string{"timestamp", "user.id"}},
+	})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	exp := &securityEventExporter{
+		logger: zap.NewNop(),
+		config: &Config{
+			Endpoint: server.URL + "/events",
+			Timeout:  5 * time.Second,
+		},
+		client:  &http.Client{Timeout: 5 * time.Second},
+		metrics: &exporterMetrics{},
+	}
+
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Attributes().PutStr("user.id", "alice")
+
+	ctx := context.Background()
+	if err := exp.ConsumeLogs(ctx, ld); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if events := mock.Events(); len(events) != 1 || events[0]["user.id"] != "alice" {
+		t.Errorf("Unexpected delivered events: %v", events)
+	}
+
+	// Injected server errors surface as export failures
+	mock.FailNext(http.StatusInternalServerError, 1)
+	if err := exp.ConsumeLogs(ctx, ld); err == nil {
+		t.Error("Expected ConsumeLogs() to fail on injected 500")
+	}
+	if exp.metrics.eventsExported.Load() != 1 || exp.metrics.eventsFailed.Load() != 1 {
+		t.Errorf("Unexpected metrics: exported=%d failed=%d", exp.metrics.eventsExported.Load(), exp.metrics.eventsFailed.Load())
+	}
+}
+
+func TestConcurrentConsumeLogs(t *testing.T) {
+	mock := mockservice.New(mockservice.Config{})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	exp := &securityEventExporter{
+		logger: zap.NewNop(),
+		config: &Config{
+			Endpoint: server.URL + "/events",
+			Timeout:  5 * time.Second,
+		},
+		client:  &http.Client{Timeout: 5 * time.Second},
+		metrics: &exporterMetrics{},
+	}
+
+	ld := plog.NewLogs()
+	resourceLogs := ld.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("host.name", "web-01")
+	records := resourceLogs.ScopeLogs().AppendEmpty().LogRecords()
+	for i := 0; i < 3; i++ {
+		records.AppendEmpty().Attributes().PutStr("user.id", "alice")
+	}
+	// One record of every batch has a conflicting host.name
+	records.At(0).Attributes().PutStr("host.name", "web-02")
+
+	// The default queue calls ConsumeLogs from several consumers at once
+	const calls = 20
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := exp.ConsumeLogs(context.Background(), ld); err != nil {
+				t.Errorf("ConsumeLogs() returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(mock.Events()); got != 3*calls {
+		t.Errorf("Delivered %d events, want %d", got, 3*calls)
+	}
+	metrics := exp.metrics
+	if got := metrics.logsReceived.Load(); got != 3*calls {
+		t.Errorf("logsReceived = %d, want %d", got, 3*calls)
+	}
+	if got := metrics.eventsExported.Load(); got != 3*calls {
+		t.Errorf("eventsExported = %d, want %d", got, 3*calls)
+	}
+	if got := metrics.httpRequests.Load(); got != calls {
+		t.Errorf("httpRequests = %d, want %d", got, calls)
+	}
+	if got := metrics.attributeConflicts.Load(); got != calls {
+		t.Errorf("attributeConflicts = %d, want %d", got, calls)
+	}
+	if count, _ := metrics.httpDurations.summary(); count != calls {
+		t.Errorf("Recorded %d request durations, want %d", count, calls)
+	}
+}
+
+func TestFactoryExporterRetriesFailedRequests(t *testing.T) {
+	mock := mockservice.New(mockservice.Config{})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL + "/events"
+	cfg.QueueSettings.Enabled = false
+	cfg.RetrySettings.InitialInterval = 10 * time.Millisecond
+	cfg.RetrySettings.MaxInterval = 10 * time.Millisecond
+	cfg.RetrySettings.MaxElapsedTime = time.Second
+
+	set := exporter.Settings{
+		ID:                component.NewID(component.MustNewType("securityevent")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+		BuildInfo:         component.NewDefaultBuildInfo(),
+	}
+	ctx := context.Background()
+	exp, err := NewFactory().CreateLogs(ctx, set, cfg)
+	if err != nil {
+		t.Fatalf("CreateLogs() returned error: %v", err)
+	}
+	if err := exp.Start(ctx, &mockHost{}); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	defer func() {
+		if err := exp.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown() returned error: %v", err)
+		}
+	}()
+
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Attributes().PutStr("user.id", "alice")
+
+	// A server error is retried until it succeeds
+	mock.FailNext(http.StatusInternalServerError, 2)
+	if err := exp.ConsumeLogs(ctx, ld); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stats := mock.Stats(); stats.Requests != 3 || stats.Accepted != 1 {
+		t.Errorf("Expected 2 failed attempts and 1 accepted request, got %+v", stats)
+	}
+
+	// A client error is permanent and is not retried
+	mock.Reset()
+	mock.FailNext(http.StatusBadRequest, 1)
+	if err := exp.ConsumeLogs(ctx, ld); !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error, got %v", err)
+	}
+	if stats := mock.Stats(); stats.Requests != 1 {
+		t.Errorf("Expected a single attempt for a client error, got %d", stats.Requests)
+	}
+}
+
+func TestCreateLogsRejectsInvalidConfig(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.RetrySettings.RandomizationFactor = 2
+
+	set := exporter.Settings{
+		ID:                component.NewID(component.MustNewType("securityevent")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+		BuildInfo:         component.NewDefaultBuildInfo(),
+	}
+	if _, err := NewFactory().CreateLogs(context.Background(), set, cfg); err == nil {
+		t.Error("Expected CreateLogs() to reject invalid retry settings")
+	}
+}
+
+func TestClassifyStatusError(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{status: http.StatusBadRequest, permanent: true},
+		{status: http.StatusUnauthorized, permanent: true},
+		{status: http.StatusRequestTimeout, permanent: false},
+		{status: http.StatusTooManyRequests, permanent: false},
+		{status: http.StatusInternalServerError, permanent: false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		err := classifyStatusError(errors.New("failed"), resp)
+		if consumererror.IsPermanent(err) != tt.permanent {
+			t.Errorf("status %d: permanent = %v, want %v", tt.status, consumererror.IsPermanent(err), tt.permanent)
+		}
+	}
+
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("parseRetryAfter(\"7\") = %v, want 7s", got)
+	}
+}
+
 // mockHost is a simple mock implementation of component.Host
 type mockHost struct{}
 
@@ -0,0 +1,452 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+const (
+	// fileFormatNDJSON writes each event as one JSON line
+	fileFormatNDJSON = "ndjson"
+
+	// fileFormatCEF writes each event as one CEF line
+	fileFormatCEF = "cef"
+
+	// fileBackupTimeFormat is the rotation time in backup file names; it sorts chronologically
+	fileBackupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// FileConfig configures the local rotating file. With the "file" transport the file is the only
+// destination; with any other transport, setting Path also writes every event to the file.
+type FileConfig struct {
+	// Path is the file events are written to. Its directory is created when missing.
+	Path string `mapstructure:"path"`
+
+	// Format is the line format: "ndjson" (default) writes each event as JSON in the configured
+	// encoding, "cef" writes CEF lines built with the cef settings whatever the encoding
+	Format string `mapstructure:"format"`
+
+	// MaxSize is the size in bytes at which the file is rotated
+	MaxSize int64 `mapstructure:"max_size"`
+
+	// RotationInterval is how long the file is written before it is rotated; zero rotates by
+	// size only. It is checked when events are written.
+	RotationInterval time.Duration `mapstructure:"rotation_interval"`
+
+	// MaxBackups is the number of rotated files kept; zero keeps them all
+	MaxBackups int `mapstructure:"max_backups"`
+
+	// Compress gzips rotated files
+	Compress bool `mapstructure:"compress"`
+
+	// Fsync flushes the file to disk after each batch, so acknowledged events survive a crash
+	Fsync bool `mapstructure:"fsync"`
+}
+
+// Validate checks the format and rotation settings. The path is only required when the
+// transport is selected, which validateTransport checks.
+func (cfg *FileConfig) Validate() error {
+	var errs []error
+	switch cfg.Format {
+	case fileFormatNDJSON, fileFormatCEF:
+	default:
+		errs = append(errs, fmt.Errorf("invalid file format %q: must be %q or %q", cfg.Format, fileFormatNDJSON, fileFormatCEF))
+	}
+	if cfg.MaxSize <= 0 {
+		errs = append(errs, fmt.Errorf("file max_size must be positive, got %d", cfg.MaxSize))
+	}
+	if cfg.RotationInterval < 0 {
+		errs = append(errs, fmt.Errorf("file rotation_interval must not be negative, got %s", cfg.RotationInterval))
+	}
+	if cfg.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("file max_backups must not be negative, got %d", cfg.MaxBackups))
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultFileConfig creates the default format and rotation settings
+func createDefaultFileConfig() FileConfig {
+	return FileConfig{
+		Format:           fileFormatNDJSON,
+		MaxSize:          100 << 20,
+		RotationInterval: 24 * time.Hour,
+		MaxBackups:       7,
+		Compress:         true,
+		Fsync:            true,
+	}
+}
+
+// fileSink appends events to a local file, rotating it by size and age. It is the "file"
+// transport and, alongside other transports, the file tee.
+type fileSink struct {
+	exp    *securityEventExporter
+	config *FileConfig
+	cef    *cefEncoder
+	now    func() time.Time
+	rename func(oldpath, newpath string) error
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// compressions tracks the rotated files being compressed in the background; housekeeping
+	// runs one compression and pruning at a time
+	compressions sync.WaitGroup
+	housekeeping sync.Mutex
+}
+
+// newFileSink creates the file sink of e
+func newFileSink(e *securityEventExporter) (*fileSink, error) {
+	cfg := &e.config.File
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Path == "" {
+		return nil, errors.New("file path is required")
+	}
+	sink := &fileSink{exp: e, config: cfg, now: time.Now, rename: os.Rename}
+	if cfg.Format == fileFormatCEF {
+		encoder, err := newCEFEncoder(&e.config.CEF)
+		if err != nil {
+			return nil, err
+		}
+		sink.cef = encoder
+	}
+	return sink, nil
+}
+
+// start opens the file, appending to an existing one
+func (s *fileSink) start(context.Context, component.Host) error {
+	if s.exp.config.isDryRun() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open()
+}
+
+// open opens the file for appending, creating it and its directory when missing
+func (s *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.config.Path), 0o750); err != nil {
+		return fmt.Errorf("failed to create file directory: %w", err)
+	}
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open file %q: %w", s.config.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat file %q: %w", s.config.Path, err)
+	}
+	s.file, s.size, s.opened = file, info.Size(), s.now()
+	return nil
+}
+
+// send writes the events as lines, rotating the file before a line that would exceed max_size
+// or once rotation_interval has passed, and syncs the file when fsync is enabled. Events that
+// cannot be encoded are dropped. When a write or rotation fails, the lines written before the
+// failure are kept and only the remaining events are retried; a failed sync retries the whole
+// batch, as the written lines may not have reached the disk.
+func (s *fileSink) send(_ context.Context, events []map[string]interface{}, sources []eventSource) error {
+	lines := make([][]byte, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	var dropped []int
+	var droppedErr error
+	for i, event := range events {
+		line, err := s.line(event, sources[i])
+		if err != nil {
+			s.exp.metrics.httpErrors.Add(1)
+			s.exp.logger.Warn("Dropping security event that cannot be written to file",
+				zap.String("path", s.config.Path),
+				zap.Error(err))
+			dropped, droppedErr = append(dropped, i), consumererror.NewPermanent(err)
+			continue
+		}
+		lines = append(lines, line)
+		indexes = append(indexes, i)
+	}
+
+	if err := s.write(lines); err != nil {
+		var unwritten *partialSendError
+		if !errors.As(err, &unwritten) {
+			if len(dropped) == 0 {
+				return err
+			}
+			return &partialSendError{err: err, failed: indexes, dropped: dropped}
+		}
+		failed := make([]int, 0, len(unwritten.failed))
+		for _, n := range unwritten.failed {
+			failed = append(failed, indexes[n])
+		}
+		return &partialSendError{err: unwritten.err, failed: failed, dropped: dropped}
+	}
+	if len(dropped) > 0 {
+		return newPartialSendError(droppedErr, dropped)
+	}
+	return nil
+}
+
+// write writes the lines to the file. When a write or rotation fails after some lines were
+// written, the error is a partialSendError for the lines not written.
+func (s *fileSink) write(lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	if s.exp.dryRun != nil {
+		return s.exp.dryRun.writeRecord("WRITE", s.config.Path, nil, bytes.Join(lines, nil), len(lines))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.New("file is closed")
+	}
+	// lines[:written] are in the file and lines[written:queued] are in pending
+	var pending bytes.Buffer
+	written := 0
+	flush := func(queued int) error {
+		n, err := s.flush(&pending)
+		if err == nil {
+			written = queued
+			return nil
+		}
+		// A line only partly written is written again
+		for ; written < queued && len(lines[written]) <= n; written++ {
+			n -= len(lines[written])
+		}
+		return unsentError(err, written, len(lines))
+	}
+	for i, line := range lines {
+		full := s.size+int64(pending.Len()+len(line)) > s.config.MaxSize
+		expired := s.config.RotationInterval > 0 && s.now().Sub(s.opened) >= s.config.RotationInterval
+		if (full || expired) && s.size+int64(pending.Len()) > 0 {
+			if err := flush(i); err != nil {
+				return err
+			}
+			if err := s.rotate(); err != nil {
+				return unsentError(err, written, len(lines))
+			}
+		}
+		pending.Write(line)
+	}
+	if err := flush(len(lines)); err != nil {
+		return err
+	}
+	if s.config.Fsync {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file %q: %w", s.config.Path, err)
+		}
+	}
+	return nil
+}
+
+// add writes a copy of the events delivered by another transport, logging failures
+func (s *fileSink) add(ctx context.Context, events []map[string]interface{}, sources []eventSource) {
+	if err := s.send(ctx, events, sources); err != nil {
+		s.exp.logger.Warn("Failed to write security events to file",
+			zap.String("path", s.config.Path),
+			zap.Int("event_count", len(events)),
+			zap.Error(err))
+	}
+}
+
+// line returns the event in the configured format, terminated by a newline
+func (s *fileSink) line(event map[string]interface{}, source eventSource) ([]byte, error) {
+	if s.cef == nil {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal security event: %w", err)
+		}
+		return append(line, '\n'), nil
+	}
+	encoded, err := s.cef.encode(source.attributes, source.record, source.resource)
+	if err != nil {
+		return nil, err
+	}
+	line, err := s.cef.renderLine(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line + "\n"), nil
+}
+
+// flush writes and empties pending, returning the number of bytes written
+func (s *fileSink) flush(pending *bytes.Buffer) (int, error) {
+	if pending.Len() == 0 {
+		return 0, nil
+	}
+	n, err := s.file.Write(pending.Bytes())
+	s.size += int64(n)
+	pending.Reset()
+	if err != nil {
+		return n, fmt.Errorf("failed to write file %q: %w", s.config.Path, err)
+	}
+	return n, nil
+}
+
+// rotate renames the file to a timestamped backup, opens a new file and compresses and prunes
+// the backups in the background. When the rotation fails the file is opened again, so the batch
+// is retried and later batches are written to it.
+func (s *fileSink) rotate() error {
+	err := s.file.Close()
+	s.file = nil
+	if err != nil {
+		return s.reopen(fmt.Errorf("failed to close file %q: %w", s.config.Path, err))
+	}
+	backup := s.backupName(s.now())
+	if err := s.rename(s.config.Path, backup); err != nil {
+		return s.reopen(fmt.Errorf("failed to rotate file %q: %w", s.config.Path, err))
+	}
+	if err := s.open(); err != nil {
+		// Keep writing to the previous file rather than to nothing
+		if restoreErr := s.rename(backup, s.config.Path); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return s.reopen(err)
+	}
+
+	s.compressions.Add(1)
+	go func() {
+		defer s.compressions.Done()
+		s.housekeeping.Lock()
+		defer s.housekeeping.Unlock()
+		if s.config.Compress {
+			if err := compressFile(backup); err != nil {
+				s.exp.logger.Warn("Failed to compress rotated file", zap.String("path", backup), zap.Error(err))
+			}
+		}
+		s.prune()
+	}()
+	return nil
+}
+
+// reopen opens the file again after a failed rotation and returns err
+func (s *fileSink) reopen(err error) error {
+	if openErr := s.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// backupName returns the name of a backup rotated at t: the file name with the time inserted
+// before the extension, such as events-2024-01-31T09-15-00.000.log. The time is moved forward
+// past existing backups, so a backup is never overwritten.
+func (s *fileSink) backupName(t time.Time) string {
+	ext := filepath.Ext(s.config.Path)
+	for {
+		name := strings.TrimSuffix(s.config.Path, ext) + "-" + t.UTC().Format(fileBackupTimeFormat) + ext
+		if !fileExists(name) && !fileExists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// backups returns the backup files, oldest first
+func (s *fileSink) backups() ([]string, error) {
+	ext := filepath.Ext(s.config.Path)
+	prefix := filepath.Base(strings.TrimSuffix(s.config.Path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(s.config.Path))
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if _, err := time.Parse(fileBackupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(s.config.Path), name))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// prune removes the oldest backups beyond max_backups
+func (s *fileSink) prune() {
+	if s.config.MaxBackups == 0 {
+		return
+	}
+	backups, err := s.backups()
+	if err != nil {
+		s.exp.logger.Warn("Failed to list rotated files", zap.Error(err))
+		return
+	}
+	for len(backups) > s.config.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.exp.logger.Warn("Failed to remove rotated file", zap.String("path", backups[0]), zap.Error(err))
+		}
+		backups = backups[1:]
+	}
+}
+
+// compressFile gzips path to path.gz and removes path
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// shutdown closes the file and waits for rotated files to be compressed
+func (s *fileSink) shutdown(context.Context) error {
+	s.mu.Lock()
+	var err error
+	if s.file != nil {
+		err = s.file.Close()
+		s.file = nil
+	}
+	s.mu.Unlock()
+	s.compressions.Wait()
+	return err
+}
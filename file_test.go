@@ -0,0 +1,357 @@
+package exporter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newTestFileExporter starts an exporter using the file transport writing to events.ndjson in a
+// new temporary directory
+func newTestFileExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	exp := newTestTransportExporter(t, transportFile, "http://localhost:8080/security-events", func(cfg *Config) {
+		cfg.File.Path = path
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+	return exp, path
+}
+
+// readLines returns the lines of path, decompressing .gz files
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%s is not gzip: %v", path, err)
+		}
+		scanner = bufio.NewScanner(gz)
+	}
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// listBackups returns the names of the files next to path other than path itself, sorted
+func listBackups(t *testing.T, path string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to list %s: %v", filepath.Dir(path), err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != filepath.Base(path) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileWritesNDJSON(t *testing.T) {
+	exp, path := newTestFileExporter(t, nil)
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	lines := readLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("Line %q is not JSON: %v", lines[1], err)
+	}
+	if event["user.name"] != "bob" {
+		t.Errorf("Unexpected event %v", event)
+	}
+}
+
+func TestFileWritesCEF(t *testing.T) {
+	exp, path := newTestFileExporter(t, func(cfg *Config) {
+		cfg.File.Format = fileFormatCEF
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	lines := readLines(t, path)
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "CEF:0|") || !strings.Contains(lines[0], "suser=alice") {
+		t.Errorf("Expected a CEF line for the JSON encoding, got %q", lines)
+	}
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	exp, path := newTestFileExporter(t, func(cfg *Config) {
+		cfg.File.MaxSize = 1
+		cfg.File.MaxBackups = 2
+	})
+	sink := exp.transport.(*fileSink)
+	sink.now = func() time.Time { return time.Date(2024, 1, 31, 9, 15, 0, 0, time.UTC) }
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "dave")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := sink.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() returned error: %v", err)
+	}
+
+	if lines := readLines(t, path); len(lines) != 1 || !strings.Contains(lines[0], "dave") {
+		t.Errorf("Expected the last event in the current file, got %q", lines)
+	}
+	backups := listBackups(t, path)
+	want := []string{"events-2024-01-31T09-15-00.001.ndjson.gz", "events-2024-01-31T09-15-00.002.ndjson.gz"}
+	if strings.Join(backups, ",") != strings.Join(want, ",") {
+		t.Fatalf("Backups = %v, want the 2 newest compressed backups %v", backups, want)
+	}
+	if lines := readLines(t, filepath.Join(filepath.Dir(path), backups[1])); len(lines) != 1 || !strings.Contains(lines[0], "carol") {
+		t.Errorf("Expected carol in the newest backup, got %q", lines)
+	}
+}
+
+func TestFileKeepsWritingAfterFailedRotation(t *testing.T) {
+	exp, path := newTestFileExporter(t, func(cfg *Config) {
+		cfg.File.MaxSize = 1
+	})
+	sink := exp.transport.(*fileSink)
+	sink.rename = func(string, string) error {
+		return errors.New("disk full")
+	}
+
+	// alice was written before the rotation failed, so only bob is retried
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob"))
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected a retryable error for the unwritten events, got %v", err)
+	}
+	retried := logsErr.Data()
+	if retried.LogRecordCount() != 1 {
+		t.Fatalf("Expected 1 record to be retried, got %d", retried.LogRecordCount())
+	}
+	if name, _ := retried.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("user.name"); name.Str() != "bob" {
+		t.Errorf("Expected bob to be retried, got %q", name.Str())
+	}
+
+	// The file was opened again, so the next batch rotates it and is written
+	sink.rename = os.Rename
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("carol")); err != nil {
+		t.Fatalf("Expected the sink to stay usable after a failed rotation, got %v", err)
+	}
+	if err := sink.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() returned error: %v", err)
+	}
+	if lines := readLines(t, path); len(lines) != 1 || !strings.Contains(lines[0], "carol") {
+		t.Errorf("Expected carol in the current file, got %q", lines)
+	}
+	backups := listBackups(t, path)
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup, got %v", backups)
+	}
+	if lines := readLines(t, filepath.Join(filepath.Dir(path), backups[0])); len(lines) != 1 || !strings.Contains(lines[0], "alice") {
+		t.Errorf("Expected alice in the backup, got %q", lines)
+	}
+}
+
+func TestFileDropsUnencodableEvents(t *testing.T) {
+	exp, path := newTestFileExporter(t, nil)
+	events := []map[string]interface{}{
+		{"user.name": "alice", "risk.score": math.NaN()},
+		{"user.name": "bob"},
+	}
+	sources := []eventSource{newEventSource(events[0], plog.NewLogRecord()), newEventSource(events[1], plog.NewLogRecord())}
+
+	err := exp.transport.send(context.Background(), events, sources)
+	var partial *partialSendError
+	if !errors.As(err, &partial) || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected a permanent partial failure, got %v", err)
+	}
+	if len(partial.failed) != 1 || partial.failed[0] != 0 {
+		t.Errorf("Expected only event 0 to be dropped, got %v", partial.failed)
+	}
+	if lines := readLines(t, path); len(lines) != 1 || !strings.Contains(lines[0], "bob") {
+		t.Errorf("Expected the event for bob to be written, got %q", lines)
+	}
+}
+
+func TestFileRotatesByAge(t *testing.T) {
+	exp, path := newTestFileExporter(t, func(cfg *Config) {
+		cfg.File.RotationInterval = time.Hour
+		cfg.File.Compress = false
+	})
+	sink := exp.transport.(*fileSink)
+	now := time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+	sink.opened = now
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if backups := listBackups(t, path); len(backups) != 0 {
+		t.Fatalf("Expected no rotation before rotation_interval, got %v", backups)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("carol")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if err := sink.shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() returned error: %v", err)
+	}
+	backups := listBackups(t, path)
+	if len(backups) != 1 || backups[0] != "events-2024-01-31T10-00-00.000.ndjson" {
+		t.Fatalf("Expected one uncompressed backup, got %v", backups)
+	}
+	if lines := readLines(t, filepath.Join(filepath.Dir(path), backups[0])); len(lines) != 2 {
+		t.Errorf("Expected alice and bob in the backup, got %q", lines)
+	}
+	if lines := readLines(t, path); len(lines) != 1 || !strings.Contains(lines[0], "carol") {
+		t.Errorf("Expected carol in the new file, got %q", lines)
+	}
+}
+
+func TestFileAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "events.ndjson")
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{\"previous\":true}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	exp := newTestTransportExporter(t, transportFile, "http://localhost:8080/security-events", func(cfg *Config) {
+		cfg.File.Path = path
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if lines := readLines(t, path); len(lines) != 2 || lines[0] != `{"previous":true}` {
+		t.Errorf("Expected the event to be appended, got %q", lines)
+	}
+}
+
+func TestFileTee(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status int
+		lines  int
+	}{
+		{name: "delivered", status: http.StatusOK, lines: 2},
+		{name: "retried", status: http.StatusServiceUnavailable, lines: 0},
+		{name: "rejected", status: http.StatusBadRequest, lines: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.ndjson")
+			exp := newTestTransportExporter(t, transportHTTP, newTestPrimaryServer(t, tt.status), func(cfg *Config) {
+				cfg.File.Path = path
+			})
+			var err error
+			if exp.fileTee, err = newFileSink(exp); err != nil {
+				t.Fatalf("newFileSink() returned error: %v", err)
+			}
+			if err := exp.fileTee.start(context.Background(), componenttest.NewNopHost()); err != nil {
+				t.Fatalf("start() returned error: %v", err)
+			}
+
+			_ = exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob"))
+			if lines := readLines(t, path); len(lines) != tt.lines {
+				t.Errorf("Expected %d lines in the file, got %d", tt.lines, len(lines))
+			}
+		})
+	}
+}
+
+func TestFileDryRun(t *testing.T) {
+	var out strings.Builder
+	exp, path := newTestFileExporter(t, nil)
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var request dryRunRequest
+	if err := json.Unmarshal([]byte(out.String()), &request); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	if request.Method != "WRITE" || request.URL != path || request.EventCount != 1 || !strings.Contains(out.String(), `"user.name":"alice"`) {
+		t.Errorf("Unexpected dry-run output: %s", out.String())
+	}
+	if lines := readLines(t, path); len(lines) != 0 {
+		t.Errorf("Expected nothing written in dry-run mode, got %q", lines)
+	}
+}
+
+func TestFileConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *FileConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*FileConfig) {}},
+		{name: "invalid format", modify: func(cfg *FileConfig) {
+			cfg.Format = "leef"
+		}, errorMsg: "invalid file format"},
+		{name: "invalid max size", modify: func(cfg *FileConfig) {
+			cfg.MaxSize = 0
+		}, errorMsg: "max_size must be positive"},
+		{name: "negative rotation interval", modify: func(cfg *FileConfig) {
+			cfg.RotationInterval = -time.Hour
+		}, errorMsg: "rotation_interval must not be negative"},
+		{name: "negative max backups", modify: func(cfg *FileConfig) {
+			cfg.MaxBackups = -1
+		}, errorMsg: "max_backups must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultFileConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Transport = transportFile
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "file path is required") {
+		t.Errorf("Validate() error = %v, want error for the missing path", err)
+	}
+}
@@ -0,0 +1,16 @@
+// Package metadata holds the component status declared in metadata.yaml. This file is written
+// by hand to match mdatagen's output; make generate replaces it with the generated version.
+package metadata
+
+import (
+	"go.opentelemetry.io/collector/component"
+)
+
+var (
+	Type      = component.MustNewType("securityevent")
+	ScopeName = "github.com/henrikrexed/SecurityEventExporter"
+)
+
+const (
+	LogsStability = component.StabilityLevelStable
+)
@@ -0,0 +1,376 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configoptional"
+	"go.opentelemetry.io/collector/config/configtls"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+const (
+	// kafkaScheme is the optional scheme of the broker list endpoint
+	kafkaScheme = "kafka://"
+
+	// kafkaAcksAll waits for all in-sync replicas to acknowledge a record
+	kafkaAcksAll = "all"
+
+	// kafkaAcksLeader waits for the partition leader only
+	kafkaAcksLeader = "leader"
+
+	// kafkaAcksNone does not wait for acknowledgement
+	kafkaAcksNone = "none"
+)
+
+// kafkaCompressions maps the compression setting to producer batch codecs
+var kafkaCompressions = map[string]kgo.CompressionCodec{
+	"none":   kgo.NoCompression(),
+	"gzip":   kgo.GzipCompression(),
+	"snappy": kgo.SnappyCompression(),
+	"lz4":    kgo.Lz4Compression(),
+	"zstd":   kgo.ZstdCompression(),
+}
+
+// KafkaConfig configures the Kafka producer transport. The endpoint is the comma-separated list
+// of bootstrap brokers, for example kafka://broker-1:9092,broker-2:9092.
+type KafkaConfig struct {
+	// Topic is the topic of each message. "%{attribute}" placeholders are replaced by event
+	// attributes and "%{+yyyy.MM.dd}" by the event date.
+	Topic string `mapstructure:"topic"`
+
+	// PartitionKey is the message key, so events with the same value, such as the same user or
+	// source address, land in the same partition in order. Messages without a key are spread
+	// over partitions.
+	PartitionKey FieldSource `mapstructure:"partition_key"`
+
+	// Headers are static headers added to every message
+	Headers map[string]string `mapstructure:"headers"`
+
+	// HeaderAttributes are attributes also sent as message headers named after the attribute
+	HeaderAttributes []string `mapstructure:"header_attributes"`
+
+	// Acks is the acknowledgement required for a produced message: "all" (default), "leader"
+	// or "none"
+	Acks string `mapstructure:"acks"`
+
+	// Idempotent enables the idempotent producer, so broker retries do not duplicate messages.
+	// It requires acks "all".
+	Idempotent bool `mapstructure:"idempotent"`
+
+	// Compression is the batch compression: "none" (default), "gzip", "snappy", "lz4" or "zstd"
+	Compression string `mapstructure:"compression"`
+
+	// ClientID is the client ID reported to the brokers
+	ClientID string `mapstructure:"client_id"`
+
+	// TLS enables TLS to the brokers when set
+	TLS configoptional.Optional[configtls.ClientConfig] `mapstructure:"tls"`
+
+	// SASL configures SASL authentication
+	SASL KafkaSASLConfig `mapstructure:"sasl"`
+}
+
+// KafkaSASLConfig configures SASL authentication to the brokers
+type KafkaSASLConfig struct {
+	// Mechanism is "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512"; SASL is disabled when empty
+	Mechanism string `mapstructure:"mechanism"`
+
+	// Username is the SASL username
+	Username string `mapstructure:"username"`
+
+	// Password is the SASL password
+	Password configopaque.String `mapstructure:"password"`
+}
+
+// Validate checks the topic template, producer settings and SASL mechanism
+func (cfg *KafkaConfig) Validate() error {
+	if cfg.Topic == "" {
+		return errors.New("kafka topic is required")
+	}
+	if _, err := parseTemplate(cfg.Topic); err != nil {
+		return fmt.Errorf("invalid kafka topic: %w", err)
+	}
+	switch cfg.Acks {
+	case kafkaAcksAll, kafkaAcksLeader, kafkaAcksNone:
+	default:
+		return fmt.Errorf("invalid kafka acks %q: must be %q, %q or %q", cfg.Acks, kafkaAcksAll, kafkaAcksLeader, kafkaAcksNone)
+	}
+	if cfg.Idempotent && cfg.Acks != kafkaAcksAll {
+		return fmt.Errorf("the idempotent kafka producer requires acks %q", kafkaAcksAll)
+	}
+	if _, ok := kafkaCompressions[cfg.Compression]; !ok {
+		return fmt.Errorf("invalid kafka compression %q: must be none, gzip, snappy, lz4 or zstd", cfg.Compression)
+	}
+	switch cfg.SASL.Mechanism {
+	case "":
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		if cfg.SASL.Username == "" {
+			return fmt.Errorf("kafka sasl %s requires a username", cfg.SASL.Mechanism)
+		}
+	default:
+		return fmt.Errorf("invalid kafka sasl mechanism %q: must be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", cfg.SASL.Mechanism)
+	}
+	return nil
+}
+
+// createDefaultKafkaConfig creates the default topic and producer settings
+func createDefaultKafkaConfig() KafkaConfig {
+	return KafkaConfig{
+		Topic:       "security-events",
+		Acks:        kafkaAcksAll,
+		Idempotent:  true,
+		Compression: "none",
+		ClientID:    "otelcol-securityevent",
+	}
+}
+
+// parseKafkaEndpoint returns the brokers of a comma-separated host:port list, with or without
+// the kafka:// scheme
+func parseKafkaEndpoint(endpoint string) ([]string, error) {
+	list := strings.TrimPrefix(endpoint, kafkaScheme)
+	if list == "" {
+		return nil, errors.New("endpoint is required: list the kafka brokers as host:port")
+	}
+	var brokers []string
+	for _, broker := range strings.Split(list, ",") {
+		broker = strings.TrimSpace(broker)
+		host, port, err := net.SplitHostPort(broker)
+		if err != nil || host == "" || port == "" {
+			return nil, fmt.Errorf("invalid kafka endpoint %q: brokers must be host:port", endpoint)
+		}
+		brokers = append(brokers, broker)
+	}
+	return brokers, nil
+}
+
+// kafkaTransport produces one message per security event
+type kafkaTransport struct {
+	exp     *securityEventExporter
+	config  *KafkaConfig
+	brokers []string
+	topic   *fieldTemplate
+	client  *kgo.Client
+}
+
+// kafkaDryRunMessage is a would-be message written in dry-run mode
+type kafkaDryRunMessage struct {
+	Topic   string            `json:"topic"`
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Value   json.RawMessage   `json:"value"`
+}
+
+// newKafkaTransport creates the Kafka transport of e
+func newKafkaTransport(e *securityEventExporter) (*kafkaTransport, error) {
+	cfg := &e.config.Kafka
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	brokers, err := parseKafkaEndpoint(e.config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	topic, err := parseTemplate(cfg.Topic)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaTransport{exp: e, config: cfg, brokers: brokers, topic: topic}, nil
+}
+
+// start creates the producer. Brokers are contacted by the first send, so the collector starts
+// even while the cluster is unreachable.
+func (t *kafkaTransport) start(ctx context.Context, _ component.Host) error {
+	if t.exp.config.isDryRun() {
+		return nil
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(t.brokers...),
+		kgo.ClientID(t.config.ClientID),
+		kgo.ProducerBatchCompression(kafkaCompressions[t.config.Compression]),
+	}
+	switch t.config.Acks {
+	case kafkaAcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case kafkaAcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	default:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	}
+	if !t.config.Idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+	opts = append(opts, kgo.RecordDeliveryTimeout(t.exp.config.requestTimeout()))
+	if t.config.TLS.HasValue() {
+		tlsConfig, err := t.config.TLS.Get().LoadTLSConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load kafka TLS configuration: %w", err)
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+	if mechanism := t.saslMechanism(); mechanism != nil {
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	t.client = client
+	return nil
+}
+
+// saslMechanism returns the configured SASL mechanism, or nil when SASL is disabled
+func (t *kafkaTransport) saslMechanism() sasl.Mechanism {
+	username, password := t.config.SASL.Username, string(t.config.SASL.Password)
+	switch t.config.SASL.Mechanism {
+	case "PLAIN":
+		return plain.Auth{User: username, Pass: password}.AsMechanism()
+	case "SCRAM-SHA-256":
+		return scram.Auth{User: username, Pass: password}.AsSha256Mechanism()
+	case "SCRAM-SHA-512":
+		return scram.Auth{User: username, Pass: password}.AsSha512Mechanism()
+	default:
+		return nil
+	}
+}
+
+// send produces the batch and waits for the configured acknowledgement. Events whose message
+// cannot be built, such as those whose topic renders empty, and messages the brokers rejected for
+// good, such as oversized messages or unauthorized topics, are dropped; others that failed are
+// retried without producing the acknowledged messages again.
+func (t *kafkaTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	records := make([]*kgo.Record, 0, len(events))
+	indexes := make(map[*kgo.Record]int, len(events))
+	var retry, dropped []int
+	var retryErr, droppedErr error
+	for i, event := range events {
+		record, err := t.record(event, sources[i])
+		if err != nil {
+			t.exp.logger.Warn("Failed to build Kafka message for security event", zap.Error(err))
+			dropped, droppedErr = append(dropped, i), err
+			continue
+		}
+		records = append(records, record)
+		indexes[record] = i
+	}
+
+	if t.exp.dryRun != nil {
+		if len(records) > 0 {
+			if err := t.writeDryRun(records); err != nil {
+				return err
+			}
+		}
+	} else if len(records) > 0 {
+		for _, result := range t.client.ProduceSync(ctx, records...) {
+			if result.Err == nil {
+				continue
+			}
+			t.exp.logger.Warn("Failed to produce security event",
+				zap.String("topic", result.Record.Topic),
+				zap.Error(result.Err))
+			var kafkaErr *kerr.Error
+			if errors.As(result.Err, &kafkaErr) && !kafkaErr.Retriable {
+				dropped, droppedErr = append(dropped, indexes[result.Record]), result.Err
+			} else {
+				retry, retryErr = append(retry, indexes[result.Record]), result.Err
+			}
+		}
+	}
+
+	if len(retry)+len(dropped) > 0 {
+		t.exp.metrics.httpErrors.Add(1)
+	}
+	switch {
+	case len(retry) > 0:
+		return &partialSendError{err: fmt.Errorf("failed to produce kafka messages: %w", retryErr), failed: retry, dropped: dropped}
+	case len(dropped) > 0:
+		return newPartialSendError(consumererror.NewPermanent(fmt.Errorf("dropped kafka messages: %w", droppedErr)), dropped)
+	}
+
+	t.exp.logger.Debug("Produced security events to Kafka",
+		zap.Strings("brokers", t.brokers),
+		zap.Int("event_count", len(events)))
+	return nil
+}
+
+// record builds the message of one event. Line-oriented encodings are sent as the rendered line;
+// all others as the event JSON. The timestamp is left to the producer: the delivery timeout is
+// measured from it, so late events carrying their original time would expire before being sent.
+func (t *kafkaTransport) record(event map[string]interface{}, source eventSource) (*kgo.Record, error) {
+	record := &kgo.Record{Topic: t.topic.render(source.attributes, source.time)}
+	if record.Topic == "" {
+		return nil, fmt.Errorf("kafka topic template %q rendered empty", t.config.Topic)
+	}
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, err
+		}
+		record.Value = []byte(line)
+	} else {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal security event: %w", err)
+		}
+		record.Value = value
+	}
+
+	if key := t.config.PartitionKey.resolve(source.attributes); key != "" {
+		record.Key = []byte(key)
+	}
+	for name, value := range t.config.Headers {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: name, Value: []byte(value)})
+	}
+	for _, attribute := range t.config.HeaderAttributes {
+		if value := stringField(source.attributes, attribute); value != "" {
+			record.Headers = append(record.Headers, kgo.RecordHeader{Key: attribute, Value: []byte(value)})
+		}
+	}
+	return record, nil
+}
+
+// writeDryRun records the would-be messages as one JSON array
+func (t *kafkaTransport) writeDryRun(records []*kgo.Record) error {
+	messages := make([]kafkaDryRunMessage, 0, len(records))
+	for _, record := range records {
+		message := kafkaDryRunMessage{Topic: record.Topic, Key: string(record.Key), Value: record.Value}
+		if !json.Valid(record.Value) {
+			message.Value, _ = json.Marshal(string(record.Value))
+		}
+		for _, header := range record.Headers {
+			if message.Headers == nil {
+				message.Headers = make(map[string]string, len(record.Headers))
+			}
+			message.Headers[header.Key] = string(header.Value)
+		}
+		messages = append(messages, message)
+	}
+	body, err := json.Marshal(messages)
+	if err != nil {
+		return consumererror.NewPermanent(fmt.Errorf("failed to marshal dry-run messages: %w", err))
+	}
+	return t.exp.dryRun.writeRecord("KAFKA", kafkaScheme+strings.Join(t.brokers, ","), nil, body, len(records))
+}
+
+// shutdown flushes and closes the producer
+func (t *kafkaTransport) shutdown(context.Context) error {
+	if t.client != nil {
+		t.client.Close()
+		t.client = nil
+	}
+	return nil
+}
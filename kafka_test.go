@@ -0,0 +1,257 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// newTestKafkaExporter starts an exporter using the Kafka transport against a new in-process
+// cluster hosting the topics the tests produce to
+func newTestKafkaExporter(t *testing.T, opts []kfake.Opt, configure func(cfg *Config)) (*securityEventExporter, *kfake.Cluster) {
+	t.Helper()
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(1, "security-events", "security-auth")}, opts...)...)
+	if err != nil {
+		t.Fatalf("Failed to start kafka cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	exp := newTestTransportExporter(t, transportKafka, kafkaScheme+strings.Join(cluster.ListenAddrs(), ","), configure)
+	return exp, cluster
+}
+
+// consumeKafka reads count records from topic
+func consumeKafka(t *testing.T, cluster *kfake.Cluster, topic string, count int, opts ...kgo.Opt) []*kgo.Record {
+	t.Helper()
+	client, err := kgo.NewClient(append([]kgo.Opt{
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) < count {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			t.Fatalf("Consumed %d of %d records from %s before the timeout", len(records), count, topic)
+		}
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func TestKafkaProducesMessages(t *testing.T) {
+	exp, cluster := newTestKafkaExporter(t, nil, func(cfg *Config) {
+		cfg.Kafka.Topic = "security-%{service.name}"
+		cfg.Kafka.PartitionKey = FieldSource{Attributes: []string{"user.id", "user.name"}}
+		cfg.Kafka.Headers = map[string]string{"schema": "security-event/v1"}
+		cfg.Kafka.HeaderAttributes = []string{"host.name"}
+		cfg.Kafka.Compression = "zstd"
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	records := consumeKafka(t, cluster, "security-auth", 2)
+	byKey := map[string]*kgo.Record{}
+	for _, record := range records {
+		byKey[string(record.Key)] = record
+	}
+	record, ok := byKey["alice"]
+	if !ok {
+		t.Fatalf("Expected a message keyed alice, got %d messages", len(records))
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(record.Value, &event); err != nil {
+		t.Fatalf("Value is not the event JSON: %v", err)
+	}
+	if event["user.name"] != "alice" {
+		t.Errorf("Unexpected event %v", event)
+	}
+	headers := map[string]string{}
+	for _, header := range record.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers["schema"] != "security-event/v1" || headers["host.name"] != "web-1" {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+}
+
+func TestKafkaLineEncoding(t *testing.T) {
+	exp, cluster := newTestKafkaExporter(t, nil, func(cfg *Config) {
+		cfg.Encoding = encodingCEF
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	records := consumeKafka(t, cluster, "security-events", 1)
+	if !strings.HasPrefix(string(records[0].Value), "CEF:0|") {
+		t.Errorf("Expected a CEF line, got %q", records[0].Value)
+	}
+	if records[0].Key != nil {
+		t.Errorf("Expected no key without a partition key, got %q", records[0].Key)
+	}
+}
+
+func TestKafkaSASLPlain(t *testing.T) {
+	exp, cluster := newTestKafkaExporter(t, []kfake.Opt{kfake.EnableSASL(), kfake.Superuser("PLAIN", "admin", "secret")}, func(cfg *Config) {
+		cfg.Kafka.SASL = KafkaSASLConfig{Mechanism: "PLAIN", Username: "admin", Password: "secret"}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	mechanism := exp.transport.(*kafkaTransport).saslMechanism()
+	if len(consumeKafka(t, cluster, "security-events", 1, kgo.SASL(mechanism))) != 1 {
+		t.Error("Expected the message to be produced with SASL authentication")
+	}
+}
+
+func TestKafkaDropsRejectedMessages(t *testing.T) {
+	exp, cluster := newTestKafkaExporter(t, nil, nil)
+
+	logs := newSyslogTestLogs("alice", "mallory")
+	oversized := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(1)
+	oversized.Attributes().PutStr("payload", strings.Repeat("x", 2<<20))
+
+	err := exp.ConsumeLogs(context.Background(), logs)
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected permanent error for an oversized message, got %v", err)
+	}
+	var partial *partialSendError
+	if !errors.As(err, &partial) || len(partial.dropped)+len(partial.failed) != 1 {
+		t.Errorf("Expected only the oversized message to fail, got %v", err)
+	}
+	if exp.metrics.eventsExported.Load() != 1 {
+		t.Errorf("eventsExported = %d, want 1", exp.metrics.eventsExported.Load())
+	}
+	if records := consumeKafka(t, cluster, "security-events", 1); !strings.Contains(string(records[0].Value), "alice") {
+		t.Errorf("Expected alice's message to be produced, got %q", records[0].Value)
+	}
+}
+
+func TestKafkaDropsEventsWithoutTopic(t *testing.T) {
+	exp, cluster := newTestKafkaExporter(t, nil, func(cfg *Config) {
+		cfg.Kafka.Topic = "%{tenant.id}"
+	})
+
+	logs := newSyslogTestLogs("alice", "mallory")
+	logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().PutStr("tenant.id", "security-events")
+
+	err := exp.ConsumeLogs(context.Background(), logs)
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected permanent error for an event without a topic, got %v", err)
+	}
+	var partial *partialSendError
+	if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.failed[0] != 1 {
+		t.Errorf("Expected only the event without a topic to be dropped, got %v", err)
+	}
+	if records := consumeKafka(t, cluster, "security-events", 1); !strings.Contains(string(records[0].Value), "alice") {
+		t.Errorf("Expected alice's message to be produced, got %q", records[0].Value)
+	}
+}
+
+func TestKafkaDryRun(t *testing.T) {
+	var out strings.Builder
+	exp := newTestTransportExporter(t, transportKafka, "kafka://broker-1:9092,broker-2:9092", func(cfg *Config) {
+		cfg.Kafka.PartitionKey = FieldSource{Attributes: []string{"user.name"}}
+	})
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var request struct {
+		Method     string               `json:"method"`
+		URL        string               `json:"url"`
+		EventCount int                  `json:"event_count"`
+		Body       []kafkaDryRunMessage `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &request); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	if request.Method != "KAFKA" || request.URL != "kafka://broker-1:9092,broker-2:9092" || request.EventCount != 1 {
+		t.Errorf("Unexpected dry-run output: %s", out.String())
+	}
+	if len(request.Body) != 1 || request.Body[0].Topic != "security-events" || request.Body[0].Key != "alice" {
+		t.Errorf("Unexpected dry-run messages %+v", request.Body)
+	}
+}
+
+func TestParseKafkaEndpoint(t *testing.T) {
+	brokers, err := parseKafkaEndpoint("kafka://broker-1:9092, broker-2:9093")
+	if err != nil || len(brokers) != 2 || brokers[1] != "broker-2:9093" {
+		t.Errorf("parseKafkaEndpoint() = %v, %v", brokers, err)
+	}
+	if brokers, err := parseKafkaEndpoint("broker-1:9092"); err != nil || len(brokers) != 1 {
+		t.Errorf("Expected a scheme-less broker list to be accepted, got %v, %v", brokers, err)
+	}
+	for _, endpoint := range []string{"", "kafka://broker-1", "http://broker-1:9092"} {
+		if _, err := parseKafkaEndpoint(endpoint); err == nil {
+			t.Errorf("Expected error for endpoint %q", endpoint)
+		}
+	}
+}
+
+func TestKafkaConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *KafkaConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*KafkaConfig) {}},
+		{name: "missing topic", modify: func(cfg *KafkaConfig) {
+			cfg.Topic = ""
+		}, errorMsg: "kafka topic is required"},
+		{name: "invalid acks", modify: func(cfg *KafkaConfig) {
+			cfg.Acks = "1"
+		}, errorMsg: "invalid kafka acks"},
+		{name: "idempotent without acks all", modify: func(cfg *KafkaConfig) {
+			cfg.Acks = kafkaAcksLeader
+		}, errorMsg: "idempotent kafka producer requires acks"},
+		{name: "leader acks without idempotence", modify: func(cfg *KafkaConfig) {
+			cfg.Acks = kafkaAcksLeader
+			cfg.Idempotent = false
+		}},
+		{name: "invalid compression", modify: func(cfg *KafkaConfig) {
+			cfg.Compression = "brotli"
+		}, errorMsg: "invalid kafka compression"},
+		{name: "invalid sasl mechanism", modify: func(cfg *KafkaConfig) {
+			cfg.SASL.Mechanism = "GSSAPI"
+		}, errorMsg: "invalid kafka sasl mechanism"},
+		{name: "sasl without username", modify: func(cfg *KafkaConfig) {
+			cfg.SASL.Mechanism = "SCRAM-SHA-512"
+		}, errorMsg: "requires a username"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultKafkaConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
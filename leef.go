@@ -0,0 +1,237 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// LEEFConfig configures the LEEF 2.0 encoding
+type LEEFConfig struct {
+	// Delimiter separates the event attributes: a single character, or its hex code prefixed
+	// with x or 0x (for example x09 for tab, the default)
+	Delimiter string `mapstructure:"delimiter"`
+
+	// Vendor is the Vendor header field
+	Vendor FieldSource `mapstructure:"vendor"`
+
+	// Product is the Product header field
+	Product FieldSource `mapstructure:"product"`
+
+	// Version is the Version header field
+	Version FieldSource `mapstructure:"version"`
+
+	// EventID is the EventID header field QRadar maps events to QIDs with
+	EventID FieldSource `mapstructure:"event_id"`
+
+	// SeverityMap overrides sev (1-10) per OpenTelemetry severity family, for example ERROR: 8.
+	// Unlisted families use the shared linear mapping.
+	SeverityMap map[string]int `mapstructure:"severity_map"`
+
+	// Keys maps attribute keys to LEEF attribute keys, overriding or extending the built-in
+	// mapping (for example client.address to src). An empty key drops the attribute.
+	Keys map[string]string `mapstructure:"keys"`
+
+	// UnmappedAttributes controls attributes without a LEEF key: "keep" (default) adds them with
+	// the key reduced to letters, digits and underscores, "drop" removes them
+	UnmappedAttributes string `mapstructure:"unmapped_attributes"`
+}
+
+// defaultLEEFKeys maps semantic convention attributes to predefined LEEF attribute keys
+var defaultLEEFKeys = map[string]string{
+	"client.address":      "src",
+	"source.address":      "src",
+	"source.ip":           "src",
+	"client.port":         "srcPort",
+	"source.port":         "srcPort",
+	"server.address":      "dst",
+	"destination.address": "dst",
+	"destination.ip":      "dst",
+	"server.port":         "dstPort",
+	"destination.port":    "dstPort",
+	"network.transport":   "proto",
+	"user.name":           "usrName",
+	"enduser.id":          "usrName",
+	"user.domain":         "domain",
+	"host.name":           "identHostName",
+	"event.category":      "cat",
+	"url.full":            "url",
+	"user_agent.original": "userAgent",
+	"http.request.method": "method",
+	"file.path":           "resource",
+	"rule.name":           "policy",
+	"user.roles":          "role",
+}
+
+// defaultLEEFDelimiter is the attribute delimiter used when none is configured
+const defaultLEEFDelimiter = "x09"
+
+// Validate checks the delimiter, severity overrides and the unmapped attribute handling
+func (cfg *LEEFConfig) Validate() error {
+	var errs []error
+	if _, err := parseLEEFDelimiter(cfg.Delimiter); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateSeverityMap(cfg.SeverityMap, 1, 10); err != nil {
+		errs = append(errs, fmt.Errorf("leef: %w", err))
+	}
+	switch cfg.UnmappedAttributes {
+	case "", unmappedKeep, unmappedDrop:
+	default:
+		errs = append(errs, fmt.Errorf("invalid leef unmapped_attributes %q: must be %q or %q", cfg.UnmappedAttributes, unmappedKeep, unmappedDrop))
+	}
+	for attribute, key := range cfg.Keys {
+		if key != "" && sanitizeExtensionKey(key) != key {
+			errs = append(errs, fmt.Errorf("leef key %q for %q must contain only letters, digits and underscores", key, attribute))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultLEEFConfig creates the default LEEF header field sources
+func createDefaultLEEFConfig() LEEFConfig {
+	return LEEFConfig{
+		Delimiter:          defaultLEEFDelimiter,
+		Vendor:             FieldSource{Value: "OpenTelemetry"},
+		Product:            FieldSource{Attributes: []string{"service.name"}, Value: "SecurityEventExporter"},
+		Version:            FieldSource{Attributes: []string{"service.version"}, Value: "1.0"},
+		EventID:            FieldSource{Attributes: []string{"event.code", "rule.id", "event.action"}, Value: "security-event"},
+		UnmappedAttributes: unmappedKeep,
+	}
+}
+
+// parseLEEFDelimiter returns the delimiter character of a delimiter setting. An empty setting
+// selects tab.
+func parseLEEFDelimiter(delimiter string) (rune, error) {
+	if delimiter == "" {
+		delimiter = defaultLEEFDelimiter
+	}
+
+	var r rune
+	lower := strings.ToLower(delimiter)
+	switch {
+	case utf8.RuneCountInString(delimiter) == 1:
+		r, _ = utf8.DecodeRuneInString(delimiter)
+	case strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "x"):
+		code, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(lower, "0"), "x"), 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid leef delimiter %q: hex code expected after x", delimiter)
+		}
+		r = rune(code)
+	default:
+		return 0, fmt.Errorf("invalid leef delimiter %q: must be a single character or a hex code such as x09", delimiter)
+	}
+
+	switch r {
+	case '=', '|', '\\', '\n', '\r', 0:
+		return 0, fmt.Errorf("invalid leef delimiter %q: %q cannot delimit attributes", delimiter, r)
+	}
+	return r, nil
+}
+
+// leefEncoder renders security events as LEEF 2.0 lines
+type leefEncoder struct {
+	config    *LEEFConfig
+	delimiter rune
+	keys      *extensionMapping
+}
+
+// newLEEFEncoder creates a LEEF encoder from cfg
+func newLEEFEncoder(cfg *LEEFConfig) (*leefEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	delimiter, _ := parseLEEFDelimiter(cfg.Delimiter)
+
+	keys := newExtensionMapping(defaultLEEFKeys, cfg.Keys, cfg.UnmappedAttributes)
+	return &leefEncoder{config: cfg, delimiter: delimiter, keys: keys}, nil
+}
+
+// encode builds the structured LEEF event: the header fields and the attribute key/value pairs.
+// renderLine turns it into the LEEF line.
+func (enc *leefEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	sev := severityScore(severityNumber(logRecord), enc.config.SeverityMap)
+	if sev < 1 {
+		sev = 1
+	}
+	attributes := map[string]interface{}{
+		"devTime": strconv.FormatInt(eventTime(logRecord).UnixMilli(), 10),
+		"sev":     strconv.Itoa(sev),
+	}
+
+	for leefKey, attribute := range enc.keys.resolve(event) {
+		attributes[leefKey] = fmt.Sprintf("%v", event[attribute])
+	}
+
+	return map[string]interface{}{
+		"version":         "2.0",
+		"vendor":          enc.config.Vendor.resolve(event),
+		"product":         enc.config.Product.resolve(event),
+		"product_version": enc.config.Version.resolve(event),
+		"event_id":        enc.config.EventID.resolve(event),
+		"attributes":      attributes,
+	}, nil
+}
+
+// renderLine renders a structured LEEF event as
+// LEEF:2.0|Vendor|Product|Version|EventID|DelimiterCharacter|key=value<delimiter>key=value
+func (enc *leefEncoder) renderLine(event map[string]interface{}) (string, error) {
+	var b strings.Builder
+	b.WriteString("LEEF:")
+	b.WriteString(stringField(event, "version"))
+	for _, field := range []string{"vendor", "product", "product_version", "event_id"} {
+		b.WriteByte('|')
+		b.WriteString(escapeCEFHeader(stringField(event, field)))
+	}
+	b.WriteByte('|')
+	if enc.delimiter > ' ' && enc.delimiter < utf8.RuneSelf {
+		b.WriteRune(enc.delimiter)
+	} else {
+		fmt.Fprintf(&b, "x%02X", enc.delimiter)
+	}
+	b.WriteByte('|')
+
+	attributes, _ := event["attributes"].(map[string]interface{})
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteRune(enc.delimiter)
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(enc.escapeValue(fmt.Sprintf("%v", attributes[key])))
+	}
+	return b.String(), nil
+}
+
+// escapeValue escapes backslashes, the delimiter and line breaks in an attribute value
+func (enc *leefEncoder) escapeValue(value string) string {
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case enc.delimiter:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
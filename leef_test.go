@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestLEEFEncoderRendersLine(t *testing.T) {
+	cfg := createDefaultLEEFConfig()
+	enc, err := newLEEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newLEEFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	record.SetSeverityNumber(plog.SeverityNumberWarn)
+
+	event := map[string]interface{}{
+		"event.code":     "4625",
+		"client.address": "10.0.0.7",
+		"server.address": "10.0.0.1",
+		"user.name":      "alice",
+		"tenant.id":      "acme",
+		"timestamp":      "2023-11-14T22:13:20Z",
+	}
+
+	encoded, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	line, err := enc.renderLine(encoded)
+	if err != nil {
+		t.Fatalf("renderLine() returned error: %v", err)
+	}
+
+	expected := "LEEF:2.0|OpenTelemetry|SecurityEventExporter|1.0|4625|x09|" +
+		"devTime=1700000000000\tdst=10.0.0.1\tevent_code=4625\tsev=5\tsrc=10.0.0.7\ttenant_id=acme\tusrName=alice"
+	if line != expected {
+		t.Errorf("renderLine() =\n%q\nwant\n%q", line, expected)
+	}
+}
+
+func TestLEEFEncoderConfiguration(t *testing.T) {
+	cfg := createDefaultLEEFConfig()
+	cfg.Delimiter = "^"
+	cfg.EventID = FieldSource{Attributes: []string{"qradar.qid"}, Value: "generic"}
+	cfg.SeverityMap = map[string]int{"ERROR": 9}
+	cfg.Keys = map[string]string{"tenant.id": "tenant"}
+	cfg.UnmappedAttributes = unmappedDrop
+	enc, err := newLEEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newLEEFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetSeverityNumber(plog.SeverityNumberError)
+	event := map[string]interface{}{
+		"qradar.qid": "1002",
+		"tenant.id":  "ac^me",
+		"other":      "dropped",
+	}
+
+	encoded, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	line, err := enc.renderLine(encoded)
+	if err != nil {
+		t.Fatalf("renderLine() returned error: %v", err)
+	}
+
+	expected := `LEEF:2.0|OpenTelemetry|SecurityEventExporter|1.0|1002|^|devTime=0^sev=9^tenant=ac\^me`
+	if line != expected {
+		t.Errorf("renderLine() =\n%s\nwant\n%s", line, expected)
+	}
+}
+
+func TestLEEFKeyCollisions(t *testing.T) {
+	cfg := createDefaultLEEFConfig()
+	enc, err := newLEEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newLEEFEncoder() returned error: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"client.address": "10.0.0.1",
+		"source.address": "10.0.0.2",
+		"src":            "spoofed",
+		"enduser.id":     "svc-account",
+		"user.name":      "alice",
+		"usrName":        "spoofed",
+	}
+
+	// Map iteration order varies between runs, so encode repeatedly
+	for i := 0; i < 50; i++ {
+		encoded, err := enc.encode(event, plog.NewLogRecord(), pcommon.NewResource())
+		if err != nil {
+			t.Fatalf("encode() returned error: %v", err)
+		}
+		attributes := encoded["attributes"].(map[string]interface{})
+		if attributes["src"] != "10.0.0.2" || attributes["usrName"] != "alice" {
+			t.Fatalf("Expected src=10.0.0.2 and usrName=alice, got %v", attributes)
+		}
+	}
+}
+
+func TestLEEFSeverityMinimum(t *testing.T) {
+	cfg := createDefaultLEEFConfig()
+	enc, err := newLEEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newLEEFEncoder() returned error: %v", err)
+	}
+
+	// LEEF severities start at 1, so unspecified and trace severities are raised to it
+	for _, number := range []plog.SeverityNumber{plog.SeverityNumberUnspecified, plog.SeverityNumberTrace} {
+		record := plog.NewLogRecord()
+		record.SetSeverityNumber(number)
+		encoded, err := enc.encode(map[string]interface{}{}, record, pcommon.NewResource())
+		if err != nil {
+			t.Fatalf("encode() returned error: %v", err)
+		}
+		if sev := encoded["attributes"].(map[string]interface{})["sev"]; sev != "1" {
+			t.Errorf("sev for %v = %v, want 1", number, sev)
+		}
+	}
+}
+
+func TestLEEFEscaping(t *testing.T) {
+	cfg := createDefaultLEEFConfig()
+	enc, err := newLEEFEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newLEEFEncoder() returned error: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"event.code": "a|b",
+		"url.full":   "https://example.com/?q=1\\2\tx\ny",
+	}
+	encoded, err := enc.encode(event, plog.NewLogRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	line, err := enc.renderLine(encoded)
+	if err != nil {
+		t.Fatalf("renderLine() returned error: %v", err)
+	}
+
+	if !strings.Contains(line, `|a\|b|`) {
+		t.Errorf("Header field not escaped: %s", line)
+	}
+	if !strings.Contains(line, "url=https://example.com/?q=1\\\\2\\\tx\\ny") {
+		t.Errorf("Attribute value not escaped: %q", line)
+	}
+}
+
+func TestParseLEEFDelimiter(t *testing.T) {
+	tests := []struct {
+		delimiter string
+		expected  rune
+		wantErr   bool
+	}{
+		{delimiter: "", expected: '\t'},
+		{delimiter: "x09", expected: '\t'},
+		{delimiter: "0x5E", expected: '^'},
+		{delimiter: "^", expected: '^'},
+		{delimiter: "\t", expected: '\t'},
+		{delimiter: "x", expected: 'x'},
+		{delimiter: "xZZ", wantErr: true},
+		{delimiter: "ab", wantErr: true},
+		{delimiter: "=", wantErr: true},
+		{delimiter: "x7C", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.delimiter, func(t *testing.T) {
+			got, err := parseLEEFDelimiter(tt.delimiter)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseLEEFDelimiter(%q) = %q, want error", tt.delimiter, got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("parseLEEFDelimiter(%q) = %q, %v, want %q", tt.delimiter, got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLEEFConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      LEEFConfig
+		errorMsg string
+	}{
+		{name: "defaults", cfg: createDefaultLEEFConfig()},
+		{name: "zero severity", cfg: LEEFConfig{SeverityMap: map[string]int{"INFO": 0}}, errorMsg: "between 1 and 10"},
+		{name: "invalid delimiter", cfg: LEEFConfig{Delimiter: "||"}, errorMsg: "invalid leef delimiter"},
+		{name: "invalid unmapped handling", cfg: LEEFConfig{UnmappedAttributes: "labels"}, errorMsg: "unmapped_attributes"},
+		{name: "invalid key", cfg: LEEFConfig{Keys: map[string]string{"tenant.id": "tenant.id"}}, errorMsg: "leef key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
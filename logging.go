@@ -0,0 +1,35 @@
+package exporter
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// recordLogging is how much the conversion of a log record is logged at the debug level
+type recordLogging uint8
+
+const (
+	// recordLogNone logs nothing but conversion errors and attribute conflicts
+	recordLogNone recordLogging = iota
+
+	// recordLogDebug logs the steps of the conversion with their counts
+	recordLogDebug
+
+	// recordLogTrace also logs every attribute added to the event
+	recordLogTrace
+)
+
+// recordLogging decides how much the conversion of the next log record is logged. Nothing is
+// logged unless the debug level is enabled, and then only one in every debug_sample_rate records,
+// so the per-record log arguments are only evaluated for the records that are logged.
+func (e *securityEventExporter) recordLogging() recordLogging {
+	if !e.logger.Core().Enabled(zapcore.DebugLevel) {
+		return recordLogNone
+	}
+	if rate := e.config.DebugSampleRate; rate > 1 && (e.debugSamples.Add(1)-1)%uint64(rate) != 0 {
+		return recordLogNone
+	}
+	if e.config.TraceConversion {
+		return recordLogTrace
+	}
+	return recordLogDebug
+}
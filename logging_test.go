@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newLoggingTestExporter creates an exporter logging at level to an observer
+func newLoggingTestExporter(level zapcore.Level, configure func(cfg *Config)) (*securityEventExporter, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	exp := newStreamTestExporter(configure)
+	exp.logger = zap.New(core)
+	return exp, logs
+}
+
+func TestRecordLoggingSampling(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      zapcore.Level
+		sampleRate int
+		trace      bool
+		records    int
+		attributes int
+	}{
+		{name: "info level", level: zapcore.InfoLevel, sampleRate: 1},
+		{name: "every record", level: zapcore.DebugLevel, sampleRate: 1, records: 9},
+		{name: "one in three", level: zapcore.DebugLevel, sampleRate: 3, records: 3},
+		// Records 0, 3 and 6 are logged, each with four log attributes
+		{name: "one in three with trace", level: zapcore.DebugLevel, sampleRate: 3, trace: true, records: 3, attributes: 12},
+		{name: "trace at info level", level: zapcore.InfoLevel, sampleRate: 1, trace: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, logs := newLoggingTestExporter(tt.level, func(cfg *Config) {
+				cfg.DebugSampleRate = tt.sampleRate
+				cfg.TraceConversion = tt.trace
+			})
+
+			events, _, _, _ := exp.convertLogs(newConversionTestLogs([]int{9}))
+			if len(events) != 9 {
+				t.Fatalf("Got %d events, want 9", len(events))
+			}
+			if n := logs.FilterMessage("Processing log record").Len(); n != tt.records {
+				t.Errorf("Logged %d records, want %d", n, tt.records)
+			}
+			if n := logs.FilterMessage("Completed log to security event conversion").Len(); n != tt.records {
+				t.Errorf("Logged %d completed conversions, want %d", n, tt.records)
+			}
+			if n := logs.FilterMessage("Added log attribute").Len(); n != tt.attributes {
+				t.Errorf("Logged %d log attributes, want %d", n, tt.attributes)
+			}
+			// Conflicts are logged for every record regardless of sampling
+			if n := logs.FilterMessage("Attribute key conflict detected").Len(); n != 3 {
+				t.Errorf("Logged %d conflicts, want 3", n)
+			}
+		})
+	}
+}
+
+func TestConversionLoggingAllocations(t *testing.T) {
+	ld := newConversionTestLogs([]int{1})
+	record := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	resource := ld.ResourceLogs().At(0).Resource()
+
+	allocs := func(exp *securityEventExporter) float64 {
+		return testing.AllocsPerRun(100, func() {
+			if _, err := exp.convertLogToSecurityEvent(record, resource, exp.recordLogging()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// The record has a conflicting attribute, so the warning is gated as well
+	want := allocs(newStreamTestExporter(nil))
+	exp, logs := newLoggingTestExporter(zapcore.ErrorLevel, func(cfg *Config) {
+		cfg.TraceConversion = true
+	})
+	if got := allocs(exp); got != want {
+		t.Errorf("Conversion with debug logging disabled made %v allocations, want %v like without a logger", got, want)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no logs, got %d", logs.Len())
+	}
+}
+
+// BenchmarkConversionLogging converts batches of 1000 records with the logger at the info level,
+// at the debug level with sampling, at the debug level for every record, and with
+// trace_conversion, writing the logs as JSON to io.Discard
+func BenchmarkConversionLogging(b *testing.B) {
+	const events = 1000
+	ld := newBenchmarkLogs(events)
+	benchmarks := []struct {
+		name       string
+		level      zapcore.Level
+		sampleRate int
+		trace      bool
+	}{
+		{name: "info", level: zapcore.InfoLevel, sampleRate: 1},
+		{name: "debug/sample_rate=100", level: zapcore.DebugLevel, sampleRate: 100},
+		{name: "debug", level: zapcore.DebugLevel, sampleRate: 1},
+		{name: "debug/trace_conversion", level: zapcore.DebugLevel, sampleRate: 1, trace: true},
+	}
+
+	for _, bm := range benchmarks {
+		exp := newStreamTestExporter(func(cfg *Config) {
+			cfg.DebugSampleRate = bm.sampleRate
+			cfg.TraceConversion = bm.trace
+		})
+		encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+		exp.logger = zap.New(zapcore.NewCore(encoder, zapcore.AddSync(io.Discard), bm.level))
+
+		b.Run(fmt.Sprintf("%s/events=%d", bm.name, events), func(b *testing.B) {
+			b.ReportAllocs()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				exp.convertLogs(ld)
+			}
+			reportAllocsPerEvent(b, &before, events)
+		})
+	}
+}
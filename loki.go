@@ -0,0 +1,413 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// lokiPushPath is the push API, relative to the endpoint
+	lokiPushPath = "/loki/api/v1/push"
+
+	// lokiFormatProtobuf sends snappy-compressed protobuf push requests
+	lokiFormatProtobuf = "protobuf"
+
+	// lokiFormatJSON sends JSON push requests
+	lokiFormatJSON = "json"
+
+	// lokiMaxLabelNames is Loki's default limit on the number of labels of a stream
+	lokiMaxLabelNames = 15
+
+	// lokiOverflowValue replaces label values beyond the configured cardinality limit
+	lokiOverflowValue = "_overflow"
+)
+
+// lokiLabelNamePattern matches valid Loki label names
+var lokiLabelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LokiConfig configures the Grafana Loki push transport. The endpoint is the Loki URL, for example
+// http://loki.example.com:3100.
+type LokiConfig struct {
+	// Format is the push request format: "protobuf" (default, snappy-compressed) or "json"
+	Format string `mapstructure:"format"`
+
+	// TenantID is sent as X-Scope-OrgID. Events of different tenants are pushed separately; no
+	// header is sent when it resolves empty.
+	TenantID FieldSource `mapstructure:"tenant_id"`
+
+	// LabelAttributes are the attributes events are grouped into streams by. Label names are the
+	// attribute names with characters other than letters, digits and underscores replaced by
+	// underscores, so k8s.namespace.name becomes k8s_namespace_name.
+	LabelAttributes []string `mapstructure:"label_attributes"`
+
+	// Labels are static labels added to every stream; a label set to an empty value is left out
+	Labels map[string]string `mapstructure:"labels"`
+
+	// MaxLabelValues is the number of distinct values kept per label attribute. Further values
+	// are replaced by "_overflow" so an unexpected attribute cannot create unbounded streams.
+	MaxLabelValues int `mapstructure:"max_label_values"`
+
+	// MaxLabelValueLength is the length label values are truncated to
+	MaxLabelValueLength int `mapstructure:"max_label_value_length"`
+}
+
+// Validate checks the format, label names and cardinality limits
+func (cfg *LokiConfig) Validate() error {
+	if cfg.Format != lokiFormatProtobuf && cfg.Format != lokiFormatJSON {
+		return fmt.Errorf("invalid loki format %q: must be %q or %q", cfg.Format, lokiFormatProtobuf, lokiFormatJSON)
+	}
+	if cfg.MaxLabelValues <= 0 {
+		return errors.New("loki max_label_values must be positive")
+	}
+	if cfg.MaxLabelValueLength <= 0 {
+		return errors.New("loki max_label_value_length must be positive")
+	}
+
+	names := make(map[string]bool, len(cfg.Labels)+len(cfg.LabelAttributes))
+	for name := range cfg.Labels {
+		if !lokiLabelNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid loki label name %q", name)
+		}
+		names[name] = true
+	}
+	for _, attribute := range cfg.LabelAttributes {
+		name := lokiLabelName(attribute)
+		if names[name] {
+			return fmt.Errorf("loki label %q of attribute %q is defined twice", name, attribute)
+		}
+		names[name] = true
+	}
+	if len(names) > lokiMaxLabelNames {
+		return fmt.Errorf("loki streams have %d labels, more than the %d Loki accepts", len(names), lokiMaxLabelNames)
+	}
+	return nil
+}
+
+// createDefaultLokiConfig creates the default format, labels and limits
+func createDefaultLokiConfig() LokiConfig {
+	return LokiConfig{
+		Format:              lokiFormatProtobuf,
+		LabelAttributes:     []string{"service.name", "event.category"},
+		Labels:              map[string]string{"job": "security-events"},
+		MaxLabelValues:      100,
+		MaxLabelValueLength: 1024,
+	}
+}
+
+// lokiLabelName returns the label name of an attribute
+func lokiLabelName(attribute string) string {
+	name := []byte(attribute)
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || (c >= '0' && c <= '9' && i > 0)) {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// lokiStream is a stream of a push request with the indexes of its events
+type lokiStream struct {
+	labels  map[string]string
+	key     string
+	entries []int
+}
+
+// lokiTransport pushes events to Loki, one line per event in streams grouped by label attributes
+type lokiTransport struct {
+	exp    *securityEventExporter
+	config *LokiConfig
+	target string
+
+	mu       sync.Mutex
+	seen     map[string]map[string]struct{}
+	overflow map[string]bool
+}
+
+// newLokiTransport creates the Loki transport of e
+func newLokiTransport(e *securityEventExporter) (*lokiTransport, error) {
+	cfg := &e.config.Loki
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	target, err := url.JoinPath(e.config.Endpoint, lokiPushPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	return &lokiTransport{
+		exp:      e,
+		config:   cfg,
+		target:   target,
+		seen:     make(map[string]map[string]struct{}, len(cfg.LabelAttributes)),
+		overflow: make(map[string]bool),
+	}, nil
+}
+
+// start has nothing to open; push requests use the exporter's HTTP client
+func (t *lokiTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send pushes the batch, one request per tenant. When a tenant's push fails, the events of the
+// other tenants are not sent again.
+func (t *lokiTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	lines := make([]string, len(events))
+	for i, event := range events {
+		line, err := t.line(event)
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(err)
+		}
+		lines[i] = line
+	}
+
+	var tenants []string
+	byTenant := make(map[string][]int)
+	for i := range events {
+		tenant := t.config.TenantID.resolve(sources[i].attributes)
+		if _, ok := byTenant[tenant]; !ok {
+			tenants = append(tenants, tenant)
+		}
+		byTenant[tenant] = append(byTenant[tenant], i)
+	}
+
+	var retry, dropped []int
+	var retryErr, droppedErr error
+	for _, tenant := range tenants {
+		indexes := byTenant[tenant]
+		if err := t.push(ctx, tenant, t.streams(indexes, sources), lines, sources); err != nil {
+			if len(tenants) == 1 {
+				return err
+			}
+			t.exp.logger.Warn("Failed to push security events to Loki tenant",
+				zap.String("tenant", tenant),
+				zap.Int("event_count", len(indexes)),
+				zap.Error(err))
+			if consumererror.IsPermanent(err) {
+				dropped, droppedErr = append(dropped, indexes...), err
+			} else {
+				retry, retryErr = append(retry, indexes...), err
+			}
+		}
+	}
+
+	switch {
+	case len(retry) > 0:
+		return &partialSendError{err: retryErr, failed: retry, dropped: dropped}
+	case len(dropped) > 0:
+		return newPartialSendError(droppedErr, dropped)
+	default:
+		return nil
+	}
+}
+
+// line returns the log line of an event: the rendered line of line-oriented encodings and the
+// event JSON otherwise
+func (t *lokiTransport) line(event map[string]interface{}) (string, error) {
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		return lines.renderLine(event)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal security event: %w", err)
+	}
+	return string(data), nil
+}
+
+// streams groups the events at indexes into streams, in order of first appearance, with the
+// entries of each stream ordered by time
+func (t *lokiTransport) streams(indexes []int, sources []eventSource) []*lokiStream {
+	var streams []*lokiStream
+	byKey := make(map[string]*lokiStream)
+	for _, i := range indexes {
+		labels := t.labels(sources[i].attributes)
+		key := lokiLabelString(labels)
+		stream, ok := byKey[key]
+		if !ok {
+			stream = &lokiStream{labels: labels, key: key}
+			byKey[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, i)
+	}
+	for _, stream := range streams {
+		sort.SliceStable(stream.entries, func(a, b int) bool {
+			return sources[stream.entries[a]].time.Before(sources[stream.entries[b]].time)
+		})
+	}
+	return streams
+}
+
+// labels returns the stream labels of an event, enforcing the cardinality limits
+func (t *lokiTransport) labels(attributes map[string]interface{}) map[string]string {
+	labels := make(map[string]string, len(t.config.Labels)+len(t.config.LabelAttributes))
+	for name, value := range t.config.Labels {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, attribute := range t.config.LabelAttributes {
+		value := stringField(attributes, attribute)
+		if value == "" {
+			continue
+		}
+		if len(value) > t.config.MaxLabelValueLength {
+			// Cut without a partial UTF-8 sequence at the end
+			value = strings.ToValidUTF8(value[:t.config.MaxLabelValueLength], "")
+		}
+		seen := t.seen[attribute]
+		if seen == nil {
+			seen = make(map[string]struct{})
+			t.seen[attribute] = seen
+		}
+		if _, ok := seen[value]; !ok {
+			if len(seen) >= t.config.MaxLabelValues {
+				if !t.overflow[attribute] {
+					t.overflow[attribute] = true
+					t.exp.logger.Warn("Loki label reached its value limit; further values are replaced",
+						zap.String("attribute", attribute),
+						zap.Int("max_label_values", t.config.MaxLabelValues),
+						zap.String("replacement", lokiOverflowValue))
+				}
+				value = lokiOverflowValue
+			} else {
+				seen[value] = struct{}{}
+			}
+		}
+		labels[lokiLabelName(attribute)] = value
+	}
+	return labels
+}
+
+// push sends the streams of one tenant
+func (t *lokiTransport) push(ctx context.Context, tenant string, streams []*lokiStream, lines []string, sources []eventSource) error {
+	header := http.Header{}
+	var body []byte
+	if t.config.Format == lokiFormatJSON {
+		header.Set("Content-Type", "application/json")
+		var err error
+		if body, err = lokiJSONPushRequest(streams, lines, sources); err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(err)
+		}
+	} else {
+		header.Set("Content-Type", "application/x-protobuf")
+		body = snappy.Encode(nil, lokiProtobufPushRequest(streams, lines, sources))
+	}
+	if tenant != "" {
+		header.Set("X-Scope-OrgID", tenant)
+	}
+
+	count := 0
+	for _, stream := range streams {
+		count += len(stream.entries)
+	}
+	_, err := t.exp.postBatch(ctx, t.target, body, header, count)
+	return err
+}
+
+// shutdown has nothing to close
+func (t *lokiTransport) shutdown(context.Context) error {
+	return nil
+}
+
+// lokiLabelString returns labels in the {name="value", ...} form of push requests, sorted by name
+func lokiLabelString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiJSONPushRequest encodes streams as a JSON push request
+func lokiJSONPushRequest(streams []*lokiStream, lines []string, sources []eventSource) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, 0, len(streams))}
+	for _, stream := range streams {
+		values := make([][2]string, 0, len(stream.entries))
+		for _, i := range stream.entries {
+			values = append(values, [2]string{strconv.FormatInt(sources[i].time.UnixNano(), 10), lines[i]})
+		}
+		request.Streams = append(request.Streams, jsonStream{Stream: stream.labels, Values: values})
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(request); err != nil {
+		return nil, fmt.Errorf("failed to marshal loki push request: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+// lokiProtobufPushRequest encodes streams as a logproto.PushRequest:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func lokiProtobufPushRequest(streams []*lokiStream, lines []string, sources []eventSource) []byte {
+	var request []byte
+	for _, stream := range streams {
+		var message []byte
+		message = protowire.AppendTag(message, 1, protowire.BytesType)
+		message = protowire.AppendString(message, stream.key)
+		for _, i := range stream.entries {
+			var timestamp []byte
+			if seconds := sources[i].time.Unix(); seconds != 0 {
+				timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+				timestamp = protowire.AppendVarint(timestamp, uint64(seconds))
+			}
+			if nanos := sources[i].time.Nanosecond(); nanos != 0 {
+				timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+				timestamp = protowire.AppendVarint(timestamp, uint64(nanos))
+			}
+
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, timestamp)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, lines[i])
+
+			message = protowire.AppendTag(message, 2, protowire.BytesType)
+			message = protowire.AppendBytes(message, entry)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, message)
+	}
+	return request
+}
@@ -0,0 +1,377 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// lokiEntry is a received log line
+type lokiEntry struct {
+	timestamp time.Time
+	line      string
+}
+
+// lokiStub is a minimal Loki push API that decodes protobuf and JSON push requests into streams
+// per tenant and fails the tenants listed in failTenants with their status
+type lokiStub struct {
+	failTenants map[string]int
+
+	mu           sync.Mutex
+	requests     int
+	contentTypes []string
+	streams      map[string]map[string][]lokiEntry
+}
+
+func (s *lokiStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != lokiPushPath {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	tenant := r.Header.Get("X-Scope-OrgID")
+	s.requests++
+	s.contentTypes = append(s.contentTypes, r.Header.Get("Content-Type"))
+	if status, ok := s.failTenants[tenant]; ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	var streams map[string][]lokiEntry
+	if r.Header.Get("Content-Type") == "application/json" {
+		streams, err = decodeLokiJSON(body)
+	} else {
+		streams, err = decodeLokiProtobuf(body)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, err.Error())
+		return
+	}
+	if s.streams == nil {
+		s.streams = make(map[string]map[string][]lokiEntry)
+	}
+	if s.streams[tenant] == nil {
+		s.streams[tenant] = make(map[string][]lokiEntry)
+	}
+	for labels, entries := range streams {
+		s.streams[tenant][labels] = append(s.streams[tenant][labels], entries...)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeLokiJSON decodes a JSON push request, keyed by the label string of each stream
+func decodeLokiJSON(body []byte) (map[string][]lokiEntry, error) {
+	var request struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+	streams := make(map[string][]lokiEntry)
+	for _, stream := range request.Streams {
+		key := lokiLabelString(stream.Stream)
+		for _, value := range stream.Values {
+			nanos, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			streams[key] = append(streams[key], lokiEntry{timestamp: time.Unix(0, nanos).UTC(), line: value[1]})
+		}
+	}
+	return streams, nil
+}
+
+// decodeLokiProtobuf decodes a snappy-compressed logproto.PushRequest
+func decodeLokiProtobuf(body []byte) (map[string][]lokiEntry, error) {
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+	streams := make(map[string][]lokiEntry)
+	err = consumeLokiFields(data, func(_ protowire.Number, stream []byte) error {
+		var labels string
+		var entries []lokiEntry
+		err := consumeLokiFields(stream, func(num protowire.Number, value []byte) error {
+			if num == 1 {
+				labels = string(value)
+				return nil
+			}
+			var entry lokiEntry
+			err := consumeLokiFields(value, func(num protowire.Number, value []byte) error {
+				if num == 2 {
+					entry.line = string(value)
+					return nil
+				}
+				var seconds, nanos uint64
+				for len(value) > 0 {
+					field, _, n := protowire.ConsumeTag(value)
+					value = value[n:]
+					v, n := protowire.ConsumeVarint(value)
+					if n < 0 {
+						return protowire.ParseError(n)
+					}
+					value = value[n:]
+					if field == 1 {
+						seconds = v
+					} else {
+						nanos = v
+					}
+				}
+				entry.timestamp = time.Unix(int64(seconds), int64(nanos)).UTC()
+				return nil
+			})
+			entries = append(entries, entry)
+			return err
+		})
+		streams[labels] = append(streams[labels], entries...)
+		return err
+	})
+	return streams, err
+}
+
+// consumeLokiFields calls fn with each length-delimited field of a protobuf message
+func consumeLokiFields(data []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || typ != protowire.BytesType {
+			return errors.New("unexpected protobuf field")
+		}
+		data = data[n:]
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newLokiTestLogs returns logs with one WARN record per user, with the given event.category and
+// tenant.id, in the namespace payments
+func newLokiTestLogs(records ...[3]string) plog.Logs {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("service.name", "auth")
+	resourceLogs.Resource().Attributes().PutStr("k8s.namespace.name", "payments")
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	for i, fields := range records {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20-i, 0, time.UTC)))
+		record.SetSeverityNumber(plog.SeverityNumberWarn)
+		record.Attributes().PutStr("user.name", fields[0])
+		record.Attributes().PutStr("event.category", fields[1])
+		record.Attributes().PutStr("tenant.id", fields[2])
+	}
+	return logs
+}
+
+// newTestLokiExporter starts an exporter using the Loki transport against a new stub
+func newTestLokiExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, *lokiStub) {
+	t.Helper()
+	stub := &lokiStub{}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	exp := newTestTransportExporter(t, transportLoki, server.URL, configure)
+	return exp, stub
+}
+
+func TestLokiPushProtobufStreams(t *testing.T) {
+	exp, stub := newTestLokiExporter(t, func(cfg *Config) {
+		cfg.Loki.LabelAttributes = []string{"k8s.namespace.name", "event.category"}
+	})
+
+	logs := newLokiTestLogs(
+		[3]string{"alice", "authentication", ""},
+		[3]string{"bob", "network", ""},
+		[3]string{"carol", "authentication", ""},
+	)
+	if err := exp.ConsumeLogs(context.Background(), logs); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.requests != 1 || stub.contentTypes[0] != "application/x-protobuf" {
+		t.Fatalf("Expected 1 protobuf request, got %d with %v", stub.requests, stub.contentTypes)
+	}
+
+	streams := stub.streams[""]
+	if len(streams) != 2 {
+		t.Fatalf("Expected 2 streams, got %v", streams)
+	}
+	auth := streams[`{event_category="authentication", job="security-events", k8s_namespace_name="payments"}`]
+	if len(auth) != 2 {
+		t.Fatalf("Expected 2 authentication entries, got %v", streams)
+	}
+	// Entries are ordered by time; carol's record is older than alice's
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(auth[0].line), &event); err != nil {
+		t.Fatalf("Line is not the event JSON: %v", err)
+	}
+	if event["user.name"] != "carol" || !auth[0].timestamp.Equal(time.Date(2023, 11, 14, 22, 13, 18, 0, time.UTC)) {
+		t.Errorf("Unexpected first entry: %v at %v", event, auth[0].timestamp)
+	}
+}
+
+func TestLokiPushJSONWithTenant(t *testing.T) {
+	exp, stub := newTestLokiExporter(t, func(cfg *Config) {
+		cfg.Loki.Format = lokiFormatJSON
+		cfg.Loki.TenantID = FieldSource{Value: "security"}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.contentTypes[0] != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", stub.contentTypes[0])
+	}
+	entries := stub.streams["security"][`{job="security-events", service_name="auth"}`]
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries for tenant security, got %v", stub.streams)
+	}
+	if !strings.Contains(entries[0].line, `"user.name":"alice"`) {
+		t.Errorf("Unexpected line %q", entries[0].line)
+	}
+}
+
+func TestLokiRetriesOnlyFailedTenants(t *testing.T) {
+	exp, stub := newTestLokiExporter(t, func(cfg *Config) {
+		cfg.Loki.TenantID = FieldSource{Attributes: []string{"tenant.id"}}
+	})
+	stub.failTenants = map[string]int{"globex": http.StatusTooManyRequests}
+
+	logs := newLokiTestLogs(
+		[3]string{"alice", "authentication", "acme"},
+		[3]string{"bob", "authentication", "globex"},
+		[3]string{"carol", "authentication", "acme"},
+	)
+	err := exp.ConsumeLogs(context.Background(), logs)
+	var logsErr consumererror.Logs
+	if err == nil || consumererror.IsPermanent(err) || !errors.As(err, &logsErr) {
+		t.Fatalf("Expected retryable consumererror.Logs, got %v", err)
+	}
+	retry := logsErr.Data()
+	if retry.LogRecordCount() != 1 {
+		t.Fatalf("Expected 1 record to retry, got %d", retry.LogRecordCount())
+	}
+	if user, _ := retry.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("user.name"); user.Str() != "bob" {
+		t.Errorf("Expected bob to be retried, got %q", user.Str())
+	}
+	if stub.requests != 2 {
+		t.Errorf("Expected one request per tenant, got %d", stub.requests)
+	}
+	acme := 0
+	for _, entries := range stub.streams["acme"] {
+		acme += len(entries)
+	}
+	if acme != 2 {
+		t.Errorf("Expected 2 entries for tenant acme, got %d", acme)
+	}
+}
+
+func TestLokiLabelCardinalityLimits(t *testing.T) {
+	exp, stub := newTestLokiExporter(t, func(cfg *Config) {
+		cfg.Loki.LabelAttributes = []string{"user.name"}
+		cfg.Loki.Labels = map[string]string{"job": ""}
+		cfg.Loki.MaxLabelValues = 2
+		cfg.Loki.MaxLabelValueLength = 3
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	streams := stub.streams[""]
+	counts := map[string]int{}
+	for labels, entries := range streams {
+		counts[labels] = len(entries)
+	}
+	expected := map[string]int{
+		`{user_name="ali"}`:       2,
+		`{user_name="bob"}`:       1,
+		`{user_name="_overflow"}`: 1,
+	}
+	for labels, want := range expected {
+		if counts[labels] != want {
+			t.Errorf("stream %s has %d entries, want %d (streams %v)", labels, counts[labels], want, counts)
+		}
+	}
+}
+
+func TestLokiConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *LokiConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*LokiConfig) {}},
+		{name: "invalid format", modify: func(cfg *LokiConfig) {
+			cfg.Format = "logfmt"
+		}, errorMsg: "invalid loki format"},
+		{name: "invalid static label", modify: func(cfg *LokiConfig) {
+			cfg.Labels = map[string]string{"app-name": "x"}
+		}, errorMsg: "invalid loki label name"},
+		{name: "duplicate label", modify: func(cfg *LokiConfig) {
+			cfg.LabelAttributes = []string{"service.name", "service_name"}
+		}, errorMsg: "is defined twice"},
+		{name: "too many labels", modify: func(cfg *LokiConfig) {
+			cfg.LabelAttributes = nil
+			for i := 0; i < lokiMaxLabelNames; i++ {
+				cfg.LabelAttributes = append(cfg.LabelAttributes, "attribute."+strconv.Itoa(i))
+			}
+		}, errorMsg: "more than the 15 Loki accepts"},
+		{name: "zero max label values", modify: func(cfg *LokiConfig) {
+			cfg.MaxLabelValues = 0
+		}, errorMsg: "max_label_values must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultLokiConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestLokiLabelName(t *testing.T) {
+	tests := map[string]string{
+		"k8s.namespace.name": "k8s_namespace_name",
+		"event.category":     "event_category",
+		"1st-label":          "_st_label",
+	}
+	for attribute, want := range tests {
+		if got := lokiLabelName(attribute); got != want {
+			t.Errorf("lokiLabelName(%q) = %q, want %q", attribute, got, want)
+		}
+	}
+}
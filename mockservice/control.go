@@ -0,0 +1,190 @@
+package mockservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// newControlMux builds the control API used to inspect the server and inject faults at runtime:
+//
+//	GET    /_mock/batches                      accepted batches
+//	GET    /_mock/events                       events of all accepted batches
+//	GET    /_mock/stats                        request counters
+//	POST   /_mock/reset                        drop batches, pending failures and counters
+//	GET    /_mock/faults                       current faults
+//	PUT    /_mock/faults                       replace faults
+//	POST   /_mock/fail-next?status=429&count=2 fail the next requests (status 0 resets the connection)
+//	GET    /_mock/schema                       current schema
+//	PUT    /_mock/schema                       replace schema
+func (s *Server) newControlMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+ControlPathPrefix+"batches", func(w http.ResponseWriter, r *http.Request) {
+		batches := s.Batches()
+		if batches == nil {
+			batches = []Batch{}
+		}
+		writeJSON(w, http.StatusOK, batches)
+	})
+
+	mux.HandleFunc("GET "+ControlPathPrefix+"events", func(w http.ResponseWriter, r *http.Request) {
+		events := s.Events()
+		if events == nil {
+			events = []map[string]interface{}{}
+		}
+		writeJSON(w, http.StatusOK, events)
+	})
+
+	mux.HandleFunc("GET "+ControlPathPrefix+"stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Stats())
+	})
+
+	mux.HandleFunc("POST "+ControlPathPrefix+"reset", func(w http.ResponseWriter, r *http.Request) {
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET "+ControlPathPrefix+"faults", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		faults := s.faults
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, faults)
+	})
+
+	mux.HandleFunc("PUT "+ControlPathPrefix+"faults", func(w http.ResponseWriter, r *http.Request) {
+		var faults Faults
+		if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.SetFaults(faults)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST "+ControlPathPrefix+"fail-next", func(w http.ResponseWriter, r *http.Request) {
+		status, err := strconv.Atoi(r.URL.Query().Get("status"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "status must be an integer")
+			return
+		}
+		count := 1
+		if raw := r.URL.Query().Get("count"); raw != "" {
+			if count, err = strconv.Atoi(raw); err != nil || count < 1 {
+				writeError(w, http.StatusBadRequest, "count must be a positive integer")
+				return
+			}
+		}
+		s.FailNext(status, count)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET "+ControlPathPrefix+"schema", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		schema := s.schema
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, schema)
+	})
+
+	mux.HandleFunc("PUT "+ControlPathPrefix+"schema", func(w http.ResponseWriter, r *http.Request) {
+		var schema Schema
+		if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.schema = schema
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// faultsJSON is the wire form of Faults, with durations as Go duration strings such as "250ms"
+type faultsJSON struct {
+	ConnectionResetRate float64 `json:"connection_reset_rate,omitempty"`
+	PayloadTooLargeRate float64 `json:"payload_too_large_rate,omitempty"`
+	TooManyRequestsRate float64 `json:"too_many_requests_rate,omitempty"`
+	ServerErrorRate     float64 `json:"server_error_rate,omitempty"`
+	RetryAfter          string  `json:"retry_after,omitempty"`
+	Latency             string  `json:"latency,omitempty"`
+	LatencyJitter       string  `json:"latency_jitter,omitempty"`
+}
+
+// MarshalJSON encodes durations as duration strings
+func (f Faults) MarshalJSON() ([]byte, error) {
+	return json.Marshal(faultsJSON{
+		ConnectionResetRate: f.ConnectionResetRate,
+		PayloadTooLargeRate: f.PayloadTooLargeRate,
+		TooManyRequestsRate: f.TooManyRequestsRate,
+		ServerErrorRate:     f.ServerErrorRate,
+		RetryAfter:          formatDuration(f.RetryAfter),
+		Latency:             formatDuration(f.Latency),
+		LatencyJitter:       formatDuration(f.LatencyJitter),
+	})
+}
+
+// UnmarshalJSON decodes durations from duration strings and validates the rates
+func (f *Faults) UnmarshalJSON(data []byte) error {
+	var raw faultsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	decoded := Faults{
+		ConnectionResetRate: raw.ConnectionResetRate,
+		PayloadTooLargeRate: raw.PayloadTooLargeRate,
+		TooManyRequestsRate: raw.TooManyRequestsRate,
+		ServerErrorRate:     raw.ServerErrorRate,
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"retry_after", raw.RetryAfter, &decoded.RetryAfter},
+		{"latency", raw.Latency, &decoded.Latency},
+		{"latency_jitter", raw.LatencyJitter, &decoded.LatencyJitter},
+	} {
+		if field.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(field.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field.name, err)
+		}
+		*field.dst = duration
+	}
+
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+	*f = decoded
+	return nil
+}
+
+// Validate checks that the rates are probabilities that sum to at most 1
+func (f Faults) Validate() error {
+	total := 0.0
+	for _, rate := range []float64{f.ConnectionResetRate, f.PayloadTooLargeRate, f.TooManyRequestsRate, f.ServerErrorRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("fault rates must be between 0 and 1, got %v", rate)
+		}
+		total += rate
+	}
+	if total > 1 {
+		return fmt.Errorf("fault rates must sum to at most 1, got %v", total)
+	}
+	return nil
+}
+
+// formatDuration formats positive durations and omits the rest
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
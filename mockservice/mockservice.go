@@ -0,0 +1,399 @@
+// Package mockservice provides an in-process security event receiver for integration tests.
+//
+// A Server accepts the batches sent by the securityevent exporter, validates them against a
+// configurable Schema and stores them so tests can assert on what was delivered. Faults such as
+// 429/500/413 responses, added latency and connection resets can be injected on demand to
+// reproduce retry and queue behavior.
+package mockservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlPathPrefix is the path prefix of the control API served next to the ingest endpoint
+const ControlPathPrefix = "/_mock/"
+
+// Schema describes what a valid batch looks like
+type Schema struct {
+	// RequiredFields are fields every event must contain
+	RequiredFields []string `json:"required_fields,omitempty"`
+
+	// FieldTypes maps field names to their expected JSON type: string, number, boolean, object or array
+	FieldTypes map[string]string `json:"field_types,omitempty"`
+
+	// MaxBatchSize rejects batches with more events with 413 (0 means unlimited)
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+
+	// MaxBodyBytes rejects larger request bodies with 413 (0 means unlimited)
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+}
+
+// Faults configures failures injected into otherwise valid requests. Rates are probabilities
+// between 0 and 1 and are evaluated in the order connection reset, 413, 429, 500.
+type Faults struct {
+	// ConnectionResetRate is the rate of requests whose connection is reset without a response
+	ConnectionResetRate float64 `json:"connection_reset_rate,omitempty"`
+
+	// PayloadTooLargeRate is the rate of requests answered with 413
+	PayloadTooLargeRate float64 `json:"payload_too_large_rate,omitempty"`
+
+	// TooManyRequestsRate is the rate of requests answered with 429
+	TooManyRequestsRate float64 `json:"too_many_requests_rate,omitempty"`
+
+	// ServerErrorRate is the rate of requests answered with 500
+	ServerErrorRate float64 `json:"server_error_rate,omitempty"`
+
+	// RetryAfter is sent as the Retry-After header on 429 responses when positive
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+
+	// Latency delays every response
+	Latency time.Duration `json:"latency,omitempty"`
+
+	// LatencyJitter adds a random delay of up to this duration on top of Latency
+	LatencyJitter time.Duration `json:"latency_jitter,omitempty"`
+}
+
+// Config configures a Server
+type Config struct {
+	Schema Schema `json:"schema"`
+	Faults Faults `json:"faults"`
+
+	// Seed seeds fault injection so failure sequences are reproducible (0 uses the current time)
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// Batch is a batch accepted by the server
+type Batch struct {
+	Received time.Time                `json:"received"`
+	Path     string                   `json:"path"`
+	Headers  http.Header              `json:"headers"`
+	Events   []map[string]interface{} `json:"events"`
+}
+
+// Stats counts the requests handled by the server
+type Stats struct {
+	Requests         int64 `json:"requests"`
+	Accepted         int64 `json:"accepted"`
+	EventsAccepted   int64 `json:"events_accepted"`
+	Invalid          int64 `json:"invalid"`
+	InjectedFailures int64 `json:"injected_failures"`
+	ConnectionResets int64 `json:"connection_resets"`
+}
+
+// Server is an http.Handler that receives security event batches
+type Server struct {
+	mu       sync.Mutex
+	schema   Schema
+	faults   Faults
+	rand     *rand.Rand
+	failNext []int
+	batches  []Batch
+	stats    Stats
+	control  *http.ServeMux
+}
+
+// New creates a Server from cfg
+func New(cfg Config) *Server {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	s := &Server{
+		schema: cfg.Schema,
+		faults: cfg.Faults,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+	s.control = s.newControlMux()
+	return s
+}
+
+// ServeHTTP serves the control API under ControlPathPrefix and accepts batches on every other path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, ControlPathPrefix) {
+		s.control.ServeHTTP(w, r)
+		return
+	}
+	s.ingest(w, r)
+}
+
+// SetFaults replaces the injected faults
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+}
+
+// FailNext makes the next n requests fail with status, before rate-based faults are evaluated.
+// A status of 0 resets the connection instead of responding.
+func (s *Server) FailNext(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failNext = append(s.failNext, status)
+	}
+}
+
+// Batches returns a copy of the accepted batches in arrival order
+func (s *Server) Batches() []Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Batch(nil), s.batches...)
+}
+
+// Events returns the events of all accepted batches in arrival order
+func (s *Server) Events() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []map[string]interface{}
+	for _, batch := range s.batches {
+		events = append(events, batch.Events...)
+	}
+	return events
+}
+
+// Stats returns the request counters
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Reset drops the stored batches, pending FailNext failures and counters
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = nil
+	s.failNext = nil
+	s.stats = Stats{}
+}
+
+// ingest handles a batch: faults are injected first, then the body is validated and stored
+func (s *Server) ingest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "only POST is supported")
+		return
+	}
+
+	s.mu.Lock()
+	s.stats.Requests++
+	status, inject := s.nextFault()
+	delay := s.delay()
+	retryAfter := s.faults.RetryAfter
+	schema := s.schema
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if inject {
+		s.count(func(stats *Stats) { stats.InjectedFailures++ })
+		if status == 0 {
+			s.count(func(stats *Stats) { stats.ConnectionResets++ })
+			resetConnection(w)
+			return
+		}
+		if status == http.StatusTooManyRequests && retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second)/time.Second)))
+		}
+		writeError(w, status, "injected failure")
+		return
+	}
+
+	body, err := readBody(r, schema.MaxBodyBytes)
+	if err != nil {
+		s.count(func(stats *Stats) { stats.Invalid++ })
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+
+	events, err := decodeBatch(body)
+	if err != nil {
+		s.count(func(stats *Stats) { stats.Invalid++ })
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if schema.MaxBatchSize > 0 && len(events) > schema.MaxBatchSize {
+		s.count(func(stats *Stats) { stats.Invalid++ })
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("batch of %d events exceeds max_batch_size %d", len(events), schema.MaxBatchSize))
+		return
+	}
+
+	if err := schema.Validate(events); err != nil {
+		s.count(func(stats *Stats) { stats.Invalid++ })
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, Batch{
+		Received: time.Now(),
+		Path:     r.URL.Path,
+		Headers:  r.Header.Clone(),
+		Events:   events,
+	})
+	s.stats.Accepted++
+	s.stats.EventsAccepted += int64(len(events))
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"accepted": len(events),
+	})
+}
+
+// nextFault picks the fault for the current request; callers must hold s.mu
+func (s *Server) nextFault() (int, bool) {
+	if len(s.failNext) > 0 {
+		status := s.failNext[0]
+		s.failNext = s.failNext[1:]
+		return status, true
+	}
+
+	roll := s.rand.Float64()
+	for _, fault := range []struct {
+		rate   float64
+		status int
+	}{
+		{s.faults.ConnectionResetRate, 0},
+		{s.faults.PayloadTooLargeRate, http.StatusRequestEntityTooLarge},
+		{s.faults.TooManyRequestsRate, http.StatusTooManyRequests},
+		{s.faults.ServerErrorRate, http.StatusInternalServerError},
+	} {
+		if roll < fault.rate {
+			return fault.status, true
+		}
+		roll -= fault.rate
+	}
+	return 0, false
+}
+
+// delay returns the latency for the current request; callers must hold s.mu
+func (s *Server) delay() time.Duration {
+	delay := s.faults.Latency
+	if s.faults.LatencyJitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(s.faults.LatencyJitter)))
+	}
+	return delay
+}
+
+// count updates the counters under the lock
+func (s *Server) count(update func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+// Validate checks events against the schema
+func (schema Schema) Validate(events []map[string]interface{}) error {
+	for i, event := range events {
+		for _, field := range schema.RequiredFields {
+			if _, ok := event[field]; !ok {
+				return fmt.Errorf("event %d: missing required field %q", i, field)
+			}
+		}
+		for field, want := range schema.FieldTypes {
+			value, ok := event[field]
+			if !ok {
+				continue
+			}
+			if got := jsonType(value); got != want {
+				return fmt.Errorf("event %d: field %q is %s, expected %s", i, field, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// readBody reads the request body, enforcing maxBytes when positive
+func readBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	reader := io.Reader(r.Body)
+	if maxBytes > 0 {
+		reader = io.LimitReader(r.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("body exceeds max_body_bytes %d", maxBytes)
+	}
+	return body, nil
+}
+
+// decodeBatch decodes a JSON array of events
+func decodeBatch(body []byte) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("body is not a JSON array of events: %w", err)
+	}
+	return events, nil
+}
+
+// resetConnection closes the client connection without writing a response
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		// A zero linger makes Close send a RST instead of a FIN
+		_ = tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"status":  "error",
+		"message": message,
+	})
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
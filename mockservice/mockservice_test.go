@@ -0,0 +1,156 @@
+package mockservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, url, body string) (*http.Response, error) {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestServerStoresValidBatches(t *testing.T) {
+	mock := New(Config{
+		Schema: Schema{
+			RequiredFields: []string{"timestamp"},
+			FieldTypes:     map[string]string{"user.id": "string"},
+		},
+	})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	resp, err := post(t, server.URL+"/events", `[{"timestamp":"2024-01-01T00:00:00Z","user.id":"alice"},{"timestamp":"2024-01-01T00:00:01Z"}]`)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	events := mock.Events()
+	if len(events) != 2 || events[0]["user.id"] != "alice" {
+		t.Errorf("Unexpected stored events: %v", events)
+	}
+	if batches := mock.Batches(); len(batches) != 1 || batches[0].Path != "/events" {
+		t.Errorf("Unexpected stored batches: %v", batches)
+	}
+
+	mock.Reset()
+	if len(mock.Events()) != 0 || mock.Stats().Requests != 0 {
+		t.Error("Reset() should drop events and counters")
+	}
+}
+
+func TestServerRejectsInvalidBatches(t *testing.T) {
+	mock := New(Config{
+		Schema: Schema{
+			RequiredFields: []string{"timestamp"},
+			FieldTypes:     map[string]string{"user.id": "string"},
+			MaxBatchSize:   1,
+		},
+	})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "not an array", body: `{"timestamp":"now"}`, status: http.StatusBadRequest},
+		{name: "missing field", body: `[{"user.id":"alice"}]`, status: http.StatusBadRequest},
+		{name: "wrong type", body: `[{"timestamp":"now","user.id":42}]`, status: http.StatusBadRequest},
+		{name: "batch too large", body: `[{"timestamp":"a"},{"timestamp":"b"}]`, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := post(t, server.URL, tt.body)
+			if err != nil {
+				t.Fatalf("POST failed: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	if stats := mock.Stats(); stats.Invalid != int64(len(tests)) || stats.Accepted != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestServerInjectsFaults(t *testing.T) {
+	mock := New(Config{Seed: 1})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	mock.FailNext(http.StatusTooManyRequests, 1)
+	mock.FailNext(0, 1)
+
+	resp, err := post(t, server.URL, `[]`)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected injected 429, got %v %v", resp, err)
+	}
+
+	if _, err := post(t, server.URL, `[]`); err == nil {
+		t.Fatal("Expected connection reset")
+	}
+
+	mock.SetFaults(Faults{ServerErrorRate: 1, Latency: 20 * time.Millisecond})
+	start := time.Now()
+	resp, err = post(t, server.URL, `[]`)
+	if err != nil || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected injected 500, got %v %v", resp, err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("Expected injected latency")
+	}
+
+	if stats := mock.Stats(); stats.InjectedFailures != 3 || stats.ConnectionResets != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestControlAPI(t *testing.T) {
+	mock := New(Config{})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+ControlPathPrefix+"faults",
+		strings.NewReader(`{"payload_too_large_rate":1,"latency":"1ms"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("PUT faults failed: %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = post(t, server.URL+"/events", `[]`)
+	if err != nil || resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected injected 413, got %v %v", resp, err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, server.URL+ControlPathPrefix+"faults",
+		strings.NewReader(`{"server_error_rate":0.8,"too_many_requests_rate":0.5}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected rates summing above 1 to be rejected, got %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = post(t, server.URL+ControlPathPrefix+"fail-next?status=500&count=2", ``)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("POST fail-next failed: %v %v", resp, err)
+	}
+	if pending := len(mock.failNext); pending != 2 {
+		t.Errorf("Expected 2 pending failures, got %d", pending)
+	}
+}
@@ -0,0 +1,407 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// OCSFConfig configures the OCSF encoding
+type OCSFConfig struct {
+	// SchemaVersion is reported as metadata.version
+	SchemaVersion string `mapstructure:"schema_version"`
+
+	// Product is reported as metadata.product
+	Product OCSFProductConfig `mapstructure:"product"`
+
+	// Rules map log records to OCSF classes. The first matching rule wins; when no rules are
+	// configured a built-in set keyed on event.category, event.kind and common semantic
+	// convention attributes is used. Records matching no rule become Base Events.
+	Rules []OCSFRule `mapstructure:"rules"`
+
+	// Observables maps attribute keys to OCSF observable types (for example ip_address or
+	// user_name), extending the built-in semantic convention mapping
+	Observables map[string]string `mapstructure:"observables"`
+}
+
+// OCSFProductConfig describes the product reported in metadata.product
+type OCSFProductConfig struct {
+	Name       string `mapstructure:"name"`
+	VendorName string `mapstructure:"vendor_name"`
+	Version    string `mapstructure:"version"`
+}
+
+// OCSFRule maps matching log records to an OCSF class and activity
+type OCSFRule struct {
+	// Class is the OCSF class, for example authentication, api_activity or detection_finding
+	Class string `mapstructure:"class"`
+
+	// Match lists attribute values that must all be equal for the rule to apply. A value of "*"
+	// only requires the attribute to be present. An empty Match matches every record.
+	Match map[string]string `mapstructure:"match"`
+
+	// ActivityAttribute names the attribute whose value selects the activity, for example event.action
+	ActivityAttribute string `mapstructure:"activity_attribute"`
+
+	// Activity is the activity used when ActivityAttribute is unset or its value is not a known activity
+	Activity string `mapstructure:"activity"`
+}
+
+// ocsfClass describes an OCSF event class and its activities
+type ocsfClass struct {
+	uid          int
+	name         string
+	categoryUID  int
+	categoryName string
+	activities   map[string]int
+}
+
+// ocsfClasses are the supported OCSF 1.x classes, keyed by the names used in configuration
+var ocsfClasses = map[string]ocsfClass{
+	"base_event": {uid: 0, name: "Base Event", categoryUID: 0, categoryName: "Uncategorized"},
+	"file_activity": {uid: 1001, name: "File System Activity", categoryUID: 1, categoryName: "System Activity",
+		activities: map[string]int{"create": 1, "read": 2, "update": 3, "delete": 4, "rename": 5, "set_attributes": 6, "set_security": 7, "get_attributes": 8, "get_security": 9, "encrypt": 10, "decrypt": 11, "mount": 12, "unmount": 13, "open": 14}},
+	"process_activity": {uid: 1007, name: "Process Activity", categoryUID: 1, categoryName: "System Activity",
+		activities: map[string]int{"launch": 1, "terminate": 2, "open": 3, "inject": 4, "set_user_id": 5}},
+	"detection_finding": {uid: 2004, name: "Detection Finding", categoryUID: 2, categoryName: "Findings",
+		activities: map[string]int{"create": 1, "update": 2, "close": 3}},
+	"account_change": {uid: 3001, name: "Account Change", categoryUID: 3, categoryName: "Identity & Access Management",
+		activities: map[string]int{"create": 1, "enable": 2, "password_change": 3, "password_reset": 4, "disable": 5, "delete": 6, "attach_policy": 7, "detach_policy": 8, "lock": 9, "mfa_factor_enable": 10, "mfa_factor_disable": 11}},
+	"authentication": {uid: 3002, name: "Authentication", categoryUID: 3, categoryName: "Identity & Access Management",
+		activities: map[string]int{"logon": 1, "logoff": 2, "authentication_ticket": 3, "service_ticket_request": 4, "service_ticket_renew": 5, "preauth": 6}},
+	"authorize_session": {uid: 3003, name: "Authorize Session", categoryUID: 3, categoryName: "Identity & Access Management",
+		activities: map[string]int{"assign_privileges": 1, "assign_groups": 2}},
+	"network_activity": {uid: 4001, name: "Network Activity", categoryUID: 4, categoryName: "Network Activity",
+		activities: map[string]int{"open": 1, "close": 2, "reset": 3, "fail": 4, "refuse": 5, "traffic": 6, "listen": 7}},
+	"http_activity": {uid: 4002, name: "HTTP Activity", categoryUID: 4, categoryName: "Network Activity",
+		activities: map[string]int{"connect": 1, "delete": 2, "get": 3, "head": 4, "options": 5, "post": 6, "put": 7, "trace": 8}},
+	"dns_activity": {uid: 4003, name: "DNS Activity", categoryUID: 4, categoryName: "Network Activity",
+		activities: map[string]int{"query": 1, "response": 2, "traffic": 6}},
+	"web_resources_activity": {uid: 6001, name: "Web Resources Activity", categoryUID: 6, categoryName: "Application Activity",
+		activities: map[string]int{"create": 1, "read": 2, "update": 3, "delete": 4, "search": 5, "import": 6, "export": 7, "share": 8}},
+	"api_activity": {uid: 6003, name: "API Activity", categoryUID: 6, categoryName: "Application Activity",
+		activities: map[string]int{"create": 1, "read": 2, "update": 3, "delete": 4}},
+}
+
+// ocsfActivityAliases maps common attribute values onto OCSF activity names
+var ocsfActivityAliases = map[string]string{
+	"login":   "logon",
+	"log_in":  "logon",
+	"sign_in": "logon",
+	"signin":  "logon",
+	"logout":  "logoff",
+	"log_out": "logoff",
+	"signout": "logoff",
+	"get":     "read",
+	"post":    "create",
+	"put":     "update",
+	"patch":   "update",
+	"start":   "launch",
+	"exec":    "launch",
+	"kill":    "terminate",
+	"stop":    "terminate",
+}
+
+// defaultOCSFRules are used when no rules are configured
+var defaultOCSFRules = []OCSFRule{
+	{Class: "detection_finding", Match: map[string]string{"event.kind": "alert"}, Activity: "create"},
+	{Class: "authentication", Match: map[string]string{"event.category": "authentication"}, ActivityAttribute: "event.action"},
+	{Class: "account_change", Match: map[string]string{"event.category": "iam"}, ActivityAttribute: "event.action"},
+	{Class: "file_activity", Match: map[string]string{"event.category": "file"}, ActivityAttribute: "event.action"},
+	{Class: "process_activity", Match: map[string]string{"event.category": "process"}, ActivityAttribute: "event.action"},
+	{Class: "dns_activity", Match: map[string]string{"event.category": "dns"}, ActivityAttribute: "event.action"},
+	{Class: "network_activity", Match: map[string]string{"event.category": "network"}, ActivityAttribute: "event.action"},
+	{Class: "http_activity", Match: map[string]string{"http.request.method": "*"}, ActivityAttribute: "http.request.method"},
+	{Class: "api_activity", Match: map[string]string{"rpc.method": "*"}, ActivityAttribute: "event.action"},
+}
+
+// ocsfObservableTypes maps observable type names to OCSF type_id values
+var ocsfObservableTypes = map[string]int{
+	"hostname":      1,
+	"ip_address":    2,
+	"mac_address":   3,
+	"user_name":     4,
+	"email_address": 5,
+	"url_string":    6,
+	"file_name":     7,
+	"hash":          8,
+	"process_name":  9,
+	"resource_uid":  10,
+	"port":          11,
+	"subnet":        12,
+	"command_line":  13,
+	"country":       14,
+	"process_id":    15,
+	"user_agent":    16,
+	"other":         99,
+}
+
+// defaultOCSFObservables maps semantic convention attributes to observable types
+var defaultOCSFObservables = map[string]string{
+	"client.address":          "ip_address",
+	"source.address":          "ip_address",
+	"source.ip":               "ip_address",
+	"destination.address":     "ip_address",
+	"destination.ip":          "ip_address",
+	"network.peer.address":    "ip_address",
+	"host.name":               "hostname",
+	"host.ip":                 "ip_address",
+	"host.mac":                "mac_address",
+	"user.name":               "user_name",
+	"enduser.id":              "user_name",
+	"user.email":              "email_address",
+	"url.full":                "url_string",
+	"user_agent.original":     "user_agent",
+	"file.name":               "file_name",
+	"file.path":               "file_name",
+	"process.executable.name": "process_name",
+	"process.pid":             "process_id",
+	"process.command_line":    "command_line",
+	"k8s.pod.uid":             "resource_uid",
+}
+
+// ocsfSeverityIDs maps normalized severity levels to OCSF severity_id values
+var ocsfSeverityIDs = map[severityLevel]int{
+	severityUnknown:       0,
+	severityInformational: 1,
+	severityLow:           2,
+	severityMedium:        3,
+	severityHigh:          4,
+	severityCritical:      5,
+}
+
+// Validate checks class, activity and observable names
+func (cfg *OCSFConfig) Validate() error {
+	var errs []error
+	for i, rule := range cfg.Rules {
+		class, ok := ocsfClasses[rule.Class]
+		if !ok {
+			errs = append(errs, fmt.Errorf("ocsf rule %d: unknown class %q", i, rule.Class))
+			continue
+		}
+		if rule.Activity != "" {
+			if _, ok := class.activity(rule.Activity); !ok {
+				errs = append(errs, fmt.Errorf("ocsf rule %d: unknown activity %q for class %q", i, rule.Activity, rule.Class))
+			}
+		}
+	}
+	for attribute, observableType := range cfg.Observables {
+		if _, ok := ocsfObservableTypes[observableType]; !ok {
+			errs = append(errs, fmt.Errorf("ocsf observable %q: unknown type %q", attribute, observableType))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// activity resolves an activity name or alias to its activity_id and canonical name
+func (c ocsfClass) activity(value string) (string, bool) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	name = strings.NewReplacer("-", "_", " ", "_").Replace(name)
+	if _, ok := c.activities[name]; ok {
+		return name, true
+	}
+	if alias, ok := ocsfActivityAliases[name]; ok {
+		if _, ok := c.activities[alias]; ok {
+			return alias, true
+		}
+	}
+	return "", false
+}
+
+// ocsfEncoder maps flat security events to OCSF class events
+type ocsfEncoder struct {
+	config      *OCSFConfig
+	rules       []OCSFRule
+	observables map[string]string
+	// observableKeys are the observable attribute keys in a stable order
+	observableKeys []string
+}
+
+// newOCSFEncoder creates an OCSF encoder from cfg
+func newOCSFEncoder(cfg *OCSFConfig) (*ocsfEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = defaultOCSFRules
+	}
+
+	observables := make(map[string]string, len(defaultOCSFObservables)+len(cfg.Observables))
+	for attribute, observableType := range defaultOCSFObservables {
+		observables[attribute] = observableType
+	}
+	for attribute, observableType := range cfg.Observables {
+		observables[attribute] = observableType
+	}
+	keys := make([]string, 0, len(observables))
+	for attribute := range observables {
+		keys = append(keys, attribute)
+	}
+	sort.Strings(keys)
+
+	return &ocsfEncoder{
+		config:         cfg,
+		rules:          rules,
+		observables:    observables,
+		observableKeys: keys,
+	}, nil
+}
+
+// encode builds the OCSF event for one record
+func (enc *ocsfEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	className, activityName := enc.classify(event)
+	class := ocsfClasses[className]
+
+	activityID := 0
+	if activityName != "" {
+		activityID = class.activities[activityName]
+	} else if className != "base_event" {
+		// A known class with an unrecognized activity is reported as Other
+		activityID = 99
+		activityName = "other"
+	}
+
+	level := severityLevelOf(severityNumber(logRecord))
+	ocsfEvent := map[string]interface{}{
+		"class_uid":     class.uid,
+		"class_name":    class.name,
+		"category_uid":  class.categoryUID,
+		"category_name": class.categoryName,
+		"activity_id":   activityID,
+		"activity_name": ocsfDisplayName(activityName),
+		"type_uid":      class.uid*100 + activityID,
+		"type_name":     fmt.Sprintf("%s: %s", class.name, ocsfDisplayName(activityName)),
+		"severity_id":   ocsfSeverityIDs[level],
+		"severity":      level.String(),
+		"time":          eventTime(logRecord).UnixMilli(),
+		"metadata":      enc.metadata(event),
+	}
+
+	switch strings.ToLower(stringField(event, "event.outcome")) {
+	case "success":
+		ocsfEvent["status_id"] = 1
+		ocsfEvent["status"] = "Success"
+	case "failure":
+		ocsfEvent["status_id"] = 2
+		ocsfEvent["status"] = "Failure"
+	}
+
+	if observables := enc.observablesFor(event); len(observables) > 0 {
+		ocsfEvent["observables"] = observables
+	}
+
+	// Everything that came from attributes is kept under unmapped, where OCSF expects
+	// source data that has no dedicated field
+	if unmapped := eventAttributes(event); len(unmapped) > 0 {
+		ocsfEvent["unmapped"] = unmapped
+	}
+
+	return ocsfEvent, nil
+}
+
+// classify returns the class and activity of the first rule matching event
+func (enc *ocsfEncoder) classify(event map[string]interface{}) (string, string) {
+	for _, rule := range enc.rules {
+		if !matchesRule(event, rule.Match) {
+			continue
+		}
+		class := ocsfClasses[rule.Class]
+		if rule.ActivityAttribute != "" {
+			if activity, ok := class.activity(stringField(event, rule.ActivityAttribute)); ok {
+				return rule.Class, activity
+			}
+		}
+		if rule.Activity != "" {
+			activity, _ := class.activity(rule.Activity)
+			return rule.Class, activity
+		}
+		return rule.Class, ""
+	}
+	return "base_event", ""
+}
+
+// metadata builds the metadata object
+func (enc *ocsfEncoder) metadata(event map[string]interface{}) map[string]interface{} {
+	product := map[string]interface{}{
+		"name":        defaultString(enc.config.Product.Name, "OpenTelemetry Collector"),
+		"vendor_name": defaultString(enc.config.Product.VendorName, "OpenTelemetry"),
+	}
+	if enc.config.Product.Version != "" {
+		product["version"] = enc.config.Product.Version
+	}
+
+	metadata := map[string]interface{}{
+		"product": product,
+		"version": defaultString(enc.config.SchemaVersion, "1.3.0"),
+	}
+	if traceID := stringField(event, "trace_id"); traceID != "" {
+		metadata["correlation_uid"] = traceID
+	}
+	return metadata
+}
+
+// observablesFor lists the observables present in event
+func (enc *ocsfEncoder) observablesFor(event map[string]interface{}) []interface{} {
+	var observables []interface{}
+	for _, attribute := range enc.observableKeys {
+		value := stringField(event, attribute)
+		if value == "" {
+			continue
+		}
+		observableType := enc.observables[attribute]
+		observables = append(observables, map[string]interface{}{
+			"name":    attribute,
+			"type":    ocsfDisplayName(observableType),
+			"type_id": ocsfObservableTypes[observableType],
+			"value":   value,
+		})
+	}
+	return observables
+}
+
+// matchesRule reports whether every match condition holds for event
+func matchesRule(event map[string]interface{}, match map[string]string) bool {
+	for key, want := range match {
+		got, ok := event[key]
+		if !ok {
+			return false
+		}
+		if want != "*" && !strings.EqualFold(fmt.Sprintf("%v", got), want) {
+			return false
+		}
+	}
+	return true
+}
+
+// ocsfDisplayName turns a configuration name such as user_name into its display form, User Name
+func ocsfDisplayName(name string) string {
+	if name == "" {
+		return "Unknown"
+	}
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		switch word {
+		case "":
+			continue
+		case "ip", "url", "uid", "mac", "dns", "http", "api", "mfa":
+			words[i] = strings.ToUpper(word)
+		default:
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// defaultString returns value, or fallback when value is empty
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
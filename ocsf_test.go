@@ -0,0 +1,155 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestOCSFEncoderDefaultRules(t *testing.T) {
+	enc, err := newOCSFEncoder(&OCSFConfig{Product: OCSFProductConfig{Name: "auth-gateway", VendorName: "Acme"}})
+	if err != nil {
+		t.Fatalf("newOCSFEncoder() returned error: %v", err)
+	}
+
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	record.SetSeverityNumber(plog.SeverityNumberWarn)
+
+	event := map[string]interface{}{
+		"event.category": "authentication",
+		"event.action":   "login",
+		"event.outcome":  "failure",
+		"client.address": "10.0.0.7",
+		"user.name":      "alice",
+		"timestamp":      "2023-11-14T22:13:20Z",
+	}
+
+	got, err := enc.encode(event, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"class_uid":     3002,
+		"category_uid":  3,
+		"activity_id":   1,
+		"activity_name": "Logon",
+		"type_uid":      300201,
+		"severity_id":   3,
+		"severity":      "Medium",
+		"status_id":     2,
+		"time":          int64(1700000000000),
+	}
+	for key, want := range expect {
+		if got[key] != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", key, got[key], got[key], want, want)
+		}
+	}
+
+	product := got["metadata"].(map[string]interface{})["product"].(map[string]interface{})
+	if product["name"] != "auth-gateway" || product["vendor_name"] != "Acme" {
+		t.Errorf("Unexpected metadata.product: %v", product)
+	}
+
+	observables := got["observables"].([]interface{})
+	if len(observables) != 2 {
+		t.Fatalf("Expected 2 observables, got %v", observables)
+	}
+	first := observables[0].(map[string]interface{})
+	if first["name"] != "client.address" || first["type_id"] != 2 || first["value"] != "10.0.0.7" {
+		t.Errorf("Unexpected observable: %v", first)
+	}
+
+	unmapped := got["unmapped"].(map[string]interface{})
+	if unmapped["user.name"] != "alice" {
+		t.Errorf("Expected attributes under unmapped, got %v", unmapped)
+	}
+	if _, ok := unmapped["timestamp"]; ok {
+		t.Error("Converter fields should not be repeated under unmapped")
+	}
+}
+
+func TestOCSFEncoderConfiguredRules(t *testing.T) {
+	enc, err := newOCSFEncoder(&OCSFConfig{
+		Rules: []OCSFRule{
+			{Class: "api_activity", Match: map[string]string{"service.name": "billing-api"}, ActivityAttribute: "http.request.method"},
+			{Class: "detection_finding", Match: map[string]string{"rule.id": "*"}, Activity: "create"},
+		},
+		Observables: map[string]string{"tenant.id": "resource_uid"},
+	})
+	if err != nil {
+		t.Fatalf("newOCSFEncoder() returned error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		event      map[string]interface{}
+		classUID   int
+		activityID int
+	}{
+		{
+			name:       "api activity with aliased method",
+			event:      map[string]interface{}{"service.name": "billing-api", "http.request.method": "PATCH"},
+			classUID:   6003,
+			activityID: 3,
+		},
+		{
+			name:       "api activity with unknown method",
+			event:      map[string]interface{}{"service.name": "billing-api", "http.request.method": "CONNECT"},
+			classUID:   6003,
+			activityID: 99,
+		},
+		{
+			name:       "detection finding on attribute presence",
+			event:      map[string]interface{}{"rule.id": "R-1", "tenant.id": "t-1"},
+			classUID:   2004,
+			activityID: 1,
+		},
+		{
+			name:       "no rule matches",
+			event:      map[string]interface{}{"service.name": "other"},
+			classUID:   0,
+			activityID: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enc.encode(tt.event, plog.NewLogRecord(), pcommon.NewResource())
+			if err != nil {
+				t.Fatalf("encode() returned error: %v", err)
+			}
+			if got["class_uid"] != tt.classUID || got["activity_id"] != tt.activityID {
+				t.Errorf("class_uid=%v activity_id=%v, want %d/%d", got["class_uid"], got["activity_id"], tt.classUID, tt.activityID)
+			}
+			if got["type_uid"] != tt.classUID*100+tt.activityID {
+				t.Errorf("type_uid=%v, want %d", got["type_uid"], tt.classUID*100+tt.activityID)
+			}
+		})
+	}
+}
+
+func TestOCSFConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  OCSFConfig
+		wantErr bool
+	}{
+		{name: "empty", config: OCSFConfig{}},
+		{name: "valid rule", config: OCSFConfig{Rules: []OCSFRule{{Class: "authentication", Activity: "logoff"}}}},
+		{name: "unknown class", config: OCSFConfig{Rules: []OCSFRule{{Class: "login"}}}, wantErr: true},
+		{name: "unknown activity", config: OCSFConfig{Rules: []OCSFRule{{Class: "authentication", Activity: "read"}}}, wantErr: true},
+		{name: "unknown observable type", config: OCSFConfig{Observables: map[string]string{"user.id": "user"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
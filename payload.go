@@ -0,0 +1,214 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// payloadFormatJSONArray posts each batch as one JSON array of events
+	payloadFormatJSONArray = "json_array"
+
+	// payloadFormatNDJSON posts each batch as newline-delimited JSON, one event per line
+	payloadFormatNDJSON = "ndjson"
+
+	// payloadFormatJSONObjectWrapper posts each batch as a JSON object holding the event array
+	payloadFormatJSONObjectWrapper = "json_object_wrapper"
+
+	// payloadFormatProtobuf posts each batch as a securityevent.v1.SecurityEventBatch message,
+	// defined in proto/securityevent/v1/security_event.proto
+	payloadFormatProtobuf = "protobuf"
+)
+
+// supportedPayloadFormats lists the accepted values of the payload_format setting
+var supportedPayloadFormats = []string{payloadFormatJSONArray, payloadFormatNDJSON, payloadFormatJSONObjectWrapper, payloadFormatProtobuf}
+
+// validatePayloadFormat checks the payload format. Formats other than json_array only apply to
+// the http transport and to encodings that produce JSON events.
+func (cfg *Config) validatePayloadFormat() error {
+	switch cfg.PayloadFormat {
+	case "", payloadFormatJSONArray:
+		return nil
+	case payloadFormatNDJSON, payloadFormatProtobuf:
+	case payloadFormatJSONObjectWrapper:
+		if cfg.PayloadWrapperKey == "" {
+			return errors.New("payload_wrapper_key is required for the json_object_wrapper payload format")
+		}
+	default:
+		return fmt.Errorf("invalid payload_format %q: must be one of %s", cfg.PayloadFormat, strings.Join(supportedPayloadFormats, ", "))
+	}
+	if cfg.Transport != "" && cfg.Transport != transportHTTP {
+		return fmt.Errorf("payload_format %q is only supported by the %q transport", cfg.PayloadFormat, transportHTTP)
+	}
+	if cfg.Encoding == encodingCEF || cfg.Encoding == encodingLEEF {
+		return fmt.Errorf("payload_format %q is not supported by the line-oriented %q encoding", cfg.PayloadFormat, cfg.Encoding)
+	}
+	return nil
+}
+
+// marshalPayload serializes a batch of JSON events in the configured payload format and returns
+// the matching content type
+func (e *securityEventExporter) marshalPayload(securityEvents []map[string]interface{}) ([]byte, string, error) {
+	switch e.config.PayloadFormat {
+	case payloadFormatNDJSON:
+		var buf bytes.Buffer
+		for _, event := range securityEvents {
+			line, err := json.Marshal(event)
+			if err != nil {
+				return nil, "", err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	case payloadFormatJSONObjectWrapper:
+		data, err := json.Marshal(map[string]interface{}{e.config.PayloadWrapperKey: securityEvents})
+		return data, "application/json", err
+	case payloadFormatProtobuf:
+		data, err := marshalProtobufBatch(securityEvents)
+		return data, "application/x-protobuf", err
+	default:
+		data, err := json.Marshal(securityEvents)
+		return data, "application/json", err
+	}
+}
+
+// marshalProtobufBatch encodes events as a SecurityEventBatch message. Attributes are written in
+// key order, so equal batches always encode to the same bytes.
+func marshalProtobufBatch(securityEvents []map[string]interface{}) ([]byte, error) {
+	var batch []byte
+	for _, event := range securityEvents {
+		message, err := appendProtobufKeyValues(nil, 1, event)
+		if err != nil {
+			return nil, err
+		}
+		batch = protowire.AppendTag(batch, 1, protowire.BytesType)
+		batch = protowire.AppendBytes(batch, message)
+	}
+	return batch, nil
+}
+
+// appendProtobufKeyValues appends the entries of values as KeyValue messages in field num
+func appendProtobufKeyValues(b []byte, num protowire.Number, values map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := appendProtobufValue(nil, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", key, err)
+		}
+		var keyValue []byte
+		keyValue = protowire.AppendTag(keyValue, 1, protowire.BytesType)
+		keyValue = protowire.AppendString(keyValue, key)
+		keyValue = protowire.AppendTag(keyValue, 2, protowire.BytesType)
+		keyValue = protowire.AppendBytes(keyValue, value)
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, keyValue)
+	}
+	return b, nil
+}
+
+// appendProtobufValue appends the fields of an AnyValue message holding value. A nil value has no
+// field set. Types without a direct counterpart are converted through their JSON representation.
+func appendProtobufValue(b []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return b, nil
+	case string:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		return protowire.AppendString(b, v), nil
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v)), nil
+	case int:
+		return appendProtobufInt(b, int64(v)), nil
+	case int32:
+		return appendProtobufInt(b, int64(v)), nil
+	case int64:
+		return appendProtobufInt(b, v), nil
+	case uint32:
+		return appendProtobufInt(b, int64(v)), nil
+	case float64:
+		return appendProtobufDouble(b, v), nil
+	case float32:
+		return appendProtobufDouble(b, float64(v)), nil
+	case []byte:
+		b = protowire.AppendTag(b, 7, protowire.BytesType)
+		return protowire.AppendBytes(b, v), nil
+	case []interface{}:
+		var array []byte
+		for _, item := range v {
+			element, err := appendProtobufValue(nil, item)
+			if err != nil {
+				return nil, err
+			}
+			array = protowire.AppendTag(array, 1, protowire.BytesType)
+			array = protowire.AppendBytes(array, element)
+		}
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		return protowire.AppendBytes(b, array), nil
+	case map[string]interface{}:
+		list, err := appendProtobufKeyValues(nil, 1, v)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		return protowire.AppendBytes(b, list), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var generic interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&generic); err != nil {
+			return nil, err
+		}
+		return appendProtobufValue(b, normalizeJSONNumbers(generic))
+	}
+}
+
+// appendProtobufInt appends an AnyValue int_value field
+func appendProtobufInt(b []byte, v int64) []byte {
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+// appendProtobufDouble appends an AnyValue double_value field
+func appendProtobufDouble(b []byte, v float64) []byte {
+	b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+// normalizeJSONNumbers replaces the json.Number values of a decoded JSON value with int64 when
+// they are integers and float64 otherwise
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,304 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeProtobufBatch decodes a SecurityEventBatch into JSON-like events
+func decodeProtobufBatch(data []byte) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	err := consumeLokiFields(data, func(_ protowire.Number, message []byte) error {
+		event, err := decodeProtobufKeyValues(message)
+		events = append(events, event)
+		return err
+	})
+	return events, err
+}
+
+// decodeProtobufKeyValues decodes the repeated KeyValue field of a message into a map
+func decodeProtobufKeyValues(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	err := consumeLokiFields(data, func(_ protowire.Number, keyValue []byte) error {
+		var key string
+		var value interface{}
+		err := consumeLokiFields(keyValue, func(num protowire.Number, field []byte) error {
+			if num == 1 {
+				key = string(field)
+				return nil
+			}
+			var err error
+			value, err = decodeProtobufValue(field)
+			return err
+		})
+		values[key] = value
+		return err
+	})
+	return values, err
+}
+
+// decodeProtobufValue decodes an AnyValue message
+func decodeProtobufValue(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	num, typ, n := protowire.ConsumeTag(data)
+	if n < 0 {
+		return nil, protowire.ParseError(n)
+	}
+	data = data[n:]
+	switch typ {
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		if num == 2 {
+			return protowire.DecodeBool(v), nil
+		}
+		return int64(v), nil
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		return math.Float64frombits(v), nil
+	case protowire.BytesType:
+		v, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		switch num {
+		case 1:
+			return string(v), nil
+		case 5:
+			array := []interface{}{}
+			err := consumeLokiFields(v, func(_ protowire.Number, element []byte) error {
+				item, err := decodeProtobufValue(element)
+				array = append(array, item)
+				return err
+			})
+			return array, err
+		case 6:
+			return decodeProtobufKeyValues(v)
+		case 7:
+			return v, nil
+		}
+	}
+	return nil, errors.New("unexpected AnyValue field")
+}
+
+func TestMarshalProtobufBatch(t *testing.T) {
+	events := []map[string]interface{}{
+		{
+			"user.name":   "alice",
+			"empty":       "",
+			"success":     false,
+			"port":        int64(-22),
+			"class_uid":   3002,
+			"risk":        7.5,
+			"raw":         []byte{0x00, 0xff},
+			"missing":     nil,
+			"tags":        []interface{}{"auth", int64(1)},
+			"actor":       map[string]interface{}{"user": map[string]interface{}{"name": "alice"}},
+			"observables": []map[string]interface{}{{"type_id": 4}},
+		},
+		{"user.name": "bob"},
+	}
+
+	data, err := marshalProtobufBatch(events)
+	if err != nil {
+		t.Fatalf("marshalProtobufBatch() returned error: %v", err)
+	}
+	decoded, err := decodeProtobufBatch(data)
+	if err != nil {
+		t.Fatalf("Failed to decode batch: %v", err)
+	}
+	want := []map[string]interface{}{
+		{
+			"user.name":   "alice",
+			"empty":       "",
+			"success":     false,
+			"port":        int64(-22),
+			"class_uid":   int64(3002),
+			"risk":        7.5,
+			"raw":         []byte{0x00, 0xff},
+			"missing":     nil,
+			"tags":        []interface{}{"auth", int64(1)},
+			"actor":       map[string]interface{}{"user": map[string]interface{}{"name": "alice"}},
+			"observables": []interface{}{map[string]interface{}{"type_id": int64(4)}},
+		},
+		{"user.name": "bob"},
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("Decoded batch = %v, want %v", decoded, want)
+	}
+
+	again, err := marshalProtobufBatch(events)
+	if err != nil || !bytes.Equal(again, data) {
+		t.Errorf("Expected the same bytes for the same batch")
+	}
+}
+
+func TestPayloadFormats(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		decode      func(t *testing.T, body []byte) []map[string]interface{}
+	}{
+		{format: payloadFormatJSONArray, contentType: "application/json", decode: func(t *testing.T, body []byte) []map[string]interface{} {
+			var events []map[string]interface{}
+			if err := json.Unmarshal(body, &events); err != nil {
+				t.Fatalf("Body is not a JSON array: %v", err)
+			}
+			return events
+		}},
+		{format: payloadFormatNDJSON, contentType: "application/x-ndjson", decode: func(t *testing.T, body []byte) []map[string]interface{} {
+			if !bytes.HasSuffix(body, []byte("\n")) {
+				t.Errorf("Expected a trailing newline, got %q", body)
+			}
+			var events []map[string]interface{}
+			for _, line := range strings.Split(strings.TrimSuffix(string(body), "\n"), "\n") {
+				var event map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatalf("Line %q is not JSON: %v", line, err)
+				}
+				events = append(events, event)
+			}
+			return events
+		}},
+		{format: payloadFormatJSONObjectWrapper, contentType: "application/json", decode: func(t *testing.T, body []byte) []map[string]interface{} {
+			var wrapper map[string][]map[string]interface{}
+			if err := json.Unmarshal(body, &wrapper); err != nil {
+				t.Fatalf("Body is not a JSON object: %v", err)
+			}
+			if len(wrapper) != 1 {
+				t.Errorf("Expected only the records key, got %s", body)
+			}
+			return wrapper["records"]
+		}},
+		{format: payloadFormatProtobuf, contentType: "application/x-protobuf", decode: func(t *testing.T, body []byte) []map[string]interface{} {
+			events, err := decodeProtobufBatch(body)
+			if err != nil {
+				t.Fatalf("Body is not a SecurityEventBatch: %v", err)
+			}
+			return events
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var contentType string
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			exp := newTestTransportExporter(t, transportHTTP, server.URL, func(cfg *Config) {
+				cfg.PayloadFormat = tt.format
+				cfg.PayloadWrapperKey = "records"
+			})
+
+			if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+				t.Fatalf("ConsumeLogs() returned error: %v", err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.contentType)
+			}
+			events := tt.decode(t, body)
+			if len(events) != 2 || events[0]["user.name"] != "alice" || events[1]["service.name"] != "auth" {
+				t.Errorf("Unexpected events: %v", events)
+			}
+		})
+	}
+}
+
+func TestPayloadFormatProtobufDryRun(t *testing.T) {
+	var out strings.Builder
+	exp := newTestTransportExporter(t, transportHTTP, "http://127.0.0.1:1/security-events", func(cfg *Config) {
+		cfg.Mode = modeDryRun
+		cfg.PayloadFormat = payloadFormatProtobuf
+	})
+	_ = exp.dryRun.close()
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var request dryRunRequest
+	if err := json.Unmarshal([]byte(out.String()), &request); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	encoded, _ := request.Body.(string)
+	if request.BodyEncoding != "base64" || request.Headers["Content-Type"] != "application/x-protobuf" {
+		t.Fatalf("Expected a base64 protobuf body, got %s", out.String())
+	}
+	body, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("Body is not base64: %v", err)
+	}
+	if events, err := decodeProtobufBatch(body); err != nil || len(events) != 1 || events[0]["user.name"] != "alice" {
+		t.Errorf("Unexpected dry-run batch %v: %v", events, err)
+	}
+}
+
+func TestPayloadFormatValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		errorMsg string
+	}{
+		{name: "default", modify: func(*Config) {}},
+		{name: "protobuf with ocsf", modify: func(cfg *Config) {
+			cfg.PayloadFormat = payloadFormatProtobuf
+			cfg.Encoding = encodingOCSF
+		}},
+		{name: "invalid format", modify: func(cfg *Config) {
+			cfg.PayloadFormat = "msgpack"
+		}, errorMsg: "invalid payload_format"},
+		{name: "missing wrapper key", modify: func(cfg *Config) {
+			cfg.PayloadFormat = payloadFormatJSONObjectWrapper
+			cfg.PayloadWrapperKey = ""
+		}, errorMsg: "payload_wrapper_key is required"},
+		{name: "other transport", modify: func(cfg *Config) {
+			cfg.PayloadFormat = payloadFormatNDJSON
+			cfg.Transport = transportLoki
+		}, errorMsg: "only supported by the \"http\" transport"},
+		{name: "line encoding", modify: func(cfg *Config) {
+			cfg.PayloadFormat = payloadFormatNDJSON
+			cfg.Encoding = encodingCEF
+		}, errorMsg: "not supported by the line-oriented \"cef\" encoding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Endpoint = "https://siem.example.com/security-events"
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+const (
+	// redactionMask replaces the whole value of a key, or a detected value, with a fixed marker
+	redactionMask = "mask"
+
+	// redactionRemove removes the attribute from the event
+	redactionRemove = "remove"
+
+	// redactionHash replaces the value with its SHA-256 digest, keyed with hash_key when set, so
+	// equal values can still be correlated
+	redactionHash = "hash"
+
+	// redactionPartial masks all but the last four characters of the value
+	redactionPartial = "partial"
+
+	// redactedValue is what the mask action writes
+	redactedValue = "[REDACTED]"
+
+	// keyMatchExact matches keys equal to one of the rule keys
+	keyMatchExact = "exact"
+
+	// keyMatchGlob matches keys against shell patterns with *, ? and [...]
+	keyMatchGlob = "glob"
+
+	// keyMatchRegex matches keys against regular expressions
+	keyMatchRegex = "regex"
+)
+
+// valueDetector finds sensitive values inside strings
+type valueDetector struct {
+	pattern *regexp.Regexp
+	// valid confirms a match, such as the Luhn checksum of a card number; nil accepts every match
+	valid func(match string) bool
+}
+
+// redactionDetectors are the built-in value detectors
+var redactionDetectors = map[string]valueDetector{
+	"credit_card": {
+		pattern: regexp.MustCompile(`\b[2-6](?:[ -]?\d){12,18}\b`),
+		valid:   luhnValid,
+	},
+	"jwt": {
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	},
+	"aws_access_key": {
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA|AIDA|AROA)[A-Z0-9]{16}\b`),
+	},
+	"email": {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+}
+
+// RedactionConfig configures the redaction of sensitive fields in the converted security events
+// before they are encoded and sent
+type RedactionConfig struct {
+	// Rules are applied to every event in order
+	Rules []RedactionRule `mapstructure:"rules"`
+
+	// HashKey keys the SHA-256 digests of the hash action with HMAC, so the digests of guessable
+	// values such as card numbers cannot be reversed by brute force
+	HashKey configopaque.String `mapstructure:"hash_key"`
+}
+
+// RedactionRule selects sensitive fields by key, by value or both, and how they are redacted
+type RedactionRule struct {
+	// Name identifies the rule in the redaction counts
+	Name string `mapstructure:"name"`
+
+	// Keys select attributes by key, including the keys of nested objects. Without detectors or
+	// patterns, the whole value of a selected attribute is redacted.
+	Keys []string `mapstructure:"keys"`
+
+	// KeyMatch is how Keys are matched: "exact" (default), "glob" or "regex"
+	KeyMatch string `mapstructure:"key_match"`
+
+	// Detectors are built-in value detectors: "credit_card" (checked with Luhn), "jwt",
+	// "aws_access_key" and "email". Detected values are redacted within the string values of the
+	// selected keys, or of every attribute without Keys.
+	Detectors []string `mapstructure:"detectors"`
+
+	// Patterns are custom regular expressions detecting values like Detectors. When a pattern has
+	// a capturing group, only the text of the first group is redacted.
+	Patterns []string `mapstructure:"patterns"`
+
+	// Action is how matches are redacted: "mask" (default), "remove", "hash" or "partial". Remove
+	// deletes the whole attribute, also when only a detected value matched.
+	Action string `mapstructure:"action"`
+}
+
+// Validate checks the redaction rules
+func (cfg *RedactionConfig) Validate() error {
+	var errs []error
+	names := make(map[string]bool, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("redaction rule %d: name is required", i))
+		} else if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("redaction rule %q: duplicate name", rule.Name))
+		}
+		names[rule.Name] = true
+		if _, err := compileRedactionRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("redaction rule %q: %w", rule.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// redactionRule is a compiled RedactionRule
+type redactionRule struct {
+	name       string
+	matchesKey func(key string) bool
+	detectors  []valueDetector
+	action     string
+}
+
+// compileRedactionRule compiles the key matcher and value detectors of rule
+func compileRedactionRule(rule RedactionRule) (redactionRule, error) {
+	compiled := redactionRule{name: rule.Name, action: rule.Action}
+	switch compiled.action {
+	case "":
+		compiled.action = redactionMask
+	case redactionMask, redactionRemove, redactionHash, redactionPartial:
+	default:
+		return compiled, fmt.Errorf("invalid action %q: must be %q, %q, %q or %q", rule.Action, redactionMask, redactionRemove, redactionHash, redactionPartial)
+	}
+	if len(rule.Keys) == 0 && len(rule.Detectors) == 0 && len(rule.Patterns) == 0 {
+		return compiled, errors.New("keys, detectors or patterns are required")
+	}
+
+	if len(rule.Keys) > 0 {
+		switch rule.KeyMatch {
+		case "", keyMatchExact:
+			keys := make(map[string]bool, len(rule.Keys))
+			for _, key := range rule.Keys {
+				keys[key] = true
+			}
+			compiled.matchesKey = func(key string) bool { return keys[key] }
+		case keyMatchGlob:
+			for _, pattern := range rule.Keys {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return compiled, fmt.Errorf("invalid key glob %q: %w", pattern, err)
+				}
+			}
+			globs := rule.Keys
+			compiled.matchesKey = func(key string) bool {
+				for _, glob := range globs {
+					if matched, _ := path.Match(glob, key); matched {
+						return true
+					}
+				}
+				return false
+			}
+		case keyMatchRegex:
+			patterns := make([]*regexp.Regexp, 0, len(rule.Keys))
+			for _, key := range rule.Keys {
+				pattern, err := regexp.Compile(key)
+				if err != nil {
+					return compiled, fmt.Errorf("invalid key regex %q: %w", key, err)
+				}
+				patterns = append(patterns, pattern)
+			}
+			compiled.matchesKey = func(key string) bool {
+				for _, pattern := range patterns {
+					if pattern.MatchString(key) {
+						return true
+					}
+				}
+				return false
+			}
+		default:
+			return compiled, fmt.Errorf("invalid key_match %q: must be %q, %q or %q", rule.KeyMatch, keyMatchExact, keyMatchGlob, keyMatchRegex)
+		}
+	}
+
+	for _, name := range rule.Detectors {
+		detector, ok := redactionDetectors[name]
+		if !ok {
+			return compiled, fmt.Errorf("unknown detector %q", name)
+		}
+		compiled.detectors = append(compiled.detectors, detector)
+	}
+	for _, pattern := range rule.Patterns {
+		compiledPattern, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if compiledPattern.MatchString("") {
+			return compiled, fmt.Errorf("invalid pattern %q: matches the empty string", pattern)
+		}
+		compiled.detectors = append(compiled.detectors, valueDetector{pattern: compiledPattern})
+	}
+	return compiled, nil
+}
+
+// redactor applies the redaction rules to events and to logged text, and counts the redactions
+// of each rule in the events. Events are counted each time they are converted, so the events of a
+// retried batch are counted again.
+type redactor struct {
+	rules   []redactionRule
+	hashKey []byte
+	counts  []atomic.Int64
+}
+
+// newRedactor creates the redactor of cfg. It returns nil when no rules are configured.
+func newRedactor(cfg *RedactionConfig) (*redactor, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &redactor{
+		rules:   make([]redactionRule, 0, len(cfg.Rules)),
+		hashKey: []byte(cfg.HashKey),
+		counts:  make([]atomic.Int64, len(cfg.Rules)),
+	}
+	for _, rule := range cfg.Rules {
+		compiled, _ := compileRedactionRule(rule)
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// redactEvent redacts the attributes of a converted security event in place
+func (r *redactor) redactEvent(event map[string]interface{}) {
+	if r != nil {
+		r.redactObject(event, true)
+	}
+}
+
+// redactionCounts returns the number of redactions each rule made in events
+func (r *redactor) redactionCounts() map[string]int64 {
+	counts := make(map[string]int64, len(r.rules))
+	for i, rule := range r.rules {
+		counts[rule.name] = r.counts[i].Load()
+	}
+	return counts
+}
+
+// redactObject applies every rule to the attributes of object, then redacts its nested values.
+// Redactions are counted when count is set.
+func (r *redactor) redactObject(object map[string]interface{}, count bool) {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		for key, value := range object {
+			if rule.matchesKey != nil && !rule.matchesKey(key) {
+				continue
+			}
+			redacted, matches := r.redactValue(rule, value)
+			if matches == 0 {
+				continue
+			}
+			if count {
+				r.counts[i].Add(int64(matches))
+			}
+			if rule.action == redactionRemove {
+				delete(object, key)
+				continue
+			}
+			object[key] = redacted
+		}
+	}
+	for key, value := range object {
+		object[key] = r.redactNested(value, count)
+	}
+}
+
+// redactNested redacts the objects and strings nested in arrays and objects
+func (r *redactor) redactNested(value interface{}, count bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		r.redactObject(v, count)
+	case []interface{}:
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				v[i] = r.redactString(s, count)
+				continue
+			}
+			v[i] = r.redactNested(item, count)
+		}
+	}
+	return value
+}
+
+// redactValue applies rule to the value of a key it selects, returning the redacted value and the
+// number of matches. Rules without detectors match the whole value; the others only match
+// within string values.
+func (r *redactor) redactValue(rule *redactionRule, value interface{}) (interface{}, int) {
+	if len(rule.detectors) == 0 {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			// Objects and arrays are masked whatever the action, except remove
+			return redactedValue, 1
+		}
+		return r.redactMatch(rule.action, fmt.Sprint(value)), 1
+	}
+	s, ok := value.(string)
+	if !ok {
+		return value, 0
+	}
+	return r.replaceDetected(rule, s)
+}
+
+// redactString applies the rules selecting values regardless of their key to s, which is not an
+// attribute that could be removed, such as an array element or logged text
+func (r *redactor) redactString(s string, count bool) string {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.matchesKey != nil || len(rule.detectors) == 0 {
+			continue
+		}
+		redacted, matches := r.replaceDetected(rule, s)
+		if matches == 0 {
+			continue
+		}
+		if count {
+			r.counts[i].Add(int64(matches))
+		}
+		s = redacted
+	}
+	return s
+}
+
+// replaceDetected redacts the values the detectors of rule find in s and returns the number of
+// matches
+func (r *redactor) replaceDetected(rule *redactionRule, s string) (string, int) {
+	matches := 0
+	for _, detector := range rule.detectors {
+		locations := detector.pattern.FindAllStringSubmatchIndex(s, -1)
+		if len(locations) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, location := range locations {
+			// Only the first group of patterns with capturing groups is redacted
+			start, end := location[0], location[1]
+			if len(location) > 2 {
+				if location[2] < 0 {
+					continue
+				}
+				start, end = location[2], location[3]
+			}
+			if start == end {
+				// An empty group has no value to redact
+				continue
+			}
+			match := s[start:end]
+			if detector.valid != nil && !detector.valid(match) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.redactMatch(rule.action, match))
+			last = end
+			matches++
+		}
+		if last > 0 {
+			b.WriteString(s[last:])
+			s = b.String()
+		}
+	}
+	return s, matches
+}
+
+// redactMatch returns the replacement of a matched value. The remove action, which deletes whole
+// attributes, masks values in logged text.
+func (r *redactor) redactMatch(action, value string) string {
+	switch action {
+	case redactionHash:
+		var sum []byte
+		if len(r.hashKey) > 0 {
+			mac := hmac.New(sha256.New, r.hashKey)
+			mac.Write([]byte(value))
+			sum = mac.Sum(nil)
+		} else {
+			digest := sha256.Sum256([]byte(value))
+			sum = digest[:]
+		}
+		return "sha256:" + hex.EncodeToString(sum)
+	case redactionPartial:
+		n := utf8.RuneCountInString(value)
+		if n <= 4 {
+			return strings.Repeat("*", n)
+		}
+		_, tail := splitLastRunes(value, 4)
+		return strings.Repeat("*", n-4) + tail
+	default:
+		return redactedValue
+	}
+}
+
+// splitLastRunes splits s before its last n runes
+func splitLastRunes(s string, n int) (string, string) {
+	i := len(s)
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return s[:i], s[i:]
+}
+
+// redactText redacts text written to the logs, such as request previews and response bodies.
+// JSON documents are redacted like events, key rules included; in other text only the rules
+// detecting values regardless of their key apply. Redactions in logs are not counted.
+func (r *redactor) redactText(text string) string {
+	if r == nil {
+		return text
+	}
+	var document interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err == nil && !decoder.More() {
+		switch v := document.(type) {
+		case map[string]interface{}, []interface{}:
+			if data, err := json.Marshal(r.redactNested(v, false)); err == nil {
+				return string(data)
+			}
+		}
+	}
+	return r.redactString(text, false)
+}
+
+// redactLogValue redacts the value of attribute key for the logs
+func (r *redactor) redactLogValue(key, value string) string {
+	if r == nil {
+		return value
+	}
+	object := map[string]interface{}{key: value}
+	r.redactObject(object, false)
+	redacted, ok := object[key].(string)
+	if !ok {
+		return redactedValue
+	}
+	return redacted
+}
+
+// luhnValid reports whether the digits of a card number match have a valid Luhn checksum
+func luhnValid(match string) bool {
+	sum, digits := 0, 0
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
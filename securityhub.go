@@ -0,0 +1,216 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+const (
+	// securityHubImportPath is the BatchImportFindings method, relative to the endpoint
+	securityHubImportPath = "/findings/import"
+
+	// securityHubMaxFindings is the maximum number of findings of one BatchImportFindings call
+	securityHubMaxFindings = 100
+
+	// securityHubMaxFinding is the maximum size of one finding
+	securityHubMaxFinding = 240 << 10
+
+	// securityHubService is the SigV4 signing name of Security Hub
+	securityHubService = "securityhub"
+)
+
+// SecurityHubConfig configures the AWS Security Hub transport. The endpoint is the regional
+// Security Hub endpoint, for example https://securityhub.us-east-1.amazonaws.com, and requests are
+// signed with AWS Signature Version 4.
+type SecurityHubConfig struct {
+	AWSCredentialsConfig `mapstructure:",squash"`
+}
+
+// validateDestination checks the settings needed to send. Credentials may also come from the
+// environment, so only the region is required.
+func (cfg *SecurityHubConfig) validateDestination() error {
+	if cfg.Region == "" {
+		return errors.New("security_hub region is required")
+	}
+	return nil
+}
+
+// securityHubResponse is the BatchImportFindings response
+type securityHubResponse struct {
+	FailedCount    int `json:"FailedCount"`
+	SuccessCount   int `json:"SuccessCount"`
+	FailedFindings []struct {
+		ID           string `json:"Id"`
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"FailedFindings"`
+}
+
+// securityHubTransport imports ASFF findings with BatchImportFindings, 100 findings per call.
+// Findings Security Hub rejects are reported as permanent partial failures, so the rest of the
+// batch is not sent again.
+type securityHubTransport struct {
+	exp         *securityEventExporter
+	config      *SecurityHubConfig
+	target      string
+	maxFindings int
+	now         func() time.Time
+}
+
+// newSecurityHubTransport creates the Security Hub transport of e
+func newSecurityHubTransport(e *securityEventExporter) (*securityHubTransport, error) {
+	if _, ok := e.encoder.(*asffEncoder); !ok {
+		return nil, fmt.Errorf("the security_hub transport requires the %q encoding", encodingASFF)
+	}
+	target, err := url.JoinPath(e.config.Endpoint, securityHubImportPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	return &securityHubTransport{
+		exp:         e,
+		config:      &e.config.SecurityHub,
+		target:      target,
+		maxFindings: securityHubMaxFindings,
+		now:         time.Now,
+	}, nil
+}
+
+// start has nothing to prepare; credentials are resolved per batch so rotated environment
+// credentials are picked up
+func (t *securityHubTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send imports the findings in calls of at most 100 findings. Findings that cannot be marshaled
+// or exceed the size limit are dropped, and the other findings are still imported.
+func (t *securityHubTransport) send(ctx context.Context, events []map[string]interface{}, _ []eventSource) error {
+	records := make([]json.RawMessage, 0, len(events))
+	// indexes and ids are the event index and finding Id of each record
+	indexes := make([]int, 0, len(events))
+	ids := make([]string, 0, len(events))
+	var rejected []int
+	var rejectedErr error
+	for i, event := range events {
+		record, err := json.Marshal(event)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("failed to marshal asff finding: %w", err)
+		case len(record) > securityHubMaxFinding:
+			err = fmt.Errorf("asff finding of %d bytes exceeds the %d byte limit", len(record), securityHubMaxFinding)
+		}
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			t.exp.logger.Warn("Dropping security event that cannot be imported as a finding", zap.Error(err))
+			rejected, rejectedErr = append(rejected, i), err
+			continue
+		}
+		id, _ := event["Id"].(string)
+		records, indexes, ids = append(records, record), append(indexes, i), append(ids, id)
+	}
+
+	var creds awsCredentials
+	// Dry runs do not sign; the Authorization header would be masked anyway
+	if len(records) > 0 && !t.exp.config.isDryRun() {
+		var err error
+		if creds, err = t.config.resolve(); err != nil {
+			return consumererror.NewPermanent(err)
+		}
+	}
+
+	sent := 0
+	for sent < len(records) {
+		end := t.callEnd(ids, sent)
+		response, err := t.importFindings(ctx, records[sent:end], creds)
+		if err != nil {
+			// The findings of this and the following calls were not imported
+			if sent == 0 && len(rejected) == 0 {
+				return err
+			}
+			return &partialSendError{err: err, failed: indexes[sent:], dropped: rejected}
+		}
+		// Ids are unique within a call, so each failed finding names one event
+		positions := make(map[string]int, end-sent)
+		for i := sent; i < end; i++ {
+			positions[ids[i]] = indexes[i]
+		}
+		for _, finding := range response.FailedFindings {
+			index, ok := positions[finding.ID]
+			if !ok {
+				continue
+			}
+			t.exp.logger.Warn("Security Hub rejected finding",
+				zap.String("finding_id", finding.ID),
+				zap.String("error_code", finding.ErrorCode),
+				zap.String("error_message", finding.ErrorMessage))
+			rejected = append(rejected, index)
+			rejectedErr = fmt.Errorf("%s: %s", finding.ErrorCode, finding.ErrorMessage)
+		}
+		sent = end
+	}
+
+	if len(rejected) > 0 {
+		return newPartialSendError(consumererror.NewPermanent(fmt.Errorf("security hub rejected findings, last error %w", rejectedErr)), rejected)
+	}
+	return nil
+}
+
+// callEnd returns the end of the call importing the records from start: at most maxFindings
+// findings, and no Id twice, since FailedFindings identifies the rejected findings only by Id.
+// Findings sharing an Id, as id_attributes can produce, are imported in order by separate calls.
+func (t *securityHubTransport) callEnd(ids []string, start int) int {
+	seen := make(map[string]bool, t.maxFindings)
+	end := start
+	for end < len(ids) && end-start < t.maxFindings {
+		if seen[ids[end]] {
+			break
+		}
+		seen[ids[end]] = true
+		end++
+	}
+	return end
+}
+
+// importFindings makes one BatchImportFindings call and returns its response
+func (t *securityHubTransport) importFindings(ctx context.Context, findings []json.RawMessage, creds awsCredentials) (*securityHubResponse, error) {
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		t.exp.metrics.httpErrors.Add(1)
+		return nil, consumererror.NewPermanent(fmt.Errorf("failed to marshal asff findings: %w", err))
+	}
+	body := make([]byte, 0, len(`{"Findings":}`)+len(findingsJSON))
+	body = append(append(append(body, `{"Findings":`...), findingsJSON...), '}')
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if !t.exp.config.isDryRun() {
+		if header, err = sigV4Header(http.MethodPost, t.target, header, body, creds, securityHubService, t.now()); err != nil {
+			return nil, consumererror.NewPermanent(err)
+		}
+	}
+
+	respBody, err := t.exp.postBatch(ctx, t.target, body, header, len(findings))
+	if err != nil {
+		return nil, err
+	}
+	response := &securityHubResponse{}
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, response); err != nil {
+			t.exp.logger.Warn("Failed to parse Security Hub response", zap.Error(err))
+		}
+	}
+	return response, nil
+}
+
+// shutdown has nothing to close
+func (t *securityHubTransport) shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,265 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// securityHubStub is a minimal BatchImportFindings API that verifies SigV4 signatures, rejects
+// findings of the user "mallory" and fails the request numbered failRequest
+type securityHubStub struct {
+	creds       awsCredentials
+	failRequest int
+
+	mu       sync.Mutex
+	requests int
+	findings []map[string]interface{}
+}
+
+func (s *securityHubStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != securityHubImportPath {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.verifySignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"message":"The request signature we calculated does not match the signature you provided."}`)
+		return
+	}
+	s.requests++
+	if s.requests == s.failRequest {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var request struct {
+		Findings []map[string]interface{} `json:"Findings"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	type failedFinding struct {
+		ID           string `json:"Id"`
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	}
+	failed := []failedFinding{}
+	for _, finding := range request.Findings {
+		if fields, _ := finding["ProductFields"].(map[string]interface{}); fields["user.name"] == "mallory" {
+			failed = append(failed, failedFinding{ID: finding["Id"].(string), ErrorCode: "InvalidInput", ErrorMessage: "Finding does not adhere to ASFF"})
+			continue
+		}
+		s.findings = append(s.findings, finding)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"FailedCount":    len(failed),
+		"SuccessCount":   len(request.Findings) - len(failed),
+		"FailedFindings": failed,
+	})
+}
+
+// verifySignature recomputes the request signature from the signed headers
+func (s *securityHubStub) verifySignature(r *http.Request, body []byte) bool {
+	authorization := r.Header.Get("Authorization")
+	_, signedHeaders, ok := strings.Cut(authorization, "SignedHeaders=")
+	if !ok {
+		return false
+	}
+	signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+	now, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	header := http.Header{}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		switch name {
+		case "host", "x-amz-date", "x-amz-security-token":
+		default:
+			header.Set(name, r.Header.Get(name))
+		}
+	}
+	target := "http://" + r.Host + r.URL.RequestURI()
+	expected, err := sigV4Header(r.Method, target, header, body, s.creds, securityHubService, now)
+	return err == nil && expected.Get("Authorization") == authorization
+}
+
+// newTestSecurityHubExporter starts an exporter using the Security Hub transport against a new
+// stub, with findings in account 123456789012 and region us-east-1
+func newTestSecurityHubExporter(t *testing.T, configure func(cfg *Config)) (*securityEventExporter, *securityHubStub) {
+	t.Helper()
+	stub := &securityHubStub{creds: awsCredentials{
+		region:          "us-east-1",
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		sessionToken:    "session",
+	}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	exp := newTestTransportExporter(t, transportSecurityHub, server.URL, func(cfg *Config) {
+		cfg.Encoding = encodingASFF
+		cfg.ASFF.AWSAccountID.Value = "123456789012"
+		cfg.ASFF.Region.Value = "us-east-1"
+		cfg.SecurityHub.Region = "us-east-1"
+		cfg.SecurityHub.AccessKeyID = "AKIDEXAMPLE"
+		cfg.SecurityHub.SecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+		cfg.SecurityHub.SessionToken = "session"
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+	return exp, stub
+}
+
+func TestSecurityHubImportsFindings(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, nil)
+	exp.transport.(*securityHubTransport).maxFindings = 2
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.requests != 2 || len(stub.findings) != 3 {
+		t.Fatalf("Expected 3 findings in 2 requests, got %d findings in %d requests", len(stub.findings), stub.requests)
+	}
+	finding := stub.findings[0]
+	if finding["AwsAccountId"] != "123456789012" || finding["SchemaVersion"] != asffSchemaVersion {
+		t.Errorf("Unexpected finding: %v", finding)
+	}
+	if resources := finding["Resources"].([]interface{}); resources[0].(map[string]interface{})["Id"] != "web-1" {
+		t.Errorf("Expected host.name resource, got %v", resources)
+	}
+}
+
+func TestSecurityHubRejectedFindingsArePermanent(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, nil)
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "mallory", "bob"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected permanent error for rejected finding, got %v", err)
+	}
+	var partial *partialSendError
+	if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.failed[0] != 1 {
+		t.Errorf("Expected only finding 1 to fail, got %v", err)
+	}
+	if len(stub.findings) != 2 {
+		t.Errorf("Expected the other 2 findings to be imported, got %d", len(stub.findings))
+	}
+	if exp.metrics.eventsFailed.Load() != 1 || exp.metrics.eventsExported.Load() != 2 {
+		t.Errorf("eventsFailed = %d, eventsExported = %d, want 1 and 2", exp.metrics.eventsFailed.Load(), exp.metrics.eventsExported.Load())
+	}
+}
+
+func TestSecurityHubRetriesOnlyUnsentFindings(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, nil)
+	exp.transport.(*securityHubTransport).maxFindings = 1
+	stub.failRequest = 2
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol"))
+	if err == nil || consumererror.IsPermanent(err) {
+		t.Fatalf("Expected retryable error, got %v", err)
+	}
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected consumererror.Logs, got %T", err)
+	}
+	retry := logsErr.Data()
+	if retry.LogRecordCount() != 2 {
+		t.Fatalf("Expected 2 records to retry, got %d", retry.LogRecordCount())
+	}
+	resourceLogs := retry.ResourceLogs().At(0)
+	if host, _ := resourceLogs.Resource().Attributes().Get("host.name"); host.Str() != "web-1" {
+		t.Errorf("Expected the retried record to keep its resource, got %v", resourceLogs.Resource().Attributes().AsRaw())
+	}
+	if user, _ := resourceLogs.ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("user.name"); user.Str() != "bob" {
+		t.Errorf("Expected bob to be retried first, got %q", user.Str())
+	}
+}
+
+func TestSecurityHubDropsOversizedFindings(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, nil)
+
+	events := []map[string]interface{}{
+		{"Id": "oversized", "Description": strings.Repeat("x", securityHubMaxFinding)},
+		{"Id": "valid"},
+	}
+	sources := []eventSource{newEventSource(events[0], plog.NewLogRecord()), newEventSource(events[1], plog.NewLogRecord())}
+
+	err := exp.transport.send(context.Background(), events, sources)
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected permanent error for an oversized finding, got %v", err)
+	}
+	var partial *partialSendError
+	if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.failed[0] != 0 {
+		t.Errorf("Expected only finding 0 to be dropped, got %v", err)
+	}
+	if len(stub.findings) != 1 || stub.findings[0]["Id"] != "valid" {
+		t.Errorf("Expected the other finding to be imported, got %v", stub.findings)
+	}
+}
+
+func TestSecurityHubSplitsCallsOnDuplicateIDs(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, func(cfg *Config) {
+		cfg.ASFF.IDAttributes = []string{"host.name"}
+	})
+
+	// Every finding has the same Id, so only the rejected one may be reported as failed
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "mallory", "bob"))
+	var partial *partialSendError
+	if !errors.As(err, &partial) || len(partial.failed) != 1 || partial.failed[0] != 1 {
+		t.Fatalf("Expected only finding 1 to fail, got %v", err)
+	}
+	if stub.requests != 3 || len(stub.findings) != 2 {
+		t.Errorf("Expected 2 findings imported in 3 requests, got %d findings in %d requests", len(stub.findings), stub.requests)
+	}
+}
+
+func TestSecurityHubSignatureMismatchIsPermanent(t *testing.T) {
+	exp, stub := newTestSecurityHubExporter(t, func(cfg *Config) {
+		cfg.SecurityHub.SecretAccessKey = "wrong"
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for a bad signature, got %v", err)
+	}
+	if stub.requests != 0 {
+		t.Errorf("Expected no accepted requests, got %d", stub.requests)
+	}
+}
+
+func TestValidateSecurityHubTransport(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = "https://securityhub.us-east-1.amazonaws.com"
+	cfg.Transport = transportSecurityHub
+	cfg.SecurityHub.Region = "us-east-1"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "requires the \"asff\" encoding") {
+		t.Errorf("Expected asff encoding error, got %v", err)
+	}
+
+	cfg.Encoding = encodingASFF
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned error: %v", err)
+	}
+
+	cfg.SecurityHub.Region = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "region is required") {
+		t.Errorf("Expected missing region error, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package exporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+const (
+	// sentinelLogsIngestion sends to a data collection rule through the Logs Ingestion API
+	sentinelLogsIngestion = "logs_ingestion"
+
+	// sentinelDataCollector sends to a workspace through the legacy HTTP Data Collector API
+	sentinelDataCollector = "data_collector"
+
+	// sentinelLogsIngestionAPIVersion is the Logs Ingestion API version
+	sentinelLogsIngestionAPIVersion = "2023-01-01"
+
+	// sentinelDataCollectorAPIVersion is the HTTP Data Collector API version
+	sentinelDataCollectorAPIVersion = "2016-04-01"
+
+	// sentinelDataCollectorResource is the resource signed in SharedKey authorization
+	sentinelDataCollectorResource = "/api/logs"
+
+	// sentinelLogsIngestionMaxBody is the Logs Ingestion API limit on the size of one call
+	sentinelLogsIngestionMaxBody = 1 << 20
+
+	// sentinelDataCollectorMaxBody is the Data Collector API limit on the size of one post
+	sentinelDataCollectorMaxBody = 30 << 20
+
+	// sentinelRawDataField carries the rendered line of line-oriented encodings
+	sentinelRawDataField = "RawData"
+)
+
+// sentinelLogTypePattern matches the custom log names accepted by the Data Collector API
+var sentinelLogTypePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,100}$`)
+
+// SentinelConfig configures the Microsoft Sentinel transport. With the Logs Ingestion API the
+// endpoint is the data collection endpoint or rule ingestion URL, for example
+// https://my-dce.eastus-1.ingest.monitor.azure.com; with the Data Collector API it is the
+// workspace URL, for example https://<workspace-id>.ods.opinsights.azure.com.
+type SentinelConfig struct {
+	// API selects "logs_ingestion" (default) or the legacy "data_collector" API
+	API string `mapstructure:"api"`
+
+	// DCRImmutableID is the immutable ID of the data collection rule (logs_ingestion)
+	DCRImmutableID string `mapstructure:"dcr_immutable_id"`
+
+	// StreamName is the data collection rule stream, for example Custom-SecurityEvents_CL
+	// (logs_ingestion)
+	StreamName string `mapstructure:"stream_name"`
+
+	// TenantID is the Entra ID tenant of the application (logs_ingestion)
+	TenantID string `mapstructure:"tenant_id"`
+
+	// ClientID is the application (client) ID (logs_ingestion)
+	ClientID string `mapstructure:"client_id"`
+
+	// ClientSecret is the application client secret (logs_ingestion)
+	ClientSecret configopaque.String `mapstructure:"client_secret"`
+
+	// AuthorityHost is the Entra ID authority tokens are requested from (logs_ingestion)
+	AuthorityHost string `mapstructure:"authority_host"`
+
+	// Scope is the scope of the requested access token (logs_ingestion)
+	Scope string `mapstructure:"scope"`
+
+	// WorkspaceID is the Log Analytics workspace ID (data_collector)
+	WorkspaceID string `mapstructure:"workspace_id"`
+
+	// SharedKey is the base64 primary or secondary workspace key (data_collector)
+	SharedKey configopaque.String `mapstructure:"shared_key"`
+
+	// LogType is the custom log name; the table is created as <log_type>_CL (data_collector)
+	LogType string `mapstructure:"log_type"`
+
+	// TimeGeneratedField is the field each event's timestamp is written to, unless the event
+	// already has it. The Data Collector API is told to use it as TimeGenerated.
+	TimeGeneratedField string `mapstructure:"time_generated_field"`
+}
+
+// Validate checks the API and the field names. Credentials and destination are only required
+// when the transport is selected, which validateTransport checks.
+func (cfg *SentinelConfig) Validate() error {
+	var errs []error
+	switch cfg.API {
+	case sentinelLogsIngestion, sentinelDataCollector:
+	default:
+		errs = append(errs, fmt.Errorf("invalid sentinel api %q: must be %q or %q", cfg.API, sentinelLogsIngestion, sentinelDataCollector))
+	}
+	if cfg.LogType != "" && !sentinelLogTypePattern.MatchString(cfg.LogType) {
+		errs = append(errs, fmt.Errorf("invalid sentinel log_type %q: must be at most 100 letters, digits or underscores", cfg.LogType))
+	}
+	if cfg.TimeGeneratedField == "" {
+		errs = append(errs, errors.New("sentinel time_generated_field is required"))
+	}
+	if cfg.AuthorityHost != "" {
+		if err := validateEndpoint(cfg.AuthorityHost); err != nil {
+			errs = append(errs, fmt.Errorf("invalid sentinel authority_host: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateDestination checks the settings the selected API needs to send
+func (cfg *SentinelConfig) validateDestination() error {
+	var missing []string
+	require := func(name, value string) {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	switch cfg.API {
+	case sentinelLogsIngestion:
+		require("dcr_immutable_id", cfg.DCRImmutableID)
+		require("stream_name", cfg.StreamName)
+		require("tenant_id", cfg.TenantID)
+		require("client_id", cfg.ClientID)
+		require("client_secret", string(cfg.ClientSecret))
+	case sentinelDataCollector:
+		require("workspace_id", cfg.WorkspaceID)
+		require("shared_key", string(cfg.SharedKey))
+		require("log_type", cfg.LogType)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("sentinel %s requires %s", cfg.API, strings.Join(missing, ", "))
+	}
+	if cfg.API == sentinelDataCollector {
+		if _, err := base64.StdEncoding.DecodeString(string(cfg.SharedKey)); err != nil {
+			return fmt.Errorf("invalid sentinel shared_key: %w", err)
+		}
+	}
+	return nil
+}
+
+// createDefaultSentinelConfig creates the default Sentinel API, authority and time field
+func createDefaultSentinelConfig() SentinelConfig {
+	return SentinelConfig{
+		API:                sentinelLogsIngestion,
+		AuthorityHost:      "https://login.microsoftonline.com",
+		Scope:              "https://monitor.azure.com/.default",
+		TimeGeneratedField: "TimeGenerated",
+	}
+}
+
+// sentinelTransport posts batches of events as JSON arrays to the Logs Ingestion API or the
+// Data Collector API, splitting batches that exceed the API's request size limit
+type sentinelTransport struct {
+	exp         *securityEventExporter
+	config      *SentinelConfig
+	target      string
+	sharedKey   []byte
+	maxBodySize int
+	accessToken accessToken
+}
+
+// newSentinelTransport creates the Sentinel transport of e
+func newSentinelTransport(e *securityEventExporter) (*sentinelTransport, error) {
+	cfg := &e.config.Sentinel
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateDestination(); err != nil {
+		return nil, err
+	}
+
+	t := &sentinelTransport{exp: e, config: cfg}
+	var err error
+	switch cfg.API {
+	case sentinelLogsIngestion:
+		t.maxBodySize = sentinelLogsIngestionMaxBody
+		t.target, err = url.JoinPath(e.config.Endpoint, "dataCollectionRules", cfg.DCRImmutableID, "streams", cfg.StreamName)
+		t.target += "?api-version=" + sentinelLogsIngestionAPIVersion
+	case sentinelDataCollector:
+		t.maxBodySize = sentinelDataCollectorMaxBody
+		t.target, err = url.JoinPath(e.config.Endpoint, sentinelDataCollectorResource)
+		t.target += "?api-version=" + sentinelDataCollectorAPIVersion
+		t.sharedKey, _ = base64.StdEncoding.DecodeString(string(cfg.SharedKey))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	return t, nil
+}
+
+// start has nothing to open; Sentinel requests use the exporter's HTTP client
+func (t *sentinelTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send posts the batch in as many requests as the API's size limit requires
+func (t *sentinelTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	records := make([]json.RawMessage, 0, len(events))
+	for i, event := range events {
+		record, err := t.record(event, sources[i])
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(err)
+		}
+		records = append(records, record)
+	}
+	chunks, err := chunkRecords(records, t.maxBodySize, 0)
+	if err != nil {
+		t.exp.metrics.httpErrors.Add(1)
+		return consumererror.NewPermanent(fmt.Errorf("sentinel %w", err))
+	}
+
+	// The records of the chunks before a failing one were accepted and are not retried
+	sent := 0
+	for _, chunk := range chunks {
+		body, err := json.Marshal(chunk)
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return unsentError(consumererror.NewPermanent(fmt.Errorf("failed to marshal sentinel records: %w", err)), sent, len(records))
+		}
+		header, err := t.header(ctx, len(body))
+		if err != nil {
+			return unsentError(err, sent, len(records))
+		}
+		if _, err := t.exp.postBatch(ctx, t.target, body, header, len(chunk)); err != nil {
+			return unsentError(err, sent, len(records))
+		}
+		sent += len(chunk)
+	}
+	return nil
+}
+
+// shutdown has nothing to close
+func (t *sentinelTransport) shutdown(context.Context) error {
+	return nil
+}
+
+// record returns the JSON row of an event with its time in TimeGeneratedField. Line-oriented
+// encodings are sent as the RawData column.
+func (t *sentinelTransport) record(event map[string]interface{}, source eventSource) (json.RawMessage, error) {
+	row := make(map[string]interface{}, len(event)+1)
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, err
+		}
+		row[sentinelRawDataField] = line
+	} else {
+		for key, value := range event {
+			row[key] = value
+		}
+	}
+	if _, ok := row[t.config.TimeGeneratedField]; !ok {
+		row[t.config.TimeGeneratedField] = source.time.UTC().Format(time.RFC3339Nano)
+	}
+
+	record, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sentinel record: %w", err)
+	}
+	return record, nil
+}
+
+// header returns the authorization and API headers of a request with a body of contentLength
+// bytes
+func (t *sentinelTransport) header(ctx context.Context, contentLength int) (http.Header, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	if t.config.API == sentinelDataCollector {
+		date := time.Now().UTC().Format(http.TimeFormat)
+		header.Set("Log-Type", t.config.LogType)
+		header.Set("x-ms-date", date)
+		header.Set("time-generated-field", t.config.TimeGeneratedField)
+		header.Set("Authorization", "SharedKey "+t.config.WorkspaceID+":"+t.signature(date, contentLength))
+		return header, nil
+	}
+
+	// Dry runs do not contact Entra ID; the Authorization header would be masked anyway
+	if t.exp.config.isDryRun() {
+		return header, nil
+	}
+	token, err := t.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return header, nil
+}
+
+// signature returns the base64 HMAC-SHA256 SharedKey signature of a Data Collector request
+func (t *sentinelTransport) signature(date string, contentLength int) string {
+	stringToSign := sentinelStringToSign(date, contentLength)
+	mac := hmac.New(sha256.New, t.sharedKey)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sentinelStringToSign returns the string signed in SharedKey authorization of a Data Collector
+// request
+func sentinelStringToSign(date string, contentLength int) string {
+	return "POST\n" + strconv.Itoa(contentLength) + "\napplication/json\nx-ms-date:" + date + "\n" + sentinelDataCollectorResource
+}
+
+// token returns the cached Entra ID access token, requesting a new one with the client
+// credentials grant when needed
+func (t *sentinelTransport) token(ctx context.Context) (string, error) {
+	return t.accessToken.get(ctx, func(ctx context.Context) (string, time.Duration, error) {
+		tokenURL, err := url.JoinPath(t.config.AuthorityHost, t.config.TenantID, "oauth2/v2.0/token")
+		if err != nil {
+			return "", 0, consumererror.NewPermanent(fmt.Errorf("invalid sentinel authority_host: %w", err))
+		}
+		return t.exp.requestAccessToken(ctx, tokenURL, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {t.config.ClientID},
+			"client_secret": {string(t.config.ClientSecret)},
+			"scope":         {t.config.Scope},
+		})
+	})
+}
@@ -0,0 +1,302 @@
+package exporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// sentinelTestSharedKey is the base64 workspace key used by the Data Collector stub
+var sentinelTestSharedKey = base64.StdEncoding.EncodeToString([]byte("workspace-shared-key"))
+
+// sentinelStub is a minimal Entra ID token endpoint, Logs Ingestion API and Data Collector API
+// that verifies credentials and signatures and records the rows it accepts
+type sentinelStub struct {
+	mu            sync.Mutex
+	tokenRequests int
+	requests      int
+	rows          []map[string]interface{}
+	headers       []http.Header
+
+	// failRequest is the 1-based number of an ingestion request answered with 503
+	failRequest int
+	attempts    int
+}
+
+func (s *sentinelStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/tenant-1/oauth2/v2.0/token":
+		form, err := url.ParseQuery(string(body))
+		if err != nil || form.Get("grant_type") != "client_credentials" || form.Get("client_id") != "client-1" ||
+			form.Get("client_secret") != "secret-1" || form.Get("scope") != "https://monitor.azure.com/.default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error":"invalid_client"}`)
+			return
+		}
+		s.tokenRequests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"token_type":   "Bearer",
+			"access_token": "token-" + strconv.Itoa(s.tokenRequests),
+			"expires_in":   3600,
+		})
+		return
+	case r.URL.Path == "/dataCollectionRules/dcr-0123/streams/Custom-SecurityEvents_CL":
+		if r.URL.Query().Get("api-version") != sentinelLogsIngestionAPIVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case r.URL.Path == "/api/logs":
+		key, _ := base64.StdEncoding.DecodeString(sentinelTestSharedKey)
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(sentinelStringToSign(r.Header.Get("x-ms-date"), len(body))))
+		expected := "SharedKey workspace-1:" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if r.Header.Get("Authorization") != expected || r.Header.Get("Log-Type") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.attempts++
+	if s.attempts == s.failRequest {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests++
+	s.rows = append(s.rows, rows...)
+	s.headers = append(s.headers, r.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newTestSentinelExporter starts an exporter using the Sentinel transport against stub
+func newTestSentinelExporter(t *testing.T, stub *sentinelStub, api string, configure func(cfg *Config)) *securityEventExporter {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return newTestTransportExporter(t, transportSentinel, server.URL, func(cfg *Config) {
+		cfg.Sentinel.API = api
+		switch api {
+		case sentinelLogsIngestion:
+			cfg.Sentinel.DCRImmutableID = "dcr-0123"
+			cfg.Sentinel.StreamName = "Custom-SecurityEvents_CL"
+			cfg.Sentinel.TenantID = "tenant-1"
+			cfg.Sentinel.ClientID = "client-1"
+			cfg.Sentinel.ClientSecret = "secret-1"
+			cfg.Sentinel.AuthorityHost = server.URL
+		case sentinelDataCollector:
+			cfg.Sentinel.WorkspaceID = "workspace-1"
+			cfg.Sentinel.SharedKey = configopaque.String(sentinelTestSharedKey)
+			cfg.Sentinel.LogType = "SecurityEvents"
+		}
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+}
+
+func TestSentinelLogsIngestion(t *testing.T) {
+	stub := &sentinelStub{}
+	exp := newTestSentinelExporter(t, stub, sentinelLogsIngestion, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+			t.Fatalf("ConsumeLogs() returned error: %v", err)
+		}
+	}
+
+	if stub.tokenRequests != 1 {
+		t.Errorf("Expected the access token to be requested once, got %d", stub.tokenRequests)
+	}
+	if stub.requests != 2 || len(stub.rows) != 4 {
+		t.Fatalf("Expected 4 rows in 2 requests, got %d rows in %d requests", len(stub.rows), stub.requests)
+	}
+	row := stub.rows[0]
+	if row["user.name"] != "alice" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+	if row["TimeGenerated"] != "2023-11-14T22:13:20Z" {
+		t.Errorf("TimeGenerated = %v, want 2023-11-14T22:13:20Z", row["TimeGenerated"])
+	}
+	if stub.headers[1].Get("Authorization") != "Bearer token-1" {
+		t.Errorf("Expected cached token to be reused, got %q", stub.headers[1].Get("Authorization"))
+	}
+}
+
+func TestSentinelLogsIngestionInvalidCredentialsArePermanent(t *testing.T) {
+	stub := &sentinelStub{}
+	exp := newTestSentinelExporter(t, stub, sentinelLogsIngestion, func(cfg *Config) {
+		cfg.Sentinel.ClientSecret = "wrong"
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for rejected credentials, got %v", err)
+	}
+	if stub.requests != 0 {
+		t.Errorf("Expected no ingestion request without a token, got %d", stub.requests)
+	}
+}
+
+func TestSentinelLogsIngestionSplitsLargeBatches(t *testing.T) {
+	stub := &sentinelStub{}
+	exp := newTestSentinelExporter(t, stub, sentinelLogsIngestion, nil)
+	exp.transport.(*sentinelTransport).maxBodySize = 300
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "dave")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.requests < 2 {
+		t.Errorf("Expected the batch to be split, got %d requests", stub.requests)
+	}
+	if len(stub.rows) != 4 {
+		t.Errorf("Expected 4 rows, got %d", len(stub.rows))
+	}
+}
+
+func TestSentinelRetriesOnlyUnsentChunks(t *testing.T) {
+	stub := &sentinelStub{failRequest: 2}
+	exp := newTestSentinelExporter(t, stub, sentinelLogsIngestion, nil)
+	exp.transport.(*sentinelTransport).maxBodySize = 300
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol", "dave"))
+	if err == nil || consumererror.IsPermanent(err) {
+		t.Fatalf("Expected retryable error, got %v", err)
+	}
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected consumererror.Logs, got %T", err)
+	}
+
+	// The rows of the first request were accepted; the failed chunk and the rest are retried
+	accepted := len(stub.rows)
+	if accepted == 0 || accepted == 4 {
+		t.Fatalf("Expected part of the batch to be accepted, got %d rows", accepted)
+	}
+	if got := logsErr.Data().LogRecordCount(); got != 4-accepted {
+		t.Errorf("Expected %d records to retry, got %d", 4-accepted, got)
+	}
+	if exp.metrics.eventsExported.Load() != int64(accepted) || exp.metrics.eventsFailed.Load() != int64(4-accepted) {
+		t.Errorf("eventsExported = %d, eventsFailed = %d, want %d and %d",
+			exp.metrics.eventsExported.Load(), exp.metrics.eventsFailed.Load(), accepted, 4-accepted)
+	}
+}
+
+func TestSentinelDataCollector(t *testing.T) {
+	stub := &sentinelStub{}
+	exp := newTestSentinelExporter(t, stub, sentinelDataCollector, func(cfg *Config) {
+		cfg.Encoding = encodingCEF
+		cfg.Sentinel.TimeGeneratedField = "EventTime"
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if len(stub.rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(stub.rows))
+	}
+	header := stub.headers[0]
+	if header.Get("Log-Type") != "SecurityEvents" || header.Get("time-generated-field") != "EventTime" {
+		t.Errorf("Unexpected Data Collector headers: %v", header)
+	}
+	row := stub.rows[0]
+	if line, _ := row["RawData"].(string); !strings.HasPrefix(line, "CEF:0|") {
+		t.Errorf("Expected CEF line in RawData, got %v", row["RawData"])
+	}
+	if row["EventTime"] != "2023-11-14T22:13:20Z" {
+		t.Errorf("EventTime = %v, want 2023-11-14T22:13:20Z", row["EventTime"])
+	}
+}
+
+func TestSentinelDataCollectorWrongKeyIsPermanent(t *testing.T) {
+	stub := &sentinelStub{}
+	exp := newTestSentinelExporter(t, stub, sentinelDataCollector, func(cfg *Config) {
+		cfg.Sentinel.SharedKey = "d3Jvbmcta2V5"
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for rejected signature, got %v", err)
+	}
+}
+
+func TestSentinelConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *SentinelConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*SentinelConfig) {}},
+		{name: "invalid api", modify: func(cfg *SentinelConfig) { cfg.API = "http" }, errorMsg: "invalid sentinel api"},
+		{name: "invalid log type", modify: func(cfg *SentinelConfig) { cfg.LogType = "Security-Events" }, errorMsg: "invalid sentinel log_type"},
+		{name: "missing time field", modify: func(cfg *SentinelConfig) { cfg.TimeGeneratedField = "" }, errorMsg: "time_generated_field"},
+		{name: "relative authority", modify: func(cfg *SentinelConfig) { cfg.AuthorityHost = "login" }, errorMsg: "authority_host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultSentinelConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestSentinelValidateDestination(t *testing.T) {
+	cfg := createDefaultSentinelConfig()
+	err := cfg.validateDestination()
+	if err == nil || !strings.Contains(err.Error(), "dcr_immutable_id, stream_name, tenant_id, client_id, client_secret") {
+		t.Errorf("Expected missing logs ingestion settings, got %v", err)
+	}
+
+	cfg.API = sentinelDataCollector
+	cfg.WorkspaceID = "workspace-1"
+	cfg.SharedKey = "not base64!"
+	cfg.LogType = "SecurityEvents"
+	if err := cfg.validateDestination(); err == nil || !strings.Contains(err.Error(), "shared_key") {
+		t.Errorf("Expected invalid shared key error, got %v", err)
+	}
+}
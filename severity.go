@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// severityLevel is the normalized severity shared by the output encoders
+type severityLevel int
+
+const (
+	severityUnknown severityLevel = iota
+	severityInformational
+	severityLow
+	severityMedium
+	severityHigh
+	severityCritical
+)
+
+// String returns the level name as used by OCSF and ECS
+func (l severityLevel) String() string {
+	switch l {
+	case severityInformational:
+		return "Informational"
+	case severityLow:
+		return "Low"
+	case severityMedium:
+		return "Medium"
+	case severityHigh:
+		return "High"
+	case severityCritical:
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// severityNumber returns the record's severity number, falling back to its severity text when
+// the number is unspecified
+func severityNumber(logRecord plog.LogRecord) plog.SeverityNumber {
+	if number := logRecord.SeverityNumber(); number != plog.SeverityNumberUnspecified {
+		return number
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(logRecord.SeverityText())) {
+	case "TRACE":
+		return plog.SeverityNumberTrace
+	case "DEBUG":
+		return plog.SeverityNumberDebug
+	case "INFO", "INFORMATION", "INFORMATIONAL", "NOTICE":
+		return plog.SeverityNumberInfo
+	case "WARN", "WARNING":
+		return plog.SeverityNumberWarn
+	case "ERROR", "ERR":
+		return plog.SeverityNumberError
+	case "CRITICAL", "CRIT", "ALERT":
+		return plog.SeverityNumberFatal
+	case "FATAL", "EMERGENCY", "EMERG":
+		return plog.SeverityNumberFatal4
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
+
+// severityFamily returns the OpenTelemetry short name of the range a severity number falls in:
+// TRACE, DEBUG, INFO, WARN, ERROR or FATAL, or an empty string when unspecified
+func severityFamily(number plog.SeverityNumber) string {
+	switch {
+	case number >= plog.SeverityNumberFatal:
+		return "FATAL"
+	case number >= plog.SeverityNumberError:
+		return "ERROR"
+	case number >= plog.SeverityNumberWarn:
+		return "WARN"
+	case number >= plog.SeverityNumberInfo:
+		return "INFO"
+	case number >= plog.SeverityNumberDebug:
+		return "DEBUG"
+	case number >= plog.SeverityNumberTrace:
+		return "TRACE"
+	default:
+		return ""
+	}
+}
+
+// severityLevelOf maps an OpenTelemetry severity number to a normalized level
+func severityLevelOf(number plog.SeverityNumber) severityLevel {
+	switch severityFamily(number) {
+	case "TRACE", "DEBUG":
+		return severityInformational
+	case "INFO":
+		return severityLow
+	case "WARN":
+		return severityMedium
+	case "ERROR":
+		return severityHigh
+	case "FATAL":
+		return severityCritical
+	default:
+		return severityUnknown
+	}
+}
+
+// severityScore maps an OpenTelemetry severity number (1-24) linearly onto the 0-10 scale used
+// by CEF and LEEF, so TRACE is 0, INFO 3, WARN 5, ERROR 7 and FATAL 9-10. Unspecified severities
+// score 0. overrides, keyed by severity family (for example "ERROR"), take precedence.
+func severityScore(number plog.SeverityNumber, overrides map[string]int) int {
+	if score, ok := overrides[severityFamily(number)]; ok {
+		return score
+	}
+	if number == plog.SeverityNumberUnspecified {
+		return 0
+	}
+	return (int(number-plog.SeverityNumberTrace)*10 + 11) / 23
+}
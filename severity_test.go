@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestSeverityNumberFallsBackToText(t *testing.T) {
+	tests := []struct {
+		number plog.SeverityNumber
+		text   string
+		want   plog.SeverityNumber
+	}{
+		{number: plog.SeverityNumberError2, text: "INFO", want: plog.SeverityNumberError2},
+		{text: "warning", want: plog.SeverityNumberWarn},
+		{text: "Error", want: plog.SeverityNumberError},
+		{text: "emergency", want: plog.SeverityNumberFatal4},
+		{text: "custom", want: plog.SeverityNumberUnspecified},
+	}
+
+	for _, tt := range tests {
+		record := plog.NewLogRecord()
+		record.SetSeverityNumber(tt.number)
+		record.SetSeverityText(tt.text)
+		if got := severityNumber(record); got != tt.want {
+			t.Errorf("severityNumber(%v, %q) = %v, want %v", tt.number, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSeverityMappings(t *testing.T) {
+	tests := []struct {
+		number plog.SeverityNumber
+		level  severityLevel
+		score  int
+	}{
+		{number: plog.SeverityNumberUnspecified, level: severityUnknown, score: 0},
+		{number: plog.SeverityNumberTrace, level: severityInformational, score: 0},
+		{number: plog.SeverityNumberDebug, level: severityInformational, score: 2},
+		{number: plog.SeverityNumberInfo, level: severityLow, score: 3},
+		{number: plog.SeverityNumberWarn, level: severityMedium, score: 5},
+		{number: plog.SeverityNumberError, level: severityHigh, score: 7},
+		{number: plog.SeverityNumberFatal, level: severityCritical, score: 9},
+		{number: plog.SeverityNumberFatal4, level: severityCritical, score: 10},
+	}
+
+	for _, tt := range tests {
+		if got := severityLevelOf(tt.number); got != tt.level {
+			t.Errorf("severityLevelOf(%v) = %v, want %v", tt.number, got, tt.level)
+		}
+		if got := severityScore(tt.number, nil); got != tt.score {
+			t.Errorf("severityScore(%v) = %d, want %d", tt.number, got, tt.score)
+		}
+	}
+
+	if got := severityScore(plog.SeverityNumberError3, map[string]int{"ERROR": 8}); got != 8 {
+		t.Errorf("Expected override for ERROR family, got %d", got)
+	}
+}
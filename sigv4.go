@@ -0,0 +1,178 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+const (
+	// sigV4Algorithm is the AWS Signature Version 4 signing algorithm
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+	// sigV4TimeFormat is the format of the X-Amz-Date header
+	sigV4TimeFormat = "20060102T150405Z"
+)
+
+// AWSCredentialsConfig configures the credentials AWS requests are signed with. Credentials that
+// are not configured are taken from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables.
+type AWSCredentialsConfig struct {
+	// Region is the AWS region requests are signed for
+	Region string `mapstructure:"region"`
+
+	// AccessKeyID is the access key ID
+	AccessKeyID string `mapstructure:"access_key_id"`
+
+	// SecretAccessKey is the secret access key
+	SecretAccessKey configopaque.String `mapstructure:"secret_access_key"`
+
+	// SessionToken is the session token of temporary credentials
+	SessionToken configopaque.String `mapstructure:"session_token"`
+}
+
+// awsCredentials are resolved signing credentials
+type awsCredentials struct {
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// resolve returns the configured credentials, completed from the environment
+func (cfg *AWSCredentialsConfig) resolve() (awsCredentials, error) {
+	creds := awsCredentials{
+		region:          cfg.Region,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: string(cfg.SecretAccessKey),
+		sessionToken:    string(cfg.SessionToken),
+	}
+	if creds.accessKeyID == "" && creds.secretAccessKey == "" {
+		creds.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		creds.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if creds.sessionToken == "" {
+			creds.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if creds.region == "" {
+		return creds, errors.New("aws region is required")
+	}
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return creds, errors.New("aws credentials are required: set access_key_id and secret_access_key or the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+	}
+	return creds, nil
+}
+
+// sigV4Header returns the headers that sign a request to service with method, target URL, body
+// and the given headers at now. All given headers are signed; the returned headers add X-Amz-Date,
+// the session token and Authorization to them.
+func sigV4Header(method, target string, header http.Header, body []byte, creds awsCredentials, service string, now time.Time) (http.Header, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %w", target, err)
+	}
+
+	signed := header.Clone()
+	if signed == nil {
+		signed = http.Header{}
+	}
+	payloadHash := sha256Hex(body)
+	amzDate := now.UTC().Format(sigV4TimeFormat)
+	signed.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		signed.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	// Canonical headers are the lower-cased names with trimmed values, sorted, plus host
+	names := []string{"host"}
+	values := map[string]string{"host": u.Host}
+	for name, v := range signed {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		sigV4CanonicalURI(u),
+		sigV4CanonicalQuery(u.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := date + "/" + creds.region + "/" + service + "/aws4_request"
+	stringToSign := sigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, creds.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	signed.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.accessKeyID, scope, signedHeaders, signature))
+	return signed, nil
+}
+
+// sigV4CanonicalURI returns the URI-encoded path of u
+func sigV4CanonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// sigV4CanonicalQuery returns the query parameters sorted by name and strictly URI-encoded
+func sigV4CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, sigV4Escape(key)+"="+sigV4Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// sigV4Escape URI-encodes s, leaving only unreserved characters as is
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// sha256Hex returns the hex SHA-256 digest of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
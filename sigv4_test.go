@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigV4HeaderMatchesAWSExample(t *testing.T) {
+	// The GET ListUsers example from the AWS Signature Version 4 documentation
+	creds := awsCredentials{
+		region:          "us-east-1",
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signed, err := sigV4Header(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+		header, nil, creds, "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("sigV4Header() returned error: %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := signed.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if signed.Get("X-Amz-Date") != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", signed.Get("X-Amz-Date"))
+	}
+	if header.Get("Authorization") != "" {
+		t.Error("sigV4Header() should not modify the given headers")
+	}
+}
+
+func TestSigV4HeaderSignsSessionToken(t *testing.T) {
+	creds := awsCredentials{region: "eu-west-1", accessKeyID: "AKID", secretAccessKey: "secret", sessionToken: "session"}
+	signed, err := sigV4Header(http.MethodPost, "https://securityhub.eu-west-1.amazonaws.com/findings/import", nil, []byte("{}"), creds, "securityhub", time.Now())
+	if err != nil {
+		t.Fatalf("sigV4Header() returned error: %v", err)
+	}
+	if signed.Get("X-Amz-Security-Token") != "session" {
+		t.Errorf("Expected session token header, got %v", signed)
+	}
+	if !strings.Contains(signed.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token") {
+		t.Errorf("Expected the session token to be signed, got %q", signed.Get("Authorization"))
+	}
+}
+
+func TestAWSCredentialsFromEnvironment(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+
+	creds, err := (&AWSCredentialsConfig{Region: "us-east-1"}).resolve()
+	if err != nil {
+		t.Fatalf("resolve() returned error: %v", err)
+	}
+	if creds.accessKeyID != "AKIDENV" || creds.sessionToken != "token" {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := (&AWSCredentialsConfig{Region: "us-east-1"}).resolve(); err == nil {
+		t.Error("Expected error without credentials")
+	}
+	if _, err := (&AWSCredentialsConfig{AccessKeyID: "AKID", SecretAccessKey: "secret"}).resolve(); err == nil {
+		t.Error("Expected error without region")
+	}
+}
@@ -0,0 +1,301 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// splunkHECAckPath is the HEC indexer acknowledgement endpoint, relative to the endpoint host
+const splunkHECAckPath = "/services/collector/ack"
+
+// SplunkHECConfig configures the Splunk HTTP Event Collector transport. The endpoint is the HEC
+// event URL, for example https://splunk.example.com:8088/services/collector/event.
+type SplunkHECConfig struct {
+	// Token is the HEC token, sent as "Authorization: Splunk <token>"
+	Token configopaque.String `mapstructure:"token"`
+
+	// Source is the source of each event
+	Source FieldSource `mapstructure:"source"`
+
+	// SourceType is the sourcetype of each event
+	SourceType FieldSource `mapstructure:"sourcetype"`
+
+	// Index is the index of each event; the token's default index is used when it is empty
+	Index FieldSource `mapstructure:"index"`
+
+	// Host is the host of each event
+	Host FieldSource `mapstructure:"host"`
+
+	// IndexedFields are attributes also sent as indexed fields
+	IndexedFields []string `mapstructure:"indexed_fields"`
+
+	// UseAck waits for indexer acknowledgement of each batch before reporting it as delivered
+	UseAck bool `mapstructure:"use_ack"`
+
+	// Channel is the X-Splunk-Request-Channel GUID; one is generated when it is empty and
+	// acknowledgement is enabled
+	Channel string `mapstructure:"channel"`
+
+	// AckPollInterval is how often acknowledgement status is polled
+	AckPollInterval time.Duration `mapstructure:"ack_poll_interval"`
+
+	// AckTimeout is how long to wait for acknowledgement before the batch is retried
+	AckTimeout time.Duration `mapstructure:"ack_timeout"`
+}
+
+// Validate checks the channel and acknowledgement timing. The token is only required when the
+// transport is selected, which validateTransport checks.
+func (cfg *SplunkHECConfig) Validate() error {
+	var errs []error
+	if cfg.Channel != "" {
+		if _, err := uuid.Parse(cfg.Channel); err != nil {
+			errs = append(errs, fmt.Errorf("invalid splunk_hec channel %q: must be a GUID", cfg.Channel))
+		}
+	}
+	if cfg.UseAck {
+		if cfg.AckPollInterval <= 0 {
+			errs = append(errs, fmt.Errorf("splunk_hec ack_poll_interval must be positive, got %s", cfg.AckPollInterval))
+		}
+		if cfg.AckTimeout < cfg.AckPollInterval {
+			errs = append(errs, fmt.Errorf("splunk_hec ack_timeout %s must not be shorter than ack_poll_interval %s", cfg.AckTimeout, cfg.AckPollInterval))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultSplunkHECConfig creates the default HEC envelope sources and ack timing
+func createDefaultSplunkHECConfig() SplunkHECConfig {
+	return SplunkHECConfig{
+		Source:          FieldSource{Attributes: []string{"service.name"}, Value: "otelcol"},
+		SourceType:      FieldSource{Value: "otel:security"},
+		Host:            FieldSource{Attributes: []string{"host.name"}},
+		AckPollInterval: time.Second,
+		AckTimeout:      30 * time.Second,
+	}
+}
+
+// splunkHECEvent is the HEC event envelope
+type splunkHECEvent struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Event      interface{}            `json:"event"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+// splunkHECResponse is the HEC response to an event request
+type splunkHECResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// splunkHECTransport posts batches of concatenated event envelopes to HEC and optionally waits
+// for indexer acknowledgement
+type splunkHECTransport struct {
+	exp     *securityEventExporter
+	config  *SplunkHECConfig
+	channel string
+	ackURL  string
+}
+
+// newSplunkHECTransport creates the Splunk HEC transport of e
+func newSplunkHECTransport(e *securityEventExporter) (*splunkHECTransport, error) {
+	cfg := &e.config.SplunkHEC
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := url.Parse(e.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", e.config.Endpoint, err)
+	}
+	channel := cfg.Channel
+	if channel == "" && cfg.UseAck {
+		channel = uuid.NewString()
+	}
+
+	ackURL := url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: splunkHECAckPath}
+	if channel != "" {
+		ackURL.RawQuery = url.Values{"channel": {channel}}.Encode()
+	}
+	return &splunkHECTransport{exp: e, config: cfg, channel: channel, ackURL: ackURL.String()}, nil
+}
+
+// start has nothing to open; HEC requests use the exporter's HTTP client
+func (t *splunkHECTransport) start(context.Context, component.Host) error {
+	return nil
+}
+
+// send posts the batch and, with acknowledgement enabled, waits until HEC reports it indexed
+func (t *splunkHECTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i, event := range events {
+		envelope, err := t.envelope(event, sources[i])
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(err)
+		}
+		if err := encoder.Encode(envelope); err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			return consumererror.NewPermanent(fmt.Errorf("failed to marshal splunk hec event: %w", err))
+		}
+	}
+
+	respBody, err := t.exp.postBatch(ctx, t.exp.config.Endpoint, body.Bytes(), t.header(), len(events))
+	if err != nil || t.exp.config.isDryRun() {
+		return err
+	}
+
+	var resp splunkHECResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to parse splunk hec response: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("splunk hec rejected the batch: %s (code %d)", resp.Text, resp.Code)
+	}
+	if !t.config.UseAck {
+		return nil
+	}
+	if resp.AckID == nil {
+		return consumererror.NewPermanent(errors.New("splunk hec response has no ackId; indexer acknowledgement must be enabled on the token"))
+	}
+	return t.waitForAck(ctx, *resp.AckID)
+}
+
+// shutdown has nothing to close
+func (t *splunkHECTransport) shutdown(context.Context) error {
+	return nil
+}
+
+// header returns the HEC authorization and channel headers
+func (t *splunkHECTransport) header() http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", "Splunk "+string(t.config.Token))
+	if t.channel != "" {
+		header.Set("X-Splunk-Request-Channel", t.channel)
+	}
+	return header
+}
+
+// envelope wraps an encoded event in a HEC envelope. Line-oriented encodings are sent as the
+// event string; all others as the event object.
+func (t *splunkHECTransport) envelope(event map[string]interface{}, source eventSource) (splunkHECEvent, error) {
+	envelope := splunkHECEvent{
+		Time:       float64(source.time.UnixMilli()) / 1000,
+		Host:       t.config.Host.resolve(source.attributes),
+		Source:     t.config.Source.resolve(source.attributes),
+		SourceType: t.config.SourceType.resolve(source.attributes),
+		Index:      t.config.Index.resolve(source.attributes),
+		Event:      event,
+	}
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return envelope, err
+		}
+		envelope.Event = line
+	}
+	for _, field := range t.config.IndexedFields {
+		if value, ok := source.attributes[field]; ok {
+			if envelope.Fields == nil {
+				envelope.Fields = make(map[string]interface{}, len(t.config.IndexedFields))
+			}
+			envelope.Fields[field] = value
+		}
+	}
+	return envelope, nil
+}
+
+// waitForAck polls the ack endpoint until ackID is reported indexed. A batch that is not
+// acknowledged within AckTimeout is returned as a retryable error.
+func (t *splunkHECTransport) waitForAck(ctx context.Context, ackID int64) error {
+	deadline := time.NewTimer(t.config.AckTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(t.config.AckPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			t.exp.logger.Warn("Splunk HEC batch was not acknowledged in time",
+				zap.Int64("ack_id", ackID),
+				zap.Duration("ack_timeout", t.config.AckTimeout))
+			return fmt.Errorf("splunk hec ack %d not received within %s", ackID, t.config.AckTimeout)
+		case <-ticker.C:
+		}
+
+		acked, err := t.queryAck(ctx, ackID)
+		if err != nil {
+			t.exp.logger.Warn("Splunk HEC ack poll failed", zap.Int64("ack_id", ackID), zap.Error(err))
+			// A rejected ack request, such as one for a channel HEC does not know, never succeeds
+			if consumererror.IsPermanent(err) {
+				return err
+			}
+			continue
+		}
+		if acked {
+			t.exp.logger.Debug("Splunk HEC batch acknowledged", zap.Int64("ack_id", ackID))
+			return nil
+		}
+	}
+}
+
+// queryAck asks HEC whether ackID has been indexed. The request carries the same headers as the
+// event posts and is counted in the HTTP request metrics.
+func (t *splunkHECTransport) queryAck(ctx context.Context, ackID int64) (bool, error) {
+	payload, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.ackURL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header = t.header()
+	for key, value := range t.exp.config.Headers {
+		req.Header.Set(key, string(value))
+	}
+
+	startTime := time.Now()
+	resp, err := t.exp.client.Do(req)
+	t.exp.metrics.httpRequests.Add(1)
+	t.exp.metrics.httpDurations.record(time.Since(startTime))
+	if err != nil {
+		t.exp.metrics.httpErrors.Add(1)
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.exp.metrics.httpErrors.Add(1)
+		return false, classifyStatusError(fmt.Errorf("ack request failed with status: %d", resp.StatusCode), resp)
+	}
+
+	var status struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBodySize)).Decode(&status); err != nil {
+		return false, fmt.Errorf("failed to parse ack response: %w", err)
+	}
+	return status.Acks[strconv.FormatInt(ackID, 10)], nil
+}
@@ -0,0 +1,302 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// hecStub is a minimal Splunk HTTP Event Collector that records events and acknowledges each
+// batch after a number of ack polls, or answers ack polls with ackStatus when it is set
+type hecStub struct {
+	token      string
+	ackAfter   int
+	neverAck   bool
+	ackStatus  int
+	mu         sync.Mutex
+	events     []map[string]interface{}
+	channels   []string
+	ackPolls   int
+	ackTenants []string
+	nextAckID  int64
+	pendingAck map[int64]bool
+}
+
+func (s *hecStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk "+s.token {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"text":"Invalid token","code":4}`)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/services/collector/event":
+		s.channels = append(s.channels, r.Header.Get("X-Splunk-Request-Channel"))
+		decoder := json.NewDecoder(r.Body)
+		for decoder.More() {
+			var envelope map[string]interface{}
+			if err := decoder.Decode(&envelope); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"text":"Invalid data format","code":6}`)
+				return
+			}
+			s.events = append(s.events, envelope)
+		}
+		s.nextAckID++
+		if s.pendingAck == nil {
+			s.pendingAck = make(map[int64]bool)
+		}
+		s.pendingAck[s.nextAckID] = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"text": "Success", "code": 0, "ackId": s.nextAckID})
+	case "/services/collector/ack":
+		if r.URL.Query().Get("channel") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var request struct {
+			Acks []int64 `json:"acks"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.ackPolls++
+		s.ackTenants = append(s.ackTenants, r.Header.Get("X-Tenant"))
+		if s.ackStatus != 0 {
+			w.WriteHeader(s.ackStatus)
+			return
+		}
+		acks := make(map[string]bool, len(request.Acks))
+		for _, id := range request.Acks {
+			acks[strconv.FormatInt(id, 10)] = s.pendingAck[id] && !s.neverAck && s.ackPolls > s.ackAfter
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newTestHECExporter starts an exporter using the Splunk HEC transport against stub
+func newTestHECExporter(t *testing.T, stub *hecStub, configure func(cfg *Config)) *securityEventExporter {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return newTestTransportExporter(t, transportSplunkHEC, server.URL+"/services/collector/event", func(cfg *Config) {
+		cfg.SplunkHEC.Token = "00000000-0000-0000-0000-000000000001"
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+}
+
+func TestSplunkHECEnvelopes(t *testing.T) {
+	stub := &hecStub{token: "00000000-0000-0000-0000-000000000001"}
+	exp := newTestHECExporter(t, stub, func(cfg *Config) {
+		cfg.SplunkHEC.Index = FieldSource{Attributes: []string{"splunk.index"}, Value: "security"}
+		cfg.SplunkHEC.IndexedFields = []string{"user.name"}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	if len(stub.events) != 2 {
+		t.Fatalf("Expected 2 envelopes, got %d", len(stub.events))
+	}
+	envelope := stub.events[0]
+	expected := map[string]interface{}{
+		"time":       float64(1700000000),
+		"host":       "web-1",
+		"source":     "auth",
+		"sourcetype": "otel:security",
+		"index":      "security",
+	}
+	for key, want := range expected {
+		if envelope[key] != want {
+			t.Errorf("%s = %v, want %v", key, envelope[key], want)
+		}
+	}
+	event, ok := envelope["event"].(map[string]interface{})
+	if !ok || event["user.name"] != "alice" {
+		t.Errorf("Unexpected event: %v", envelope["event"])
+	}
+	if fields, _ := envelope["fields"].(map[string]interface{}); fields["user.name"] != "alice" {
+		t.Errorf("Expected user.name indexed field, got %v", envelope["fields"])
+	}
+	if stub.channels[0] != "" {
+		t.Errorf("Expected no channel without acknowledgement, got %q", stub.channels[0])
+	}
+}
+
+func TestSplunkHECLineEncodingIsSentAsString(t *testing.T) {
+	stub := &hecStub{token: "00000000-0000-0000-0000-000000000001"}
+	exp := newTestHECExporter(t, stub, func(cfg *Config) {
+		cfg.Encoding = encodingCEF
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if line, _ := stub.events[0]["event"].(string); !strings.HasPrefix(line, "CEF:0|") {
+		t.Errorf("Expected CEF line event, got %v", stub.events[0]["event"])
+	}
+}
+
+func TestSplunkHECWaitsForAck(t *testing.T) {
+	stub := &hecStub{token: "00000000-0000-0000-0000-000000000001", ackAfter: 2}
+	exp := newTestHECExporter(t, stub, func(cfg *Config) {
+		cfg.SplunkHEC.UseAck = true
+		cfg.SplunkHEC.AckPollInterval = 10 * time.Millisecond
+		cfg.SplunkHEC.AckTimeout = 5 * time.Second
+		cfg.Headers = map[string]configopaque.String{"X-Tenant": "acme"}
+	})
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if stub.ackPolls != 3 {
+		t.Errorf("Expected 3 ack polls, got %d", stub.ackPolls)
+	}
+	for _, tenant := range stub.ackTenants {
+		if tenant != "acme" {
+			t.Errorf("Expected ack polls to carry the configured headers, got X-Tenant %q", tenant)
+		}
+	}
+	if got := exp.metrics.httpRequests.Load(); got != 4 {
+		t.Errorf("Expected the post and 3 ack polls to be counted, got %d requests", got)
+	}
+	if stub.channels[0] == "" {
+		t.Error("Expected a generated channel with acknowledgement enabled")
+	}
+	if exp.metrics.eventsExported.Load() != 1 || exp.metrics.eventsFailed.Load() != 0 {
+		t.Errorf("Unexpected accounting: exported=%d failed=%d", exp.metrics.eventsExported.Load(), exp.metrics.eventsFailed.Load())
+	}
+}
+
+func TestSplunkHECAckTimeoutFailsBatch(t *testing.T) {
+	stub := &hecStub{token: "00000000-0000-0000-0000-000000000001", neverAck: true}
+	exp := newTestHECExporter(t, stub, func(cfg *Config) {
+		cfg.SplunkHEC.UseAck = true
+		cfg.SplunkHEC.Channel = "11111111-2222-3333-4444-555555555555"
+		cfg.SplunkHEC.AckPollInterval = 10 * time.Millisecond
+		cfg.SplunkHEC.AckTimeout = 50 * time.Millisecond
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob"))
+	if err == nil || !strings.Contains(err.Error(), "not received") {
+		t.Fatalf("Expected ack timeout error, got %v", err)
+	}
+	if consumererror.IsPermanent(err) {
+		t.Error("Ack timeouts should be retryable")
+	}
+	if stub.channels[0] != "11111111-2222-3333-4444-555555555555" {
+		t.Errorf("Expected configured channel, got %q", stub.channels[0])
+	}
+	if exp.metrics.eventsFailed.Load() != 2 {
+		t.Errorf("Expected 2 failed events, got %d", exp.metrics.eventsFailed.Load())
+	}
+}
+
+func TestSplunkHECRejectedAckIsPermanent(t *testing.T) {
+	stub := &hecStub{token: "00000000-0000-0000-0000-000000000001", ackStatus: http.StatusBadRequest}
+	exp := newTestHECExporter(t, stub, func(cfg *Config) {
+		cfg.SplunkHEC.UseAck = true
+		cfg.SplunkHEC.AckPollInterval = 10 * time.Millisecond
+		cfg.SplunkHEC.AckTimeout = 5 * time.Second
+	})
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected permanent error for a rejected ack poll, got %v", err)
+	}
+	if stub.ackPolls != 1 {
+		t.Errorf("Expected polling to stop after the rejection, got %d polls", stub.ackPolls)
+	}
+	if got := exp.metrics.httpErrors.Load(); got != 1 {
+		t.Errorf("Expected the rejected poll to be counted as an HTTP error, got %d", got)
+	}
+}
+
+func TestSplunkHECInvalidTokenIsPermanent(t *testing.T) {
+	stub := &hecStub{token: "another-token"}
+	exp := newTestHECExporter(t, stub, nil)
+
+	err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil || !consumererror.IsPermanent(err) {
+		t.Errorf("Expected permanent error for rejected token, got %v", err)
+	}
+}
+
+func TestSplunkHECConfigValidate(t *testing.T) {
+	valid := createDefaultSplunkHECConfig()
+	valid.Token = "token"
+
+	tests := []struct {
+		name     string
+		modify   func(cfg *SplunkHECConfig)
+		errorMsg string
+	}{
+		{name: "valid", modify: func(*SplunkHECConfig) {}},
+		{name: "invalid channel", modify: func(cfg *SplunkHECConfig) { cfg.Channel = "soc" }, errorMsg: "must be a GUID"},
+		{name: "zero poll interval", modify: func(cfg *SplunkHECConfig) {
+			cfg.UseAck = true
+			cfg.AckPollInterval = 0
+		}, errorMsg: "ack_poll_interval"},
+		{name: "timeout shorter than interval", modify: func(cfg *SplunkHECConfig) {
+			cfg.UseAck = true
+			cfg.AckTimeout = time.Millisecond
+		}, errorMsg: "ack_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestSplunkHECDryRun(t *testing.T) {
+	var out strings.Builder
+	exp := newTestTransportExporter(t, transportSplunkHEC, "http://127.0.0.1:1/services/collector/event", func(cfg *Config) {
+		cfg.Mode = modeDryRun
+		cfg.SplunkHEC.Token = "secret"
+		cfg.SplunkHEC.UseAck = true
+	})
+	_ = exp.dryRun.close()
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var request dryRunRequest
+	if err := json.Unmarshal([]byte(out.String()), &request); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	if request.Headers["Authorization"] != maskedHeaderValue {
+		t.Errorf("Expected masked Authorization header, got %v", request.Headers)
+	}
+}
@@ -0,0 +1,380 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// maxPooledBufferSize is the capacity above which batch buffers are left to the garbage collector
+// instead of being pooled, so one unusually large batch does not stay in memory
+const maxPooledBufferSize = 16 << 20
+
+// batchBufferPool holds the buffers batches are streamed into
+var batchBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// batchBody is a serialized batch in a pooled buffer. The buffer is shared by the sender and
+// the request bodies reading it, including those replayed on redirects, and goes back to the pool
+// once all of them are done with it. A reference that is never released only costs the reuse.
+type batchBody struct {
+	buf  *bytes.Buffer
+	refs atomic.Int32
+}
+
+// newBatchBody takes a buffer from the pool, referenced by the caller
+func newBatchBody() *batchBody {
+	buf := batchBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	body := &batchBody{buf: buf}
+	body.refs.Store(1)
+	return body
+}
+
+// open returns a request body reading the batch
+func (b *batchBody) open() io.ReadCloser {
+	b.refs.Add(1)
+	return &batchBodyReader{Reader: bytes.NewReader(b.buf.Bytes()), body: b}
+}
+
+// release drops a reference, returning the buffer to the pool after the last one
+func (b *batchBody) release() {
+	if b.refs.Add(-1) != 0 {
+		return
+	}
+	if b.buf.Cap() <= maxPooledBufferSize {
+		batchBufferPool.Put(b.buf)
+	}
+	b.buf = nil
+}
+
+// batchBodyReader reads a batchBody and releases its reference when closed
+type batchBodyReader struct {
+	*bytes.Reader
+	body *batchBody
+	once sync.Once
+}
+
+func (r *batchBodyReader) Close() error {
+	r.once.Do(r.body.release)
+	return nil
+}
+
+// canStream reports whether batches can be streamed straight from the log records into the
+// request body. That is the case for the http transport with the json encoding and a JSON payload
+// format, unless a file tee or an archive needs the converted events as well or redaction rules
+// need to be applied to them.
+func (e *securityEventExporter) canStream() bool {
+	if e.transport != nil || e.encoder != nil || e.fileTee != nil || e.archive != nil || e.redactor != nil {
+		return false
+	}
+	switch e.config.PayloadFormat {
+	case "", payloadFormatJSONArray, payloadFormatNDJSON, payloadFormatJSONObjectWrapper:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldOrigin is where a field of a streamed event comes from, in increasing precedence
+type fieldOrigin uint8
+
+const (
+	originDefault fieldOrigin = iota
+	originResource
+	originLog
+	originRecord
+)
+
+// streamField is a field of a streamed event. Default attributes carry their marshaled JSON,
+// resource and log attributes their string value; record fields are rendered from the record.
+type streamField struct {
+	key    string
+	origin fieldOrigin
+	str    string
+	raw    []byte
+}
+
+// eventStreamer writes the flat events of log records as JSON, producing the same bytes as
+// marshaling the events of convertLogToSecurityEvent: the later of two fields with the same key
+// wins and keys are sorted. Its field slice is reused from one event to the next.
+type eventStreamer struct {
+	exp      *securityEventExporter
+	defaults []streamField
+	fields   []streamField
+	scratch  []byte
+}
+
+// newEventStreamer marshals the default attributes once for the events of a batch
+func (e *securityEventExporter) newEventStreamer() (*eventStreamer, error) {
+	s := &eventStreamer{exp: e, defaults: make([]streamField, 0, len(e.config.DefaultAttributes))}
+	for key, value := range e.config.DefaultAttributes {
+		if key == "source" {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal default attribute %q: %w", key, err)
+		}
+		s.defaults = append(s.defaults, streamField{key: key, origin: originDefault, raw: raw})
+	}
+	return s, nil
+}
+
+// writeEvent writes the event of logRecord to buf
+func (s *eventStreamer) writeEvent(buf *bytes.Buffer, logRecord plog.LogRecord, resource pcommon.Resource) {
+	fields := append(s.fields[:0], s.defaults...)
+	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
+		fields = append(fields, streamField{key: key, origin: originResource, str: value.AsString()})
+		return true
+	})
+	logRecord.Attributes().Range(func(key string, value pcommon.Value) bool {
+		fields = append(fields, streamField{key: key, origin: originLog, str: value.AsString()})
+		return true
+	})
+	fields = append(fields, streamField{key: "timestamp", origin: originRecord})
+	if !logRecord.TraceID().IsEmpty() {
+		fields = append(fields, streamField{key: "trace_id", origin: originRecord})
+	}
+	if !logRecord.SpanID().IsEmpty() {
+		fields = append(fields, streamField{key: "span_id", origin: originRecord})
+	}
+	// The sort is stable, so of the fields sharing a key the one with the highest precedence is last
+	slices.SortStableFunc(fields, func(a, b streamField) int {
+		return strings.Compare(a.key, b.key)
+	})
+	s.fields = fields
+
+	b := s.scratch[:0]
+	b = append(b, '{')
+	first := true
+	for i, field := range fields {
+		if i+1 < len(fields) && fields[i+1].key == field.key {
+			if next := fields[i+1]; next.origin == originLog && field.origin < originLog {
+				s.conflict(field, next)
+			}
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendJSONString(b, field.key)
+		b = append(b, ':')
+		switch field.origin {
+		case originDefault:
+			b = append(b, field.raw...)
+		case originRecord:
+			b = appendRecordField(b, field.key, logRecord)
+		default:
+			b = appendJSONString(b, field.str)
+		}
+	}
+	b = append(b, '}')
+	buf.Write(b)
+	s.scratch = b
+}
+
+// conflict reports a log attribute overwriting a default or resource attribute, like
+// convertLogToSecurityEvent does
+func (s *eventStreamer) conflict(existing, log streamField) {
+	if ce := s.exp.logger.Check(zap.WarnLevel, "Attribute key conflict detected"); ce != nil {
+		existingValue := existing.str
+		if existing.origin == originDefault {
+			existingValue = fmt.Sprintf("%v", s.exp.config.DefaultAttributes[existing.key])
+		}
+		ce.Write(zap.String("key", log.key),
+			zap.String("resource_value", existingValue),
+			zap.String("log_value", log.str),
+			zap.String("message", "Log attribute will overwrite resource attribute"))
+	}
+	s.exp.metrics.attributeConflicts.Add(1)
+}
+
+// appendRecordField appends the JSON value of the timestamp, trace_id or span_id record field
+func appendRecordField(b []byte, key string, logRecord plog.LogRecord) []byte {
+	b = append(b, '"')
+	switch key {
+	case "timestamp":
+		b = logRecord.Timestamp().AsTime().AppendFormat(b, time.RFC3339)
+	case "trace_id":
+		traceID := logRecord.TraceID()
+		b = hex.AppendEncode(b, traceID[:])
+	case "span_id":
+		spanID := logRecord.SpanID()
+		b = hex.AppendEncode(b, spanID[:])
+	}
+	return append(b, '"')
+}
+
+// consumeStreamedLogs is ConsumeLogs for non-empty batches that canStream: the events are
+// written straight from the log records into a pooled request body, without converting them to
+// maps
+func (e *securityEventExporter) consumeStreamedLogs(ctx context.Context, ld plog.Logs) error {
+	totalLogRecords := ld.LogRecordCount()
+	e.metrics.logsReceived.Add(int64(totalLogRecords))
+
+	body := newBatchBody()
+	defer body.release()
+	eventCount, err := e.streamLogs(ld, body.buf)
+	if err != nil {
+		e.logger.Error("Failed to marshal security event batch",
+			zap.Error(err),
+			zap.Int("event_count", totalLogRecords))
+		e.metrics.httpErrors.Add(1)
+		e.metrics.eventsFailed.Add(int64(totalLogRecords))
+		return consumererror.NewPermanent(fmt.Errorf("failed to marshal security event batch: %w", err))
+	}
+
+	contentType := "application/json"
+	if e.config.PayloadFormat == payloadFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if _, err := e.postBody(ctx, e.config.Endpoint, body.buf.Bytes(), body.open, header, eventCount); err != nil {
+		e.logger.Error("Failed to send security event batch",
+			zap.Error(err),
+			zap.Int("event_count", eventCount),
+			zap.String("endpoint", e.config.Endpoint))
+		e.metrics.eventsFailed.Add(int64(eventCount))
+		return err
+	}
+	e.metrics.eventsExported.Add(int64(eventCount))
+
+	e.logger.Info("Completed processing logs batch",
+		zap.Int("total_resource_logs", ld.ResourceLogs().Len()),
+		zap.Int("total_log_records", totalLogRecords),
+		zap.Int("successful_events", eventCount),
+		zap.Int("failed_events", 0),
+		zap.Int("http_requests", 1))
+	return nil
+}
+
+// streamLogs writes the events of every log record in ld to buf in the configured JSON payload
+// format, in record order, streaming the chunks of large batches in parallel when conversion
+// workers are configured. It returns the number of events written.
+func (e *securityEventExporter) streamLogs(ld plog.Logs, buf *bytes.Buffer) (int, error) {
+	streamer, err := e.newEventStreamer()
+	if err != nil {
+		return 0, err
+	}
+
+	ndjson := e.config.PayloadFormat == payloadFormatNDJSON
+	wrapped := e.config.PayloadFormat == payloadFormatJSONObjectWrapper
+	if wrapped {
+		buf.WriteByte('{')
+		buf.Write(appendJSONString(buf.AvailableBuffer(), e.config.PayloadWrapperKey))
+		buf.WriteByte(':')
+	}
+	if !ndjson {
+		buf.WriteByte('[')
+	}
+	chunks := e.conversionChunks(ld)
+	if e.conversion == nil || len(chunks) == 1 {
+		for i, chunk := range chunks {
+			if i > 0 && !ndjson {
+				buf.WriteByte(',')
+			}
+			streamer.streamChunk(buf, chunk, ndjson)
+		}
+	} else {
+		// Every chunk is streamed into a buffer of its own, then the buffers are joined in order
+		buffers := make([]*bytes.Buffer, len(chunks))
+		e.conversion.run(len(chunks), func(i int) {
+			buffers[i] = batchBufferPool.Get().(*bytes.Buffer)
+			buffers[i].Reset()
+			chunkStreamer := &eventStreamer{exp: e, defaults: streamer.defaults}
+			chunkStreamer.streamChunk(buffers[i], chunks[i], ndjson)
+		})
+		for i, chunkBuf := range buffers {
+			if i > 0 && !ndjson {
+				buf.WriteByte(',')
+			}
+			buf.Write(chunkBuf.Bytes())
+			if chunkBuf.Cap() <= maxPooledBufferSize {
+				batchBufferPool.Put(chunkBuf)
+			}
+		}
+	}
+	if !ndjson {
+		buf.WriteByte(']')
+	}
+	if wrapped {
+		buf.WriteByte('}')
+	}
+	return ld.LogRecordCount(), nil
+}
+
+// jsonHex are the digits of \u escapes
+const jsonHex = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string escaped the way encoding/json does: control
+// characters, quotes, backslashes, the HTML characters <, > and &, and U+2028 and U+2029 are
+// escaped, and invalid UTF-8 is replaced by U+FFFD
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', jsonHex[c>>4], jsonHex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', jsonHex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,300 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// newStreamTestExporter creates an exporter for the default http transport without starting it
+func newStreamTestExporter(configure func(cfg *Config)) *securityEventExporter {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = "http://localhost:8080/security-events"
+	if configure != nil {
+		configure(cfg)
+	}
+	return &securityEventExporter{
+		config:  cfg,
+		logger:  zap.NewNop(),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		metrics: &exporterMetrics{},
+	}
+}
+
+// newStreamTestLogs creates logs exercising every kind of field: typed and conflicting
+// attributes, characters that need escaping, and trace context on some records
+func newStreamTestLogs() plog.Logs {
+	logs := plog.NewLogs()
+	for i, service := range []string{"auth", "<gateway> & \"proxy\""} {
+		resourceLogs := logs.ResourceLogs().AppendEmpty()
+		resourceLogs.Resource().Attributes().PutStr("service.name", service)
+		resourceLogs.Resource().Attributes().PutStr("environment", "resource-env")
+		resourceLogs.Resource().Attributes().PutInt("host.cpu.count", 8)
+		scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+		for j := 0; j < 3; j++ {
+			record := scopeLogs.LogRecords().AppendEmpty()
+			record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20+j, 0, time.UTC)))
+			record.Attributes().PutStr("user.name", fmt.Sprintf("user-%d-%d\n\t\u2028é\x01", i, j))
+			record.Attributes().PutBool("authenticated", j%2 == 0)
+			record.Attributes().PutDouble("risk.score", 7.25)
+			record.Attributes().PutEmptyBytes("raw").FromRaw([]byte{0xff, 0x00})
+			record.Attributes().PutEmptySlice("tags").AppendEmpty().SetStr("<b>")
+			record.Attributes().PutEmptyMap("geo").PutStr("country", "FR")
+			record.Attributes().PutStr("bad.utf8", "a\xffb")
+			if j == 1 {
+				record.Attributes().PutStr("service.name", "overridden")
+				record.Attributes().PutStr("timestamp", "not-a-timestamp")
+				record.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+				record.SetSpanID(pcommon.SpanID{0xa, 0xb, 0xc, 0xd, 0xe, 0xf, 0, 1})
+			}
+			if j == 2 {
+				record.Attributes().PutStr("environment", "log-env")
+			}
+		}
+	}
+	return logs
+}
+
+func TestAppendJSONString(t *testing.T) {
+	values := []string{
+		"",
+		"plain",
+		`quote " backslash \ slash /`,
+		"<script>alert('x') && y</script>",
+		"\x00\x01\x1f\x7f\b\f\n\r\t",
+		"é ü 日本 🔐",
+		"line\u2028para\u2029end",
+		"invalid \xff\xfe utf-8 \xc3",
+	}
+	for _, value := range values {
+		want, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := appendJSONString(nil, value); !bytes.Equal(got, want) {
+			t.Errorf("appendJSONString(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestStreamLogsMatchesMarshaledEvents(t *testing.T) {
+	for _, format := range []string{payloadFormatJSONArray, payloadFormatNDJSON, payloadFormatJSONObjectWrapper} {
+		t.Run(format, func(t *testing.T) {
+			exp := newStreamTestExporter(func(cfg *Config) {
+				cfg.PayloadFormat = format
+				cfg.DefaultAttributes = map[string]interface{}{
+					"source":      "opentelemetry-collector",
+					"environment": "default-env",
+					"retention":   30,
+					"enabled":     true,
+					"labels":      map[string]interface{}{"team": "<sec>"},
+				}
+			})
+			ld := newStreamTestLogs()
+
+			events, _, _, _ := exp.convertLogs(ld)
+			want, _, err := exp.marshalBatch(events)
+			if err != nil {
+				t.Fatalf("marshalBatch() returned error: %v", err)
+			}
+			conflicts := exp.metrics.attributeConflicts.Load()
+			exp.metrics.attributeConflicts.Store(0)
+
+			var buf bytes.Buffer
+			count, err := exp.streamLogs(ld, &buf)
+			if err != nil {
+				t.Fatalf("streamLogs() returned error: %v", err)
+			}
+			if count != len(events) {
+				t.Errorf("streamLogs() wrote %d events, want %d", count, len(events))
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Streamed batch differs from the marshaled events:\n got: %s\nwant: %s", buf.Bytes(), want)
+			}
+			if exp.metrics.attributeConflicts.Load() != conflicts {
+				t.Errorf("attributeConflicts = %d, want %d", exp.metrics.attributeConflicts.Load(), conflicts)
+			}
+		})
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(exp *securityEventExporter)
+		want      bool
+	}{
+		{name: "json array", configure: func(*securityEventExporter) {}, want: true},
+		{name: "ndjson", configure: func(exp *securityEventExporter) {
+			exp.config.PayloadFormat = payloadFormatNDJSON
+		}, want: true},
+		{name: "protobuf", configure: func(exp *securityEventExporter) {
+			exp.config.PayloadFormat = payloadFormatProtobuf
+		}},
+		{name: "encoding", configure: func(exp *securityEventExporter) {
+			exp.encoder = &ecsEncoder{}
+		}},
+		{name: "transport", configure: func(exp *securityEventExporter) {
+			exp.transport = &cloudEventsTransport{}
+		}},
+		{name: "file tee", configure: func(exp *securityEventExporter) {
+			exp.fileTee = &fileSink{}
+		}},
+		{name: "archive", configure: func(exp *securityEventExporter) {
+			exp.archive = &archiveSink{}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := newStreamTestExporter(nil)
+			tt.configure(exp)
+			if got := exp.canStream(); got != tt.want {
+				t.Errorf("canStream() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamedBatchFollowsRedirect(t *testing.T) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("ContentLength = %d, want %d", r.ContentLength, len(body))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	exp := newTestTransportExporter(t, transportHTTP, server.URL+"/old", nil)
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	if len(bodies) != 2 || !bytes.Equal(bodies[0], bodies[1]) {
+		t.Fatalf("Expected the batch to be posted again after the redirect, got %q", bodies)
+	}
+	var events []map[string]interface{}
+	if err := json.Unmarshal(bodies[1], &events); err != nil || len(events) != 2 {
+		t.Errorf("Unexpected batch %s: %v", bodies[1], err)
+	}
+	if exp.metrics.eventsExported.Load() != 2 || exp.metrics.logsReceived.Load() != 2 {
+		t.Errorf("eventsExported = %d, logsReceived = %d, want 2 and 2", exp.metrics.eventsExported.Load(), exp.metrics.logsReceived.Load())
+	}
+}
+
+func TestBatchBodyRelease(t *testing.T) {
+	body := newBatchBody()
+	body.buf.WriteString(`[{"user.name":"alice"}]`)
+	first, second := body.open(), body.open()
+
+	body.release()
+	_ = first.Close()
+	_ = first.Close()
+	if body.buf == nil {
+		t.Fatal("Buffer returned to the pool while a request body still reads it")
+	}
+	data, _ := io.ReadAll(second)
+	if string(data) != `[{"user.name":"alice"}]` {
+		t.Errorf("Unexpected body %q", data)
+	}
+	_ = second.Close()
+	if body.buf != nil {
+		t.Error("Expected the buffer to be returned to the pool after the last reference")
+	}
+}
+
+// benchmarkBatchSizes are the batch sizes of the encoding benchmarks
+var benchmarkBatchSizes = []int{100, 1000}
+
+// newBenchmarkLogs creates a batch of events with attributes typical of authentication logs
+func newBenchmarkLogs(events int) plog.Logs {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	resource := resourceLogs.Resource().Attributes()
+	resource.PutStr("service.name", "auth")
+	resource.PutStr("host.name", "web-1")
+	resource.PutStr("k8s.namespace.name", "security")
+	resource.PutStr("k8s.pod.name", "auth-7d9f8b6c4-x2x7q")
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	for i := 0; i < events; i++ {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20, i, time.UTC)))
+		record.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		record.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+		attributes := record.Attributes()
+		attributes.PutStr("event.category", "authentication")
+		attributes.PutStr("event.action", "login")
+		attributes.PutStr("event.outcome", "failure")
+		attributes.PutStr("user.name", fmt.Sprintf("user-%d", i))
+		attributes.PutStr("source.ip", "203.0.113.7")
+		attributes.PutInt("source.port", 52344)
+		attributes.PutStr("user_agent.original", "Mozilla/5.0 (X11; Linux x86_64)")
+		attributes.PutStr("http.request.method", "POST")
+		attributes.PutStr("url.path", "/api/v1/login")
+		attributes.PutInt("http.response.status_code", 401)
+	}
+	return logs
+}
+
+// reportAllocsPerEvent reports the heap allocations per event since before
+func reportAllocsPerEvent(b *testing.B, before *runtime.MemStats, events int) {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N*events), "allocs/event")
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*events), "B/event")
+}
+
+// BenchmarkBatchEncoding compares converting batches to maps and marshaling them with streaming
+// them straight from the log records into a pooled buffer
+func BenchmarkBatchEncoding(b *testing.B) {
+	for _, events := range benchmarkBatchSizes {
+		ld := newBenchmarkLogs(events)
+		exp := newStreamTestExporter(nil)
+
+		b.Run(fmt.Sprintf("maps/events=%d", events), func(b *testing.B) {
+			b.ReportAllocs()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				securityEvents, _, _, _ := exp.convertLogs(ld)
+				data, _, err := exp.marshalBatch(securityEvents)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+			}
+			reportAllocsPerEvent(b, &before, events)
+		})
+
+		b.Run(fmt.Sprintf("streaming/events=%d", events), func(b *testing.B) {
+			b.ReportAllocs()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				body := newBatchBody()
+				if _, err := exp.streamLogs(ld, body.buf); err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(body.buf.Len()))
+				body.release()
+			}
+			reportAllocsPerEvent(b, &before, events)
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configtls"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+const (
+	// syslogRFC5424 formats messages per RFC 5424
+	syslogRFC5424 = "rfc5424"
+
+	// syslogRFC3164 formats messages per the BSD syslog format of RFC 3164
+	syslogRFC3164 = "rfc3164"
+
+	// syslogOctetCounting frames TCP and TLS messages as "LEN SP MSG" (RFC 6587 section 3.4.1)
+	syslogOctetCounting = "octet_counting"
+
+	// syslogNonTransparent terminates TCP and TLS messages with a newline (RFC 6587 section 3.4.2)
+	syslogNonTransparent = "non_transparent"
+)
+
+// syslogFacilities maps facility names to their codes
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverities maps OpenTelemetry severity families to syslog severities
+var syslogSeverities = map[string]int{
+	"FATAL": 2, // critical
+	"ERROR": 3, // error
+	"WARN":  4, // warning
+	"INFO":  6, // informational
+	"DEBUG": 7, // debug
+	"TRACE": 7, // debug
+}
+
+// SyslogConfig configures the syslog transport. The endpoint selects the network with its
+// scheme: udp://host:514, tcp://host:601 or tls://host:6514.
+type SyslogConfig struct {
+	// Protocol is the message format: "rfc5424" (default) or "rfc3164"
+	Protocol string `mapstructure:"protocol"`
+
+	// Framing delimits messages on TCP and TLS connections: "octet_counting" (default) or
+	// "non_transparent". UDP sends one message per datagram.
+	Framing string `mapstructure:"framing"`
+
+	// Facility is the facility name, for example "auth", "authpriv" or "local0" (default)
+	Facility string `mapstructure:"facility"`
+
+	// AppName is the APP-NAME (RFC 5424) or TAG (RFC 3164) of each message
+	AppName FieldSource `mapstructure:"app_name"`
+
+	// Hostname is the HOSTNAME of each message; the collector host name is used when it
+	// resolves to an empty value
+	Hostname FieldSource `mapstructure:"hostname"`
+
+	// MsgID is the RFC 5424 MSGID of each message; "-" when it resolves to an empty value
+	MsgID FieldSource `mapstructure:"msg_id"`
+
+	// SeverityMap overrides the syslog severity (0-7) per OpenTelemetry severity family, for
+	// example WARN: 5
+	SeverityMap map[string]int `mapstructure:"severity_map"`
+
+	// TLS configures the client certificates and verification of tls:// endpoints
+	TLS configtls.ClientConfig `mapstructure:"tls"`
+}
+
+// Validate checks the protocol, framing, facility and severity overrides
+func (cfg *SyslogConfig) Validate() error {
+	var errs []error
+	switch cfg.Protocol {
+	case "", syslogRFC5424, syslogRFC3164:
+	default:
+		errs = append(errs, fmt.Errorf("invalid syslog protocol %q: must be %q or %q", cfg.Protocol, syslogRFC5424, syslogRFC3164))
+	}
+	switch cfg.Framing {
+	case "", syslogOctetCounting, syslogNonTransparent:
+	default:
+		errs = append(errs, fmt.Errorf("invalid syslog framing %q: must be %q or %q", cfg.Framing, syslogOctetCounting, syslogNonTransparent))
+	}
+	if _, ok := syslogFacilities[cfg.Facility]; !ok && cfg.Facility != "" {
+		names := make([]string, 0, len(syslogFacilities))
+		for name := range syslogFacilities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs = append(errs, fmt.Errorf("invalid syslog facility %q: must be one of %s", cfg.Facility, strings.Join(names, ", ")))
+	}
+	if err := validateSeverityMap(cfg.SeverityMap, 0, 7); err != nil {
+		errs = append(errs, fmt.Errorf("syslog: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// createDefaultSyslogConfig creates the default syslog settings
+func createDefaultSyslogConfig() SyslogConfig {
+	return SyslogConfig{
+		Protocol: syslogRFC5424,
+		Framing:  syslogOctetCounting,
+		Facility: "local0",
+		AppName:  FieldSource{Attributes: []string{"service.name"}, Value: "otelcol"},
+		Hostname: FieldSource{Attributes: []string{"host.name"}},
+	}
+}
+
+// parseSyslogEndpoint returns the network and address of a udp://, tcp:// or tls:// endpoint
+func parseSyslogEndpoint(endpoint string) (string, string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid syslog endpoint %q: %w", endpoint, err)
+	}
+	switch u.Scheme {
+	case "udp", "tcp", "tls":
+	default:
+		return "", "", fmt.Errorf("invalid syslog endpoint %q: scheme must be udp, tcp or tls", endpoint)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return "", "", fmt.Errorf("invalid syslog endpoint %q: host and port are required", endpoint)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// syslogTransport sends each security event as a syslog message. TCP and TLS connections are
+// kept open across batches and re-established when a write fails.
+type syslogTransport struct {
+	exp      *securityEventExporter
+	config   *SyslogConfig
+	network  string
+	address  string
+	facility int
+	hostname string
+
+	tlsConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newSyslogTransport creates the syslog transport of e
+func newSyslogTransport(e *securityEventExporter) (*syslogTransport, error) {
+	cfg := &e.config.Syslog
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	network, address, err := parseSyslogEndpoint(e.config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	facility, ok := syslogFacilities[cfg.Facility]
+	if !ok {
+		facility = syslogFacilities["local0"]
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	return &syslogTransport{
+		exp:      e,
+		config:   cfg,
+		network:  network,
+		address:  address,
+		facility: facility,
+		hostname: hostname,
+	}, nil
+}
+
+// start loads the TLS configuration. Connections are opened by the first send, so the
+// collector starts even while the syslog server is unreachable.
+func (t *syslogTransport) start(ctx context.Context, _ component.Host) error {
+	if t.network != "tls" {
+		return nil
+	}
+	tlsConfig, err := t.config.TLS.LoadTLSConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load syslog TLS configuration: %w", err)
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(t.address)
+	}
+	t.tlsConfig = tlsConfig
+	return nil
+}
+
+// send writes one syslog message per event. A failed write is retried once on a new
+// connection; if that fails too, the events not yet written are returned as a retryable error.
+// Events whose message cannot be formatted are dropped.
+func (t *syslogTransport) send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error {
+	frames := make([][]byte, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	var dropped []int
+	var droppedErr error
+	for i, event := range events {
+		message, err := t.formatMessage(event, sources[i])
+		if err != nil {
+			t.exp.metrics.httpErrors.Add(1)
+			dropped, droppedErr = append(dropped, i), consumererror.NewPermanent(fmt.Errorf("failed to format syslog message: %w", err))
+			continue
+		}
+		frames = append(frames, t.frame(message))
+		indexes = append(indexes, i)
+	}
+
+	if t.exp.dryRun != nil {
+		if len(frames) > 0 {
+			if err := t.exp.dryRun.writeRecord("SYSLOG", t.exp.config.Endpoint, nil, bytes.Join(frames, nil), len(frames)); err != nil {
+				return err
+			}
+		}
+	} else {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for n, frame := range frames {
+			if err := t.write(ctx, frame); err != nil {
+				t.exp.metrics.httpErrors.Add(1)
+				err = fmt.Errorf("failed to send syslog message to %s: %w", t.address, err)
+				// The messages before the failed one were written and are not retried
+				if len(dropped) == 0 {
+					return unsentError(err, n, len(events))
+				}
+				return &partialSendError{err: err, failed: indexes[n:], dropped: dropped}
+			}
+		}
+	}
+
+	if len(dropped) > 0 {
+		return newPartialSendError(droppedErr, dropped)
+	}
+	t.exp.logger.Debug("Sent security events as syslog messages",
+		zap.String("endpoint", t.exp.config.Endpoint),
+		zap.Int("event_count", len(events)))
+	return nil
+}
+
+// shutdown closes the open connection, if any
+func (t *syslogTransport) shutdown(context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// write writes frame to the connection, reconnecting once when the connection is missing or
+// the write fails. t.mu must be held.
+func (t *syslogTransport) write(ctx context.Context, frame []byte) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if t.conn == nil {
+			if t.conn, err = t.dial(ctx); err != nil {
+				return err
+			}
+		}
+		_ = t.conn.SetWriteDeadline(time.Now().Add(t.exp.config.requestTimeout()))
+		if _, err = t.conn.Write(frame); err == nil {
+			return nil
+		}
+
+		t.exp.logger.Warn("Syslog write failed, reconnecting",
+			zap.String("endpoint", t.exp.config.Endpoint),
+			zap.Error(err))
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+	return err
+}
+
+// dial opens a connection to the syslog server
+func (t *syslogTransport) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: t.exp.config.requestTimeout()}
+	if t.network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: t.tlsConfig}
+		return tlsDialer.DialContext(ctx, "tcp", t.address)
+	}
+	return dialer.DialContext(ctx, t.network, t.address)
+}
+
+// frame applies the configured TCP framing to message; UDP datagrams are not framed
+func (t *syslogTransport) frame(message []byte) []byte {
+	if t.network == "udp" {
+		return message
+	}
+	if t.config.Framing == syslogNonTransparent {
+		return append(message, '\n')
+	}
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+// formatMessage formats event as a syslog message whose MSG is the event rendered with the
+// exporter's encoding: a CEF or LEEF line, or compact JSON
+func (t *syslogTransport) formatMessage(event map[string]interface{}, source eventSource) ([]byte, error) {
+	var msg []byte
+	if lines, ok := t.exp.encoder.(lineEncoder); ok {
+		line, err := lines.renderLine(event)
+		if err != nil {
+			return nil, err
+		}
+		msg = []byte(line)
+	} else {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal security event: %w", err)
+		}
+		msg = data
+	}
+
+	priority := t.facility*8 + syslogSeverity(source.severity, t.config.SeverityMap)
+	hostname := t.config.Hostname.resolve(source.attributes)
+	if hostname == "" {
+		hostname = t.hostname
+	}
+	appName := t.config.AppName.resolve(source.attributes)
+	procID := stringField(source.attributes, "process.pid")
+
+	var header string
+	if t.config.Protocol == syslogRFC3164 {
+		tag := syslogHeaderField(appName, 32)
+		if procID != "" {
+			tag += "[" + syslogHeaderField(procID, 10) + "]"
+		}
+		header = fmt.Sprintf("<%d>%s %s %s: ", priority,
+			source.time.UTC().Format(time.Stamp), syslogHeaderField(hostname, 255), tag)
+	} else {
+		header = fmt.Sprintf("<%d>1 %s %s %s %s %s - ", priority,
+			source.time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			syslogHeaderField(hostname, 255), syslogHeaderField(appName, 48),
+			syslogHeaderField(procID, 128), syslogHeaderField(t.config.MsgID.resolve(source.attributes), 32))
+	}
+	return append([]byte(header), msg...), nil
+}
+
+// syslogSeverity maps an OpenTelemetry severity number to a syslog severity. Unspecified
+// severities are sent as notice (5).
+func syslogSeverity(number plog.SeverityNumber, overrides map[string]int) int {
+	family := severityFamily(number)
+	if severity, ok := overrides[family]; ok {
+		return severity
+	}
+	if severity, ok := syslogSeverities[family]; ok {
+		return severity
+	}
+	return 5
+}
+
+// syslogHeaderField makes value a valid header field: printable US-ASCII without spaces, at
+// most maxLen characters, and "-" when empty
+func syslogHeaderField(value string, maxLen int) string {
+	if value == "" {
+		return "-"
+	}
+	var b strings.Builder
+	for i := 0; i < len(value) && b.Len() < maxLen; i++ {
+		if c := value[i]; c >= 33 && c <= 126 {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,397 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newTestSyslogExporter creates an exporter using the syslog transport for endpoint
+func newTestSyslogExporter(t *testing.T, endpoint string, configure func(cfg *Config)) *securityEventExporter {
+	t.Helper()
+	return newTestTransportExporter(t, transportSyslog, endpoint, configure)
+}
+
+// newSyslogTestLogs creates logs with one record per user
+func newSyslogTestLogs(users ...string) plog.Logs {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("host.name", "web-1")
+	resourceLogs.Resource().Attributes().PutStr("service.name", "auth")
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	for _, user := range users {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)))
+		record.SetSeverityNumber(plog.SeverityNumberWarn)
+		record.Attributes().PutStr("user.name", user)
+	}
+	return logs
+}
+
+// readOctetCounted reads one octet-counted frame from r
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatalf("Failed to read frame length: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		t.Fatalf("Invalid frame length %q: %v", length, err)
+	}
+	message := make([]byte, n)
+	if _, err := io.ReadFull(r, message); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	return string(message)
+}
+
+// acceptOne returns a reader for the next connection accepted on listener
+func acceptOne(t *testing.T, listener net.Listener) <-chan *bufio.Reader {
+	t.Helper()
+	readers := make(chan *bufio.Reader, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(readers)
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+		// TLS servers complete the handshake on first use; the client waits for it while dialing
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			_ = tlsConn.Handshake()
+		}
+		readers <- bufio.NewReader(conn)
+	}()
+	return readers
+}
+
+func TestSyslogFormatRFC5424(t *testing.T) {
+	exp := newTestSyslogExporter(t, "tcp://127.0.0.1:601", func(cfg *Config) {
+		cfg.Syslog.Facility = "auth"
+		cfg.Syslog.MsgID = FieldSource{Attributes: []string{"event.action"}}
+	})
+	transport := exp.transport.(*syslogTransport)
+
+	event := map[string]interface{}{"host.name": "web 1", "service.name": "auth", "event.action": "login", "process.pid": "42"}
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 14, 22, 13, 20, 5000, time.UTC)))
+	record.SetSeverityNumber(plog.SeverityNumberError)
+
+	message, err := transport.formatMessage(event, newEventSource(event, record))
+	if err != nil {
+		t.Fatalf("formatMessage() returned error: %v", err)
+	}
+
+	// auth (4) * 8 + error (3)
+	expected := `<35>1 2023-11-14T22:13:20.000005Z web_1 auth 42 login - {"event.action":"login","host.name":"web 1","process.pid":"42","service.name":"auth"}`
+	if string(message) != expected {
+		t.Errorf("formatMessage() =\n%s\nwant\n%s", message, expected)
+	}
+}
+
+func TestSyslogFormatRFC3164WithCEF(t *testing.T) {
+	exp := newTestSyslogExporter(t, "udp://127.0.0.1:514", func(cfg *Config) {
+		cfg.Syslog.Protocol = syslogRFC3164
+		cfg.Encoding = encodingCEF
+	})
+	transport := exp.transport.(*syslogTransport)
+
+	flat := map[string]interface{}{"host.name": "web-1", "user.name": "alice"}
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2023, 11, 4, 22, 13, 20, 0, time.UTC)))
+	record.SetSeverityText("WARN")
+	event, err := exp.encoder.encode(flat, record, pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	message, err := transport.formatMessage(event, newEventSource(flat, record))
+	if err != nil {
+		t.Fatalf("formatMessage() returned error: %v", err)
+	}
+
+	// local0 (16) * 8 + warning (4)
+	prefix := "<132>Nov  4 22:13:20 web-1 otelcol: CEF:0|OpenTelemetry|"
+	if !strings.HasPrefix(string(message), prefix) {
+		t.Errorf("formatMessage() = %s, want prefix %s", message, prefix)
+	}
+	if !strings.Contains(string(message), "suser=alice") {
+		t.Errorf("Expected CEF extension in %s", message)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		number    plog.SeverityNumber
+		overrides map[string]int
+		expected  int
+	}{
+		{number: plog.SeverityNumberUnspecified, expected: 5},
+		{number: plog.SeverityNumberTrace, expected: 7},
+		{number: plog.SeverityNumberInfo, expected: 6},
+		{number: plog.SeverityNumberWarn, expected: 4},
+		{number: plog.SeverityNumberError4, expected: 3},
+		{number: plog.SeverityNumberFatal, expected: 2},
+		{number: plog.SeverityNumberInfo, overrides: map[string]int{"INFO": 5}, expected: 5},
+	}
+
+	for _, tt := range tests {
+		if got := syslogSeverity(tt.number, tt.overrides); got != tt.expected {
+			t.Errorf("syslogSeverity(%v, %v) = %d, want %d", tt.number, tt.overrides, got, tt.expected)
+		}
+	}
+}
+
+func TestSyslogTCPOctetCountingReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	exp := newTestSyslogExporter(t, "tcp://"+listener.Addr().String(), nil)
+	first := acceptOne(t, listener)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	reader := <-first
+	for _, user := range []string{"alice", "bob"} {
+		message := readOctetCounted(t, reader)
+		if !strings.HasPrefix(message, "<132>1 2023-11-14T22:13:20.000000Z web-1 auth - - - {") {
+			t.Errorf("Unexpected message header: %s", message)
+		}
+		if !strings.Contains(message, `"user.name":"`+user+`"`) {
+			t.Errorf("Expected event for %s, got %s", user, message)
+		}
+	}
+
+	// A broken connection is replaced on the next write
+	transport := exp.transport.(*syslogTransport)
+	transport.conn.Close()
+	second := acceptOne(t, listener)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("carol")); err != nil {
+		t.Fatalf("ConsumeLogs() after connection loss returned error: %v", err)
+	}
+	if message := readOctetCounted(t, <-second); !strings.Contains(message, "carol") {
+		t.Errorf("Expected event for carol after reconnecting, got %s", message)
+	}
+}
+
+func TestSyslogTLSNonTransparent(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: server.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	exp := newTestSyslogExporter(t, "tls://"+listener.Addr().String(), func(cfg *Config) {
+		cfg.Syslog.Framing = syslogNonTransparent
+		cfg.Syslog.TLS.InsecureSkipVerify = true
+		cfg.Encoding = encodingLEEF
+	})
+	accepted := acceptOne(t, listener)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	reader := <-accepted
+	for _, user := range []string{"alice", "bob"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		if !strings.Contains(line, "LEEF:2.0|") || !strings.Contains(line, "usrName="+user) {
+			t.Errorf("Unexpected message for %s: %q", user, line)
+		}
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	exp := newTestSyslogExporter(t, "udp://"+conn.LocalAddr().String(), nil)
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read datagram: %v", err)
+	}
+	datagram := string(buf[:n])
+	if !strings.HasPrefix(datagram, "<132>1 ") || !strings.HasSuffix(datagram, "}") {
+		t.Errorf("Unexpected datagram: %q", datagram)
+	}
+}
+
+func TestSyslogUnreachableIsRetryable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	exp := newTestSyslogExporter(t, endpoint, nil)
+	err = exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice"))
+	if err == nil {
+		t.Fatal("Expected error for unreachable syslog server")
+	}
+	if exp.metrics.eventsFailed.Load() != 1 {
+		t.Errorf("Expected 1 failed event, got %d", exp.metrics.eventsFailed.Load())
+	}
+}
+
+// failAfterConn is a connection whose writes fail once it has accepted writes messages
+type failAfterConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *failAfterConn) Write(p []byte) (int, error) {
+	if c.writes == 0 {
+		return 0, errors.New("connection reset")
+	}
+	c.writes--
+	return len(p), nil
+}
+
+func TestSyslogRetriesOnlyUnwrittenMessages(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	exp := newTestSyslogExporter(t, endpoint, nil)
+	client, server := net.Pipe()
+	defer server.Close()
+	// The first message is written, the second fails and reconnecting is refused
+	exp.transport.(*syslogTransport).conn = &failAfterConn{Conn: client, writes: 1}
+
+	err = exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice", "bob", "carol"))
+	var logsErr consumererror.Logs
+	if !errors.As(err, &logsErr) {
+		t.Fatalf("Expected a retryable partial failure, got %v", err)
+	}
+	retried := logsErr.Data()
+	if retried.LogRecordCount() != 2 {
+		t.Fatalf("Expected 2 records to be retried, got %d", retried.LogRecordCount())
+	}
+	for i, user := range []string{"bob", "carol"} {
+		record := retried.ResourceLogs().At(i).ScopeLogs().At(0).LogRecords().At(0)
+		if name, _ := record.Attributes().Get("user.name"); name.Str() != user {
+			t.Errorf("Retried record %d is for %q, want %q", i, name.Str(), user)
+		}
+	}
+	if exp.metrics.eventsExported.Load() != 1 || exp.metrics.eventsFailed.Load() != 2 {
+		t.Errorf("Unexpected metrics: exported=%d failed=%d", exp.metrics.eventsExported.Load(), exp.metrics.eventsFailed.Load())
+	}
+}
+
+func TestSyslogDropsUnformattableEvents(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	exp := newTestSyslogExporter(t, "tcp://"+listener.Addr().String(), nil)
+	accepted := acceptOne(t, listener)
+	events := []map[string]interface{}{
+		{"user.name": "alice"},
+		{"user.name": "bob", "risk.score": math.NaN()},
+	}
+	sources := []eventSource{newEventSource(events[0], plog.NewLogRecord()), newEventSource(events[1], plog.NewLogRecord())}
+
+	err = exp.transport.send(context.Background(), events, sources)
+	var partial *partialSendError
+	if !errors.As(err, &partial) || !consumererror.IsPermanent(err) {
+		t.Fatalf("Expected a permanent partial failure, got %v", err)
+	}
+	if len(partial.failed) != 1 || partial.failed[0] != 1 {
+		t.Errorf("Expected only event 1 to be dropped, got %v", partial.failed)
+	}
+	if message := readOctetCounted(t, <-accepted); !strings.Contains(message, "alice") {
+		t.Errorf("Expected the event for alice to be sent, got %s", message)
+	}
+}
+
+func TestSyslogConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		syslog   SyslogConfig
+		errorMsg string
+	}{
+		{name: "defaults", endpoint: "tcp://siem:601", syslog: createDefaultSyslogConfig()},
+		{name: "http endpoint", endpoint: "https://siem:601", syslog: createDefaultSyslogConfig(), errorMsg: "scheme must be udp, tcp or tls"},
+		{name: "missing port", endpoint: "tls://siem", syslog: createDefaultSyslogConfig(), errorMsg: "host and port are required"},
+		{name: "invalid protocol", endpoint: "udp://siem:514", syslog: SyslogConfig{Protocol: "rfc3339"}, errorMsg: "invalid syslog protocol"},
+		{name: "invalid framing", endpoint: "udp://siem:514", syslog: SyslogConfig{Framing: "newline"}, errorMsg: "invalid syslog framing"},
+		{name: "invalid facility", endpoint: "udp://siem:514", syslog: SyslogConfig{Facility: "local9"}, errorMsg: "invalid syslog facility"},
+		{name: "invalid severity", endpoint: "udp://siem:514", syslog: SyslogConfig{SeverityMap: map[string]int{"ERROR": 8}}, errorMsg: "between 0 and 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Transport = transportSyslog
+			cfg.Endpoint = tt.endpoint
+			cfg.Syslog = tt.syslog
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestSyslogDryRun(t *testing.T) {
+	var out strings.Builder
+	exp := newTestSyslogExporter(t, "tcp://127.0.0.1:1", nil)
+	exp.dryRun = &dryRunWriter{out: &out}
+
+	if err := exp.ConsumeLogs(context.Background(), newSyslogTestLogs("alice")); err != nil {
+		t.Fatalf("ConsumeLogs() returned error: %v", err)
+	}
+	var request dryRunRequest
+	if err := json.Unmarshal([]byte(out.String()), &request); err != nil {
+		t.Fatalf("Failed to unmarshal dry-run output: %v", err)
+	}
+	body, _ := request.Body.(string)
+	if request.Method != "SYSLOG" || request.EventCount != 1 || !strings.Contains(body, " <132>1 ") {
+		t.Errorf("Unexpected dry-run output: %s", out.String())
+	}
+}
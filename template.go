@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldTemplate is a name built from literal text, event attributes and the event time, such as
+// an index name or object key. "%{service.name}" is replaced by an attribute, "%{service.name:
+// unknown}" falls back to "unknown" when the attribute is missing, and "%{+yyyy.MM.dd}" formats
+// the event time in UTC with the yyyy, yy, MM, dd, HH, mm and ss fields.
+type fieldTemplate struct {
+	segments []templateSegment
+}
+
+// templateSegment is one literal, attribute or time part of a template
+type templateSegment struct {
+	literal   string
+	attribute string
+	fallback  string
+	timeOf    string
+}
+
+// templateTimeFields are the time format fields, longest first so yyyy is not read as yy
+var templateTimeFields = []string{"yyyy", "yy", "MM", "dd", "HH", "mm", "ss"}
+
+// parseTemplate parses a template; text without %{...} placeholders renders as is
+func parseTemplate(text string) (*fieldTemplate, error) {
+	tmpl := &fieldTemplate{}
+	for text != "" {
+		start := strings.Index(text, "%{")
+		if start < 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: text})
+			break
+		}
+		if start > 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: text[:start]})
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in %q", text)
+		}
+		placeholder := text[start+2 : start+end]
+		text = text[start+end+1:]
+
+		if layout, ok := strings.CutPrefix(placeholder, "+"); ok {
+			if layout == "" {
+				return nil, fmt.Errorf("empty time format in %%{%s}", placeholder)
+			}
+			tmpl.segments = append(tmpl.segments, templateSegment{timeOf: layout})
+			continue
+		}
+		attribute, fallback, _ := strings.Cut(placeholder, ":")
+		if attribute == "" {
+			return nil, fmt.Errorf("empty attribute name in %%{%s}", placeholder)
+		}
+		tmpl.segments = append(tmpl.segments, templateSegment{attribute: attribute, fallback: fallback})
+	}
+	return tmpl, nil
+}
+
+// render returns the template for the event with attributes at timestamp
+func (tmpl *fieldTemplate) render(attributes map[string]interface{}, timestamp time.Time) string {
+	var b strings.Builder
+	for _, segment := range tmpl.segments {
+		switch {
+		case segment.timeOf != "":
+			formatTemplateTime(&b, segment.timeOf, timestamp.UTC())
+		case segment.attribute != "":
+			if value := stringField(attributes, segment.attribute); value != "" {
+				b.WriteString(value)
+			} else {
+				b.WriteString(segment.fallback)
+			}
+		default:
+			b.WriteString(segment.literal)
+		}
+	}
+	return b.String()
+}
+
+// formatTemplateTime writes t in layout, copying characters that are not time fields
+func formatTemplateTime(b *strings.Builder, layout string, t time.Time) {
+	for layout != "" {
+		field := ""
+		for _, candidate := range templateTimeFields {
+			if strings.HasPrefix(layout, candidate) {
+				field = candidate
+				break
+			}
+		}
+		switch field {
+		case "yyyy":
+			b.WriteString(strconv.Itoa(t.Year()))
+		case "yy":
+			fmt.Fprintf(b, "%02d", t.Year()%100)
+		case "MM":
+			fmt.Fprintf(b, "%02d", int(t.Month()))
+		case "dd":
+			fmt.Fprintf(b, "%02d", t.Day())
+		case "HH":
+			fmt.Fprintf(b, "%02d", t.Hour())
+		case "mm":
+			fmt.Fprintf(b, "%02d", t.Minute())
+		case "ss":
+			fmt.Fprintf(b, "%02d", t.Second())
+		default:
+			b.WriteByte(layout[0])
+			layout = layout[1:]
+			continue
+		}
+		layout = layout[len(field):]
+	}
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldTemplateRender(t *testing.T) {
+	timestamp := time.Date(2023, 11, 4, 7, 5, 9, 0, time.FixedZone("CET", 3600))
+	attributes := map[string]interface{}{"service.name": "auth", "tenant.id": 42}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "security-events", want: "security-events"},
+		{template: "security-%{service.name}-%{+yyyy.MM.dd}", want: "security-auth-2023.11.04"},
+		{template: "%{tenant.id}/%{+yyyy/MM/dd/HH}/", want: "42/2023/11/04/06/"},
+		{template: "logs-%{k8s.namespace.name:default}", want: "logs-default"},
+		{template: "logs-%{k8s.namespace.name}", want: "logs-"},
+		{template: "%{+yy-MM-ddTHH:mm:ss}Z", want: "23-11-04T06:05:09Z"},
+		{template: "<security-{now/d}>", want: "<security-{now/d}>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseTemplate() returned error: %v", err)
+			}
+			if got := tmpl.render(attributes, timestamp); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := map[string]string{
+		"logs-%{service.name": "unterminated placeholder",
+		"logs-%{}":            "empty attribute name",
+		"logs-%{+}":           "empty time format",
+		"logs-%{:fallback}":   "empty attribute name",
+	}
+	for template, errorMsg := range tests {
+		if _, err := parseTemplate(template); err == nil || !strings.Contains(err.Error(), errorMsg) {
+			t.Errorf("parseTemplate(%q) error = %v, want error containing %q", template, err, errorMsg)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tokenRefreshMargin is how long before expiry a cached access token is renewed
+const tokenRefreshMargin = time.Minute
+
+// accessToken caches an OAuth 2.0 access token of a transport
+type accessToken struct {
+	mu     sync.Mutex
+	value  string
+	expiry time.Time
+}
+
+// get returns the cached token, calling fetch for a new one when none is cached or the cached
+// one is about to expire
+func (t *accessToken) get(ctx context.Context, fetch func(ctx context.Context) (string, time.Duration, error)) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Until(t.expiry) > tokenRefreshMargin {
+		return t.value, nil
+	}
+
+	value, lifetime, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.value = value
+	t.expiry = time.Now().Add(lifetime)
+	return t.value, nil
+}
+
+// requestAccessToken posts form to the OAuth 2.0 token endpoint tokenURL and returns the access
+// token and its lifetime. Rejected requests are classified like batch responses, so invalid
+// credentials are permanent errors.
+func (e *securityEventExporter) requestAccessToken(ctx context.Context, tokenURL string, form url.Values) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.metrics.httpErrors.Add(1)
+		return "", 0, fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if err != nil {
+		e.metrics.httpErrors.Add(1)
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e.logger.Error("Access token request failed",
+			zap.String("token_url", tokenURL),
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("response_body", truncateString(e.redactor.redactText(string(body)), 500)))
+		e.metrics.httpErrors.Add(1)
+		return "", 0, classifyStatusError(fmt.Errorf("token request failed with status: %d", resp.StatusCode), resp)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", 0, errors.New("token response has no access_token")
+	}
+	lifetime := time.Duration(result.ExpiresIn) * time.Second
+	e.logger.Debug("Obtained access token",
+		zap.String("token_url", tokenURL),
+		zap.Duration("lifetime", lifetime))
+	return result.AccessToken, lifetime, nil
+}
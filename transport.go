@@ -0,0 +1,276 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// transportHTTP posts each batch to the endpoint with sendSecurityEventBatch
+	transportHTTP = "http"
+
+	// transportSyslog sends each security event as a syslog message
+	transportSyslog = "syslog"
+
+	// transportSplunkHEC posts batches of event envelopes to a Splunk HTTP Event Collector
+	transportSplunkHEC = "splunk_hec"
+
+	// transportSentinel posts batches to Microsoft Sentinel through the Logs Ingestion API or
+	// the legacy Data Collector API
+	transportSentinel = "sentinel"
+
+	// transportChronicle posts UDM events to the Google Security Operations ingestion API
+	transportChronicle = "chronicle"
+
+	// transportSecurityHub imports ASFF findings into AWS Security Hub with BatchImportFindings
+	transportSecurityHub = "security_hub"
+
+	// transportElasticsearch indexes documents with the Elasticsearch or OpenSearch bulk API
+	transportElasticsearch = "elasticsearch"
+
+	// transportLoki pushes events to the Grafana Loki push API
+	transportLoki = "loki"
+
+	// transportKafka produces one Kafka message per event
+	transportKafka = "kafka"
+
+	// transportFile appends security events to a local rotating file
+	transportFile = "file"
+
+	// transportCloudEvents posts security events wrapped in CloudEvents 1.0 envelopes
+	transportCloudEvents = "cloudevents"
+)
+
+// supportedTransports lists the accepted values of the transport setting
+var supportedTransports = []string{transportHTTP, transportSyslog, transportSplunkHEC, transportSentinel, transportChronicle, transportSecurityHub, transportElasticsearch, transportLoki, transportKafka, transportFile, transportCloudEvents}
+
+// eventSource keeps what transports need from the record an event was converted from, since
+// the encoded event may no longer carry it
+type eventSource struct {
+	// attributes is the flat security event before encoding
+	attributes map[string]interface{}
+
+	// time is the record timestamp, falling back to the observed timestamp
+	time time.Time
+
+	// severity is the record severity number, derived from the severity text when unset
+	severity plog.SeverityNumber
+
+	// record, resource and scope are the original log record and where it came from, used to
+	// retry only the records of events a transport failed to deliver
+	record   plog.LogRecord
+	resource pcommon.Resource
+	scope    pcommon.InstrumentationScope
+}
+
+// newEventSource captures the source of an event converted from logRecord
+func newEventSource(attributes map[string]interface{}, logRecord plog.LogRecord) eventSource {
+	return eventSource{
+		attributes: attributes,
+		time:       eventTime(logRecord),
+		severity:   severityNumber(logRecord),
+		record:     logRecord,
+		resource:   pcommon.NewResource(),
+		scope:      pcommon.NewInstrumentationScope(),
+	}
+}
+
+// partialSendError reports that a transport delivered a batch except for the events at the
+// failed indexes. Unless err is permanent, only the records of the failed events are retried.
+// Events at the dropped indexes failed permanently alongside them and are not retried.
+type partialSendError struct {
+	err     error
+	failed  []int
+	dropped []int
+}
+
+// newPartialSendError creates a partial failure of the events at the failed indexes
+func newPartialSendError(err error, failed []int) error {
+	return &partialSendError{err: err, failed: failed}
+}
+
+// unsentError returns err for a batch whose events from index sent on were not delivered. Once
+// earlier events were delivered it is a partial failure of the rest, so only they are retried.
+func unsentError(err error, sent, total int) error {
+	if sent == 0 {
+		return err
+	}
+	failed := make([]int, 0, total-sent)
+	for i := sent; i < total; i++ {
+		failed = append(failed, i)
+	}
+	return newPartialSendError(err, failed)
+}
+
+func (e *partialSendError) Error() string {
+	return fmt.Sprintf("%d events failed: %v", len(e.failed)+len(e.dropped), e.err)
+}
+
+func (e *partialSendError) Unwrap() error {
+	return e.err
+}
+
+// failedLogs returns a copy of the log records of the failed events, each with its resource and
+// scope, so the retry covers only what was not delivered
+func failedLogs(sources []eventSource, failed []int) plog.Logs {
+	logs := plog.NewLogs()
+	for _, i := range failed {
+		resourceLogs := logs.ResourceLogs().AppendEmpty()
+		sources[i].resource.CopyTo(resourceLogs.Resource())
+		scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+		sources[i].scope.CopyTo(scopeLogs.Scope())
+		sources[i].record.CopyTo(scopeLogs.LogRecords().AppendEmpty())
+	}
+	return logs
+}
+
+// eventTransport delivers batches of encoded security events. sources[i] describes the record
+// events[i] was converted from.
+type eventTransport interface {
+	start(ctx context.Context, host component.Host) error
+	send(ctx context.Context, events []map[string]interface{}, sources []eventSource) error
+	shutdown(ctx context.Context) error
+}
+
+// newEventTransport creates the transport selected by the exporter's Transport setting. It
+// returns nil for the default HTTP transport, which is implemented by sendSecurityEventBatch.
+// Transports share the exporter's configuration, logger, encoder, metrics and dry-run output.
+func newEventTransport(e *securityEventExporter) (eventTransport, error) {
+	switch e.config.Transport {
+	case "", transportHTTP:
+		return nil, nil
+	case transportSyslog:
+		return newSyslogTransport(e)
+	case transportSplunkHEC:
+		return newSplunkHECTransport(e)
+	case transportSentinel:
+		return newSentinelTransport(e)
+	case transportChronicle:
+		return newChronicleTransport(e)
+	case transportSecurityHub:
+		return newSecurityHubTransport(e)
+	case transportElasticsearch:
+		return newElasticsearchTransport(e)
+	case transportLoki:
+		return newLokiTransport(e)
+	case transportKafka:
+		return newKafkaTransport(e)
+	case transportFile:
+		return newFileSink(e)
+	case transportCloudEvents:
+		return newCloudEventsTransport(e)
+	default:
+		return nil, fmt.Errorf("unsupported transport %q", e.config.Transport)
+	}
+}
+
+// validateTransport checks the transport name, the endpoint it sends to and its settings
+func (cfg *Config) validateTransport() error {
+	switch cfg.Transport {
+	case "", transportHTTP:
+		return validateEndpoint(cfg.Endpoint)
+	case transportSyslog:
+		if _, _, err := parseSyslogEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		return cfg.Syslog.Validate()
+	case transportSplunkHEC:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		if cfg.SplunkHEC.Token == "" {
+			return errors.New("splunk_hec token is required")
+		}
+		return cfg.SplunkHEC.Validate()
+	case transportSentinel:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		if err := cfg.Sentinel.Validate(); err != nil {
+			return err
+		}
+		return cfg.Sentinel.validateDestination()
+	case transportChronicle:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		if cfg.Encoding != encodingUDM {
+			return fmt.Errorf("the chronicle transport requires the %q encoding", encodingUDM)
+		}
+		if err := cfg.Chronicle.Validate(); err != nil {
+			return err
+		}
+		return cfg.Chronicle.validateDestination()
+	case transportSecurityHub:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		if cfg.Encoding != encodingASFF {
+			return fmt.Errorf("the security_hub transport requires the %q encoding", encodingASFF)
+		}
+		return cfg.SecurityHub.validateDestination()
+	case transportElasticsearch:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		return cfg.Elasticsearch.Validate()
+	case transportLoki:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		return cfg.Loki.Validate()
+	case transportKafka:
+		if _, err := parseKafkaEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		return cfg.Kafka.Validate()
+	case transportFile:
+		// The endpoint is not used; events are written to file::path
+		if cfg.File.Path == "" {
+			return errors.New("file path is required")
+		}
+		return cfg.File.Validate()
+	case transportCloudEvents:
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+		return cfg.CloudEvents.Validate()
+	default:
+		return fmt.Errorf("invalid transport %q: must be one of %s", cfg.Transport, strings.Join(supportedTransports, ", "))
+	}
+}
+
+// chunkRecords splits JSON records into consecutive chunks that fit in limit bytes when joined as
+// a JSON array and, when maxRecords is positive, hold at most maxRecords records. A record that
+// does not fit in limit on its own is an error.
+func chunkRecords(records []json.RawMessage, limit, maxRecords int) ([][]json.RawMessage, error) {
+	var chunks [][]json.RawMessage
+	for start := 0; start < len(records); {
+		end, size := start, len("[]")
+		for end < len(records) && (maxRecords <= 0 || end-start < maxRecords) {
+			next := size + len(records[end])
+			if end > start {
+				next++ // separating comma
+			}
+			if next > limit {
+				break
+			}
+			size = next
+			end++
+		}
+		if end == start {
+			return nil, fmt.Errorf("record of %d bytes exceeds the %d byte request limit", len(records[start]), limit)
+		}
+		chunks = append(chunks, records[start:end])
+		start = end
+	}
+	return chunks, nil
+}
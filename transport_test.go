@@ -0,0 +1,94 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.uber.org/zap"
+)
+
+// newTestTransportExporter creates and starts an exporter sending to endpoint with transport
+func newTestTransportExporter(t *testing.T, transport, endpoint string, configure func(cfg *Config)) *securityEventExporter {
+	t.Helper()
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = endpoint
+	cfg.Transport = transport
+	cfg.Timeout = 5 * time.Second
+	if configure != nil {
+		configure(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+
+	encoder, err := newEventEncoder(cfg)
+	if err != nil {
+		t.Fatalf("newEventEncoder() returned error: %v", err)
+	}
+	exp := &securityEventExporter{
+		config:  cfg,
+		logger:  zap.NewNop(),
+		client:  &http.Client{Timeout: cfg.Timeout},
+		encoder: encoder,
+		metrics: &exporterMetrics{},
+	}
+	if exp.transport, err = newEventTransport(exp); err != nil {
+		t.Fatalf("newEventTransport() returned error: %v", err)
+	}
+	if err := exp.Start(context.Background(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = exp.Shutdown(context.Background()) })
+	return exp
+}
+
+func TestValidateTransport(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Transport = "smtp"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for unknown transport")
+	}
+
+	cfg.Transport = transportHTTP
+	cfg.Endpoint = "tcp://siem.example.com:601"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for non-HTTP endpoint with the HTTP transport")
+	}
+
+	cfg.Transport = transportSplunkHEC
+	cfg.Endpoint = "https://splunk.example.com:8088/services/collector/event"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "token is required") {
+		t.Errorf("Expected missing token error, got %v", err)
+	}
+}
+
+func TestChunkRecords(t *testing.T) {
+	records := []json.RawMessage{
+		json.RawMessage(`{"a":1}`),
+		json.RawMessage(`{"b":2}`),
+		json.RawMessage(`{"c":3}`),
+	}
+
+	chunks, err := chunkRecords(records, 17, 0)
+	if err != nil {
+		t.Fatalf("chunkRecords() returned error: %v", err)
+	}
+	if len(chunks) != 2 || len(chunks[0]) != 2 || len(chunks[1]) != 1 {
+		t.Errorf("Expected chunks of 2 and 1 records, got %v", chunks)
+	}
+	if body, _ := json.Marshal(chunks[0]); len(body) > 17 {
+		t.Errorf("Chunk of %d bytes exceeds the limit", len(body))
+	}
+
+	if chunks, _ := chunkRecords(records, 1000, 1); len(chunks) != 3 {
+		t.Errorf("Expected one record per chunk, got %d chunks", len(chunks))
+	}
+	if _, err := chunkRecords(records, 8, 0); err == nil {
+		t.Error("Expected error for a record larger than the limit")
+	}
+}
@@ -0,0 +1,334 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// UDMConfig configures the "udm" encoding, which maps security events to Google Security
+// Operations (Chronicle) Unified Data Model events
+type UDMConfig struct {
+	// ProductName is reported as metadata.product_name
+	ProductName FieldSource `mapstructure:"product_name"`
+
+	// VendorName is reported as metadata.vendor_name
+	VendorName FieldSource `mapstructure:"vendor_name"`
+
+	// Rules map log records to UDM event types. The first matching rule wins; when no rules are
+	// configured a built-in set keyed on event.category and common semantic convention attributes
+	// is used. Records matching no rule become GENERIC_EVENT.
+	Rules []UDMRule `mapstructure:"rules"`
+
+	// Fields maps attribute keys to UDM field paths such as principal.user.userid or
+	// target.ip, overriding or extending the built-in mapping. An empty path drops the attribute.
+	Fields map[string]string `mapstructure:"fields"`
+
+	// UnmappedAttributes controls attributes with no UDM field: "keep" (default) places them in
+	// additional and "drop" removes them
+	UnmappedAttributes string `mapstructure:"unmapped_attributes"`
+}
+
+// UDMRule maps matching log records to a UDM event type
+type UDMRule struct {
+	// EventType is the metadata.event_type, for example USER_LOGIN or NETWORK_HTTP
+	EventType string `mapstructure:"event_type"`
+
+	// Match lists attribute values that must all be equal for the rule to apply. A value of "*"
+	// only requires the attribute to be present. An empty Match matches every record.
+	Match map[string]string `mapstructure:"match"`
+}
+
+// udmEventTypes are the accepted metadata.event_type values
+var udmEventTypes = map[string]bool{
+	"GENERIC_EVENT":                  true,
+	"STATUS_UPDATE":                  true,
+	"STATUS_HEARTBEAT":               true,
+	"USER_LOGIN":                     true,
+	"USER_LOGOUT":                    true,
+	"USER_CREATION":                  true,
+	"USER_DELETION":                  true,
+	"USER_CHANGE_PASSWORD":           true,
+	"USER_CHANGE_PERMISSIONS":        true,
+	"USER_RESOURCE_ACCESS":           true,
+	"USER_RESOURCE_CREATION":         true,
+	"USER_RESOURCE_DELETION":         true,
+	"USER_RESOURCE_UPDATE_CONTENT":   true,
+	"USER_UNCATEGORIZED":             true,
+	"GROUP_MODIFICATION":             true,
+	"NETWORK_CONNECTION":             true,
+	"NETWORK_HTTP":                   true,
+	"NETWORK_DNS":                    true,
+	"NETWORK_FLOW":                   true,
+	"NETWORK_UNCATEGORIZED":          true,
+	"PROCESS_LAUNCH":                 true,
+	"PROCESS_TERMINATION":            true,
+	"PROCESS_UNCATEGORIZED":          true,
+	"FILE_CREATION":                  true,
+	"FILE_DELETION":                  true,
+	"FILE_MODIFICATION":              true,
+	"FILE_READ":                      true,
+	"FILE_UNCATEGORIZED":             true,
+	"RESOURCE_CREATION":              true,
+	"RESOURCE_DELETION":              true,
+	"RESOURCE_READ":                  true,
+	"RESOURCE_WRITTEN":               true,
+	"RESOURCE_PERMISSIONS_CHANGE":    true,
+	"SCAN_HOST":                      true,
+	"SCAN_NETWORK":                   true,
+	"SCAN_UNCATEGORIZED":             true,
+	"SCAN_VULN_HOST":                 true,
+	"EMAIL_TRANSACTION":              true,
+	"SYSTEM_AUDIT_LOG_UNCATEGORIZED": true,
+}
+
+// defaultUDMRules are used when no rules are configured
+var defaultUDMRules = []UDMRule{
+	{EventType: "USER_LOGOUT", Match: map[string]string{"event.category": "authentication", "event.action": "logout"}},
+	{EventType: "USER_LOGIN", Match: map[string]string{"event.category": "authentication"}},
+	{EventType: "USER_CHANGE_PERMISSIONS", Match: map[string]string{"event.category": "iam"}},
+	{EventType: "PROCESS_LAUNCH", Match: map[string]string{"event.category": "process"}},
+	{EventType: "FILE_UNCATEGORIZED", Match: map[string]string{"event.category": "file"}},
+	{EventType: "NETWORK_DNS", Match: map[string]string{"event.category": "dns"}},
+	{EventType: "NETWORK_HTTP", Match: map[string]string{"http.request.method": "*"}},
+	{EventType: "NETWORK_HTTP", Match: map[string]string{"http.method": "*"}},
+	{EventType: "NETWORK_CONNECTION", Match: map[string]string{"event.category": "network"}},
+}
+
+// defaultUDMFields maps OpenTelemetry semantic convention attributes to UDM fields. The actor is
+// the principal and the resource acted upon, including the reporting host and service, is the
+// target.
+var defaultUDMFields = map[string]string{
+	"user.name":                 "principal.user.userid",
+	"enduser.id":                "principal.user.userid",
+	"user.id":                   "principal.user.product_object_id",
+	"user.email":                "principal.user.email_addresses",
+	"user.full_name":            "principal.user.user_display_name",
+	"client.address":            "principal.ip",
+	"client.port":               "principal.port",
+	"source.ip":                 "principal.ip",
+	"source.port":               "principal.port",
+	"network.peer.address":      "principal.ip",
+	"process.pid":               "principal.process.pid",
+	"process.command_line":      "principal.process.command_line",
+	"process.executable.path":   "principal.process.file.full_path",
+	"server.address":            "target.hostname",
+	"server.port":               "target.port",
+	"destination.ip":            "target.ip",
+	"destination.port":          "target.port",
+	"host.name":                 "target.asset.hostname",
+	"host.id":                   "target.asset.asset_id",
+	"service.name":              "target.application",
+	"k8s.namespace.name":        "target.namespace",
+	"k8s.pod.name":              "target.resource.name",
+	"url.full":                  "target.url",
+	"http.url":                  "target.url",
+	"file.path":                 "target.file.full_path",
+	"http.request.method":       "network.http.method",
+	"http.method":               "network.http.method",
+	"http.response.status_code": "network.http.response_code",
+	"http.status_code":          "network.http.response_code",
+	"user_agent.original":       "network.http.user_agent",
+	"http.user_agent":           "network.http.user_agent",
+	"event.id":                  "metadata.product_log_id",
+	"event.action":              "metadata.product_event_type",
+	"event.category":            "security_result.category_details",
+	"rule.id":                   "security_result.rule_id",
+	"rule.name":                 "security_result.rule_name",
+	"event.reason":              "security_result.summary",
+	"threat.technique.id":       "security_result.threat_id",
+	"timestamp":                 "",
+}
+
+// udmRepeatedFields are UDM fields typed as lists
+var udmRepeatedFields = map[string]bool{
+	"principal.ip":                     true,
+	"target.ip":                        true,
+	"principal.user.email_addresses":   true,
+	"target.user.email_addresses":      true,
+	"security_result.category_details": true,
+}
+
+// udmNumericFields are UDM fields typed as integers; string attribute values are converted
+var udmNumericFields = map[string]bool{
+	"principal.port":             true,
+	"target.port":                true,
+	"network.http.response_code": true,
+}
+
+// udmSeverities maps normalized severity levels to security_result.severity
+var udmSeverities = map[severityLevel]string{
+	severityInformational: "INFORMATIONAL",
+	severityLow:           "LOW",
+	severityMedium:        "MEDIUM",
+	severityHigh:          "HIGH",
+	severityCritical:      "CRITICAL",
+}
+
+// Validate checks the rules and the unmapped attribute handling
+func (cfg *UDMConfig) Validate() error {
+	for i, rule := range cfg.Rules {
+		if !udmEventTypes[rule.EventType] {
+			return fmt.Errorf("udm rule %d: unknown event_type %q", i, rule.EventType)
+		}
+	}
+	for attribute, field := range cfg.Fields {
+		if field != "" && !isUDMField(field) {
+			return fmt.Errorf("udm field for %q: %q is not under a UDM noun or metadata, network, security_result or additional", attribute, field)
+		}
+	}
+	switch cfg.UnmappedAttributes {
+	case "", unmappedKeep, unmappedDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid udm unmapped_attributes %q: must be %q or %q", cfg.UnmappedAttributes, unmappedKeep, unmappedDrop)
+	}
+}
+
+// createDefaultUDMConfig creates the default UDM product and vendor
+func createDefaultUDMConfig() UDMConfig {
+	return UDMConfig{
+		ProductName:        FieldSource{Attributes: []string{"service.name"}, Value: "SecurityEventExporter"},
+		VendorName:         FieldSource{Value: "OpenTelemetry"},
+		UnmappedAttributes: unmappedKeep,
+	}
+}
+
+// udmEncoder maps flat security events to UDM events
+type udmEncoder struct {
+	config *UDMConfig
+	rules  []UDMRule
+	fields map[string]string
+}
+
+// newUDMEncoder creates a UDM encoder from cfg
+func newUDMEncoder(cfg *UDMConfig) (*udmEncoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = defaultUDMRules
+	}
+	fields := make(map[string]string, len(defaultUDMFields)+len(cfg.Fields))
+	for attribute, field := range defaultUDMFields {
+		fields[attribute] = field
+	}
+	for attribute, field := range cfg.Fields {
+		fields[attribute] = field
+	}
+	return &udmEncoder{config: cfg, rules: rules, fields: fields}, nil
+}
+
+// encode builds the UDM event for one record
+func (enc *udmEncoder) encode(event map[string]interface{}, logRecord plog.LogRecord, resource pcommon.Resource) (map[string]interface{}, error) {
+	udm := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"event_timestamp": eventTime(logRecord).UTC().Format(time.RFC3339Nano),
+			"event_type":      enc.eventType(event),
+			"product_name":    enc.config.ProductName.resolve(event),
+			"vendor_name":     enc.config.VendorName.resolve(event),
+		},
+	}
+	result := map[string]interface{}{}
+	if severity, ok := udmSeverities[severityLevelOf(severityNumber(logRecord))]; ok {
+		result["severity"] = severity
+	}
+	switch strings.ToLower(stringField(event, "event.outcome")) {
+	case "success":
+		result["action"] = []interface{}{"ALLOW"}
+	case "failure":
+		result["action"] = []interface{}{"BLOCK"}
+	}
+	udm["security_result"] = result
+
+	additional := map[string]interface{}{}
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, mapped := enc.fields[key]
+		if mapped && field == "" {
+			continue
+		}
+		value := stringField(event, key)
+		if !mapped || !enc.setField(udm, field, value) {
+			if enc.config.UnmappedAttributes != unmappedDrop {
+				additional[key] = value
+			}
+		}
+	}
+	if len(additional) > 0 {
+		udm["additional"] = additional
+	}
+
+	// UDM events carry a list of security results; this encoder produces at most one
+	if len(result) > 0 {
+		udm["security_result"] = []interface{}{result}
+	} else {
+		delete(udm, "security_result")
+	}
+	return udm, nil
+}
+
+// eventType returns the event type of the first rule matching event
+func (enc *udmEncoder) eventType(event map[string]interface{}) string {
+	for _, rule := range enc.rules {
+		if matchesRule(event, rule.Match) {
+			return rule.EventType
+		}
+	}
+	return "GENERIC_EVENT"
+}
+
+// setField stores value at the UDM field path, converting it to the field's type. It returns false
+// when the value does not fit the field, so the attribute can be kept in additional instead.
+func (enc *udmEncoder) setField(udm map[string]interface{}, field, value string) bool {
+	var typed interface{} = value
+	switch {
+	case udmNumericFields[field]:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+		typed = number
+	case udmRepeatedFields[field]:
+		if existing, ok := nestedField(udm, field); ok {
+			if list, ok := existing.([]interface{}); ok {
+				for _, item := range list {
+					if item == value {
+						return true
+					}
+				}
+				typed = append(list, value)
+				break
+			}
+		}
+		typed = []interface{}{value}
+	default:
+		if existing, ok := nestedField(udm, field); ok && existing != value {
+			// The first attribute mapped to a single-valued field wins
+			return false
+		}
+	}
+	return setNestedField(udm, field, typed)
+}
+
+// isUDMField reports whether field is under a top-level UDM event field
+func isUDMField(field string) bool {
+	root, _, _ := strings.Cut(field, ".")
+	switch root {
+	case "metadata", "principal", "src", "target", "intermediary", "observer", "about", "network",
+		"security_result", "extensions", "additional":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// newUDMTestRecord returns a WARN record at 2023-11-14T22:13:20Z
+func newUDMTestRecord() plog.LogRecord {
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	record.SetSeverityNumber(plog.SeverityNumberWarn)
+	return record
+}
+
+func TestUDMEncoderMapsNounsAndSecurityResult(t *testing.T) {
+	cfg := createDefaultUDMConfig()
+	enc, err := newUDMEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newUDMEncoder() returned error: %v", err)
+	}
+
+	event := map[string]interface{}{
+		"timestamp":      "2023-11-14T22:13:20Z",
+		"service.name":   "auth",
+		"host.name":      "web-1",
+		"user.name":      "alice",
+		"client.address": "10.0.0.7",
+		"client.port":    "51234",
+		"event.category": "authentication",
+		"event.action":   "login",
+		"event.outcome":  "failure",
+		"rule.name":      "brute-force",
+		"tenant.id":      "acme",
+	}
+
+	udm, err := enc.encode(event, newUDMTestRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	expect := map[string]interface{}{
+		"metadata.event_timestamp":    "2023-11-14T22:13:20Z",
+		"metadata.event_type":         "USER_LOGIN",
+		"metadata.product_name":       "auth",
+		"metadata.vendor_name":        "OpenTelemetry",
+		"metadata.product_event_type": "login",
+		"principal.user.userid":       "alice",
+		"principal.port":              51234,
+		"target.asset.hostname":       "web-1",
+		"target.application":          "auth",
+	}
+	for path, want := range expect {
+		got, ok := nestedField(udm, path)
+		if !ok || got != want {
+			t.Errorf("%s = %v (%T), want %v (%T)", path, got, got, want, want)
+		}
+	}
+	if got, _ := nestedField(udm, "principal.ip"); !reflect.DeepEqual(got, []interface{}{"10.0.0.7"}) {
+		t.Errorf("principal.ip = %v, want [10.0.0.7]", got)
+	}
+	if additional, _ := udm["additional"].(map[string]interface{}); additional["tenant.id"] != "acme" {
+		t.Errorf("Expected unmapped tenant.id in additional, got %v", udm["additional"])
+	}
+	if _, ok := udm["additional"].(map[string]interface{})["timestamp"]; ok {
+		t.Error("The converter timestamp should not be kept in additional")
+	}
+
+	results, ok := udm["security_result"].([]interface{})
+	if !ok || len(results) != 1 {
+		t.Fatalf("Expected one security result, got %v", udm["security_result"])
+	}
+	result := results[0].(map[string]interface{})
+	if result["severity"] != "MEDIUM" || result["rule_name"] != "brute-force" {
+		t.Errorf("Unexpected security result: %v", result)
+	}
+	if !reflect.DeepEqual(result["action"], []interface{}{"BLOCK"}) {
+		t.Errorf("action = %v, want [BLOCK]", result["action"])
+	}
+	if !reflect.DeepEqual(result["category_details"], []interface{}{"authentication"}) {
+		t.Errorf("category_details = %v, want [authentication]", result["category_details"])
+	}
+}
+
+func TestUDMEncoderRulesAndFields(t *testing.T) {
+	cfg := createDefaultUDMConfig()
+	cfg.Rules = []UDMRule{{EventType: "USER_RESOURCE_ACCESS", Match: map[string]string{"event.action": "read"}}}
+	cfg.Fields = map[string]string{"tenant.id": "target.user.company_name", "session.token": ""}
+	cfg.UnmappedAttributes = unmappedDrop
+	enc, err := newUDMEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newUDMEncoder() returned error: %v", err)
+	}
+
+	udm, err := enc.encode(map[string]interface{}{
+		"event.action":  "read",
+		"tenant.id":     "acme",
+		"session.token": "secret",
+		"custom.field":  "x",
+	}, newUDMTestRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+
+	if got, _ := nestedField(udm, "metadata.event_type"); got != "USER_RESOURCE_ACCESS" {
+		t.Errorf("event_type = %v, want USER_RESOURCE_ACCESS", got)
+	}
+	if got, _ := nestedField(udm, "target.user.company_name"); got != "acme" {
+		t.Errorf("target.user.company_name = %v, want acme", got)
+	}
+	if _, ok := udm["additional"]; ok {
+		t.Errorf("Expected unmapped attributes to be dropped, got %v", udm["additional"])
+	}
+
+	udm, _ = enc.encode(map[string]interface{}{"event.action": "write"}, newUDMTestRecord(), pcommon.NewResource())
+	if got, _ := nestedField(udm, "metadata.event_type"); got != "GENERIC_EVENT" {
+		t.Errorf("event_type = %v, want GENERIC_EVENT for unmatched records", got)
+	}
+}
+
+func TestUDMEncoderKeepsValuesThatDoNotFit(t *testing.T) {
+	cfg := createDefaultUDMConfig()
+	enc, err := newUDMEncoder(&cfg)
+	if err != nil {
+		t.Fatalf("newUDMEncoder() returned error: %v", err)
+	}
+
+	udm, err := enc.encode(map[string]interface{}{"server.port": "https"}, newUDMTestRecord(), pcommon.NewResource())
+	if err != nil {
+		t.Fatalf("encode() returned error: %v", err)
+	}
+	if _, ok := nestedField(udm, "target.port"); ok {
+		t.Error("A non-numeric port should not be set")
+	}
+	if additional, _ := udm["additional"].(map[string]interface{}); additional["server.port"] != "https" {
+		t.Errorf("Expected server.port in additional, got %v", udm["additional"])
+	}
+}
+
+func TestUDMConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *UDMConfig)
+		errorMsg string
+	}{
+		{name: "defaults", modify: func(*UDMConfig) {}},
+		{name: "unknown event type", modify: func(cfg *UDMConfig) {
+			cfg.Rules = []UDMRule{{EventType: "LOGIN"}}
+		}, errorMsg: "unknown event_type"},
+		{name: "field outside udm", modify: func(cfg *UDMConfig) {
+			cfg.Fields = map[string]string{"user.name": "actor.name"}
+		}, errorMsg: "is not under a UDM noun"},
+		{name: "invalid unmapped attributes", modify: func(cfg *UDMConfig) {
+			cfg.UnmappedAttributes = "labels"
+		}, errorMsg: "invalid udm unmapped_attributes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultUDMConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}